@@ -1,6 +1,7 @@
 package gomq
 
 import (
+	"fmt"
 	"sync"
 	"time"
 )
@@ -9,16 +10,44 @@ type Config struct {
 	sync.RWMutex
 	reconnectTimeout time.Duration
 	connectTimeout   time.Duration
-	queueLen         int
+	sendHWM          int
+	recvHWM          int
+	sendTimeout      time.Duration
+	recvTimeout      time.Duration
+	linger           time.Duration
+	heartbeatIvl     time.Duration
+	heartbeatTimeout time.Duration
+	heartbeatTTL     time.Duration
+	reconnectIvlMax  time.Duration
+	reconnectTries   int
+	reconnectStop    ReconnectStop
 }
 
+// ReconnectStop is a set of conditions under which a connecting socket stops
+// trying to reconnect.
+type ReconnectStop int
+
+const (
+	// ReconnectStopConnRefused gives up when the peer refuses the connection.
+	ReconnectStopConnRefused ReconnectStop = 1 << iota
+	// ReconnectStopHandshakeFailed gives up when the ZMTP greeting or
+	// handshake fails.
+	ReconnectStopHandshakeFailed
+	// ReconnectStopAfterDisconnect gives up once an established connection
+	// is lost.
+	ReconnectStopAfterDisconnect
+)
+
 func (c *Config) Default() {
 	c.Lock()
 	defer c.Unlock()
 
 	c.reconnectTimeout = time.Second
 	c.connectTimeout = time.Second * 3
-	c.queueLen = 1024
+	c.sendHWM = 1000
+	c.recvHWM = 1000
+	c.sendTimeout = -1
+	c.recvTimeout = -1
 }
 
 func (c *Config) ReconnectTimeout() time.Duration {
@@ -33,6 +62,49 @@ func (c *Config) SetReconnectTimeout(d time.Duration) {
 	c.reconnectTimeout = d
 }
 
+// ReconnectIvlMax caps the exponential backoff between reconnect attempts,
+// which starts at ReconnectTimeout. Zero, the default, always waits
+// ReconnectTimeout.
+func (c *Config) ReconnectIvlMax() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+	return c.reconnectIvlMax
+}
+
+func (c *Config) SetReconnectIvlMax(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.reconnectIvlMax = d
+}
+
+// ReconnectMaxAttempts is how many attempts in a row may fail before a
+// connecting socket gives up. Zero, the default, never gives up.
+func (c *Config) ReconnectMaxAttempts() int {
+	c.RLock()
+	defer c.RUnlock()
+	return c.reconnectTries
+}
+
+func (c *Config) SetReconnectMaxAttempts(attempts int) {
+	c.Lock()
+	defer c.Unlock()
+	c.reconnectTries = attempts
+}
+
+// ReconnectStop is when a connecting socket gives up reconnecting. It is
+// empty by default.
+func (c *Config) ReconnectStop() ReconnectStop {
+	c.RLock()
+	defer c.RUnlock()
+	return c.reconnectStop
+}
+
+func (c *Config) SetReconnectStop(stop ReconnectStop) {
+	c.Lock()
+	defer c.Unlock()
+	c.reconnectStop = stop
+}
+
 func (c *Config) ConnectTimeout() time.Duration {
 	c.RLock()
 	defer c.RUnlock()
@@ -45,14 +117,239 @@ func (c *Config) SetConnectTimeout(d time.Duration) {
 	c.connectTimeout = d
 }
 
+// QueueLen returns the send high-water mark.
+//
+// Deprecated: use SendHWM or RecvHWM.
 func (c *Config) QueueLen() int {
+	return c.SendHWM()
+}
+
+// SetQueueLen sets both high-water marks.
+//
+// Deprecated: use SetSendHWM and SetRecvHWM.
+func (c *Config) SetQueueLen(queueLen int) {
+	c.SetSendHWM(queueLen)
+	c.SetRecvHWM(queueLen)
+}
+
+// SendHWM is how many whole messages may be queued for each peer before the
+// socket type's overflow policy applies, as in libzmq: PUSH blocks and PUB
+// drops, for example. Unlike libzmq, 0 does not mean no limit, since queues
+// are bounded channels: SetOption rejects values below 1 and SetSendHWM
+// raises them to 1.
+func (c *Config) SendHWM() int {
 	c.RLock()
 	defer c.RUnlock()
-	return c.queueLen
+	return c.sendHWM
 }
 
-func (c *Config) SetQueueLen(queueLen int) {
+func (c *Config) SetSendHWM(hwm int) {
+	c.Lock()
+	defer c.Unlock()
+	if hwm < 1 {
+		hwm = 1
+	}
+	c.sendHWM = hwm
+}
+
+// RecvHWM is how many whole messages may be queued from each peer before
+// reading from it pauses. Like SendHWM, it is at least 1.
+func (c *Config) RecvHWM() int {
+	c.RLock()
+	defer c.RUnlock()
+	return c.recvHWM
+}
+
+func (c *Config) SetRecvHWM(hwm int) {
+	c.Lock()
+	defer c.Unlock()
+	if hwm < 1 {
+		hwm = 1
+	}
+	c.recvHWM = hwm
+}
+
+// HeartbeatIvl is how often peers are sent a PING. Zero disables heartbeats.
+func (c *Config) HeartbeatIvl() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+	return c.heartbeatIvl
+}
+
+func (c *Config) SetHeartbeatIvl(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.heartbeatIvl = d
+}
+
+// HeartbeatTimeout is how long to wait for any traffic after sending a PING
+// before dropping the peer. Zero means the heartbeat interval.
+func (c *Config) HeartbeatTimeout() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+	if c.heartbeatTimeout == 0 {
+		return c.heartbeatIvl
+	}
+	return c.heartbeatTimeout
+}
+
+func (c *Config) SetHeartbeatTimeout(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.heartbeatTimeout = d
+}
+
+// HeartbeatTTL is sent with each PING, asking the peer to drop the
+// connection if it hears nothing for that long. It is rounded down to
+// deciseconds and zero means no limit.
+func (c *Config) HeartbeatTTL() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+	return c.heartbeatTTL
+}
+
+func (c *Config) SetHeartbeatTTL(d time.Duration) {
 	c.Lock()
 	defer c.Unlock()
-	c.queueLen = queueLen
+	c.heartbeatTTL = d
+}
+
+// SendTimeout is how long Send waits before failing with
+// types.ErrWouldBlock. Negative values wait indefinitely.
+func (c *Config) SendTimeout() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+	return c.sendTimeout
+}
+
+func (c *Config) SetSendTimeout(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.sendTimeout = d
+}
+
+// RecvTimeout is how long Recv waits before failing with
+// types.ErrWouldBlock. Negative values wait indefinitely.
+func (c *Config) RecvTimeout() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+	return c.recvTimeout
+}
+
+func (c *Config) SetRecvTimeout(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.recvTimeout = d
+}
+
+// Linger is how long Close waits for queued messages to be sent before
+// discarding them. Negative values wait indefinitely and zero, the default,
+// discards them straight away.
+func (c *Config) Linger() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+	return c.linger
+}
+
+func (c *Config) SetLinger(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.linger = d
+}
+
+// SetOption sets a socket option held in the config, returning
+// ErrUnknownOption for anything else.
+func (c *Config) SetOption(option string, val any) error {
+	switch option {
+	case OptionHeartbeatIvl, OptionHeartbeatTimeout, OptionHeartbeatTTL:
+		d, err := durationOption(option, val)
+		if err != nil {
+			return err
+		}
+
+		switch option {
+		case OptionHeartbeatIvl:
+			c.SetHeartbeatIvl(d)
+		case OptionHeartbeatTimeout:
+			c.SetHeartbeatTimeout(d)
+		case OptionHeartbeatTTL:
+			c.SetHeartbeatTTL(d)
+		}
+		return nil
+	case OptionReconnectIvl, OptionReconnectIvlMax:
+		d, err := durationOption(option, val)
+		if err != nil {
+			return err
+		}
+
+		if option == OptionReconnectIvl {
+			c.SetReconnectTimeout(d)
+		} else {
+			c.SetReconnectIvlMax(d)
+		}
+		return nil
+	case OptionReconnectMaxAttempts:
+		attempts, ok := val.(int)
+		if !ok || attempts < 0 {
+			return fmt.Errorf("Value for option %s must be non-negative int, got %v", option, val)
+		}
+
+		c.SetReconnectMaxAttempts(attempts)
+		return nil
+	case OptionReconnectStop:
+		stop, ok := val.(ReconnectStop)
+		if !ok {
+			return fmt.Errorf("Value for option %s must be ReconnectStop, got %T", option, val)
+		}
+
+		c.SetReconnectStop(stop)
+		return nil
+	case OptionLinger:
+		d, err := durationOption(option, val)
+		if err != nil {
+			return err
+		}
+
+		c.SetLinger(d)
+		return nil
+	case OptionSendTimeout, OptionRecvTimeout:
+		d, err := durationOption(option, val)
+		if err != nil {
+			return err
+		}
+
+		if option == OptionSendTimeout {
+			c.SetSendTimeout(d)
+		} else {
+			c.SetRecvTimeout(d)
+		}
+		return nil
+	case OptionSendHWM, OptionRecvHWM:
+		hwm, ok := val.(int)
+		if !ok || hwm < 1 {
+			return fmt.Errorf("Value for option %s must be positive int, got %v (0 for no limit is not supported)", option, val)
+		}
+
+		if option == OptionSendHWM {
+			c.SetSendHWM(hwm)
+		} else {
+			c.SetRecvHWM(hwm)
+		}
+		return nil
+	}
+
+	return ErrUnknownOption
+}
+
+// durationOption returns the value of an option given as a time.Duration or
+// as an int number of milliseconds.
+func durationOption(option string, val any) (time.Duration, error) {
+	switch d := val.(type) {
+	case time.Duration:
+		return d, nil
+	case int:
+		return time.Duration(d) * time.Millisecond, nil
+	}
+
+	return 0, fmt.Errorf("Value for option %s must be time.Duration or int milliseconds, got %T", option, val)
 }
@@ -0,0 +1,32 @@
+package gomq_test
+
+import (
+	"testing"
+
+	"github.com/workspace-9/gomq"
+)
+
+// TestHWMPositive checks that a high-water mark of 0, which libzmq takes as
+// no limit, is rejected rather than making every queue unbuffered.
+func TestHWMPositive(t *testing.T) {
+	var conf gomq.Config
+	conf.Default()
+
+	for _, option := range []string{gomq.OptionSendHWM, gomq.OptionRecvHWM} {
+		if err := conf.SetOption(option, 0); err == nil {
+			t.Errorf("%s accepted 0", option)
+		}
+		if err := conf.SetOption(option, 5); err != nil {
+			t.Errorf("%s rejected 5: %v", option, err)
+		}
+	}
+	if conf.SendHWM() != 5 || conf.RecvHWM() != 5 {
+		t.Fatalf("expected high-water marks of 5, got %d and %d", conf.SendHWM(), conf.RecvHWM())
+	}
+
+	conf.SetSendHWM(0)
+	conf.SetRecvHWM(-1)
+	if conf.SendHWM() != 1 || conf.RecvHWM() != 1 {
+		t.Fatalf("expected high-water marks of 1, got %d and %d", conf.SendHWM(), conf.RecvHWM())
+	}
+}
@@ -2,22 +2,32 @@ package gomq
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 
 	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/zap"
+	"github.com/workspace-9/gomq/zmtp"
 )
 
 type Context struct {
 	sync.RWMutex
 	transports map[string]transport.Transport
 	ctx        context.Context
+	zapHandler zap.Handler
+	eventBus   EventBus
+	sockets    map[SocketDriver]*Socket
+	nextID     uint64
+	shutdown   bool
 }
 
 func NewContext(ctx context.Context) *Context {
 	return &Context{
 		ctx:        ctx,
 		transports: make(map[string]transport.Transport),
+		eventBus:   PrintBus{},
+		sockets:    make(map[SocketDriver]*Socket),
 	}
 }
 
@@ -40,7 +50,38 @@ func (c *Context) getTransport(name string) (transport.Transport, bool) {
 	return nil, false
 }
 
+// SetTransport makes sockets of this context use tp for URLs with the
+// scheme name, in place of the registered transport. This lets a context
+// hold its own configured transport, such as TLS with per-endpoint configs.
+func (c *Context) SetTransport(name string, tp transport.Transport) {
+	c.Lock()
+	defer c.Unlock()
+	c.transports[name] = tp
+}
+
+// NewSocket makes a socket which posts its events to the context's bus.
 func (c *Context) NewSocket(typ string, mechStr string) (*Socket, error) {
+	return c.NewSocketWithEventBus(typ, mechStr, c.EventBus())
+}
+
+// SetEventBus sets the bus that sockets created afterwards by NewSocket post
+// their events to. It is PrintBus by default.
+func (c *Context) SetEventBus(eventBus EventBus) {
+	c.Lock()
+	defer c.Unlock()
+	c.eventBus = eventBus
+}
+
+// EventBus returns the bus set by SetEventBus.
+func (c *Context) EventBus() EventBus {
+	c.RLock()
+	defer c.RUnlock()
+	return c.eventBus
+}
+
+// NewSocketWithEventBus makes a socket which posts its events to eventBus,
+// which may be nil to only deliver them to Socket.Monitor.
+func (c *Context) NewSocketWithEventBus(typ string, mechStr string, eventBus EventBus) (*Socket, error) {
 	sock := &Socket{}
 
 	constructor, ok := FindSocketType(typ)
@@ -56,7 +97,15 @@ func (c *Context) NewSocket(typ string, mechStr string) (*Socket, error) {
 	conf := &Config{}
 	conf.Default()
 	mech := mechConstructor()
-	driver, err := constructor(c.ctx, mech, conf, PrintBus{})
+	if handler := c.ZAPHandler(); handler != nil {
+		if err := mech.SetOption(zmtp.OptionZAPHandler, handler); err != nil {
+			return nil, err
+		}
+	}
+
+	bus := &monitorBus{next: eventBus, socketType: typ, mechanism: mechStr}
+	metrics := &SocketMetrics{}
+	driver, err := constructor(WithSocketMetrics(c.ctx, metrics), mech, conf, bus)
 	if err != nil {
 		return nil, err
 	}
@@ -64,9 +113,64 @@ func (c *Context) NewSocket(typ string, mechStr string) (*Socket, error) {
 	sock.driver = driver
 	sock.ctx = c
 	sock.mech = mech
+	sock.conf = conf
+	sock.ahead = &readAhead{}
+	sock.bus = bus
+	sock.metrics = metrics
+
+	c.Lock()
+	defer c.Unlock()
+	if c.shutdown {
+		driver.Close()
+		return nil, ErrContextShutdown
+	}
+	c.nextID++
+	sock.id = c.nextID
+	c.sockets[driver] = sock
 	return sock, nil
 }
 
+// forget stops tracking a socket which is being closed.
+func (c *Context) forget(driver SocketDriver) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.sockets, driver)
+}
+
+// Shutdown closes every open socket made by the context, each lingering as
+// configured but no longer than until ctx is done, and stops new sockets
+// from being made.
+func (c *Context) Shutdown(ctx context.Context) error {
+	c.Lock()
+	c.shutdown = true
+	sockets := make([]*Socket, 0, len(c.sockets))
+	for _, sock := range c.sockets {
+		sockets = append(sockets, sock)
+	}
+	c.Unlock()
+
+	errs := make([]error, len(sockets))
+	var wg sync.WaitGroup
+	for idx, sock := range sockets {
+		wg.Add(1)
+		go func(idx int, sock *Socket) {
+			defer wg.Done()
+			errs[idx] = sock.CloseContext(ctx)
+		}(idx, sock)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+type contextShutdown struct{}
+
+func (contextShutdown) Error() string {
+	return "Context has been shut down"
+}
+
+var ErrContextShutdown contextShutdown
+
 type typeNotFound struct{}
 
 func (typeNotFound) Error() string {
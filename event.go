@@ -3,14 +3,24 @@ package gomq
 type EventType int
 
 const (
-	EventTypeConnected       = EventType(0)
-	EventTypeDisconnected    = EventType(1)
-	EventTypeConnectFailed   = EventType(2)
-	EventTypeAccepted        = EventType(3)
-	EventTypeAcceptFailed    = EventType(4)
-	EventTypeFailedGreeting  = EventType(5)
-	EventTypeFailedHandshake = EventType(6)
-	EventTypeReady           = EventType(7)
+	EventTypeConnected         = EventType(0)
+	EventTypeDisconnected      = EventType(1)
+	EventTypeConnectFailed     = EventType(2)
+	EventTypeAccepted          = EventType(3)
+	EventTypeAcceptFailed      = EventType(4)
+	EventTypeFailedGreeting    = EventType(5)
+	EventTypeFailedHandshake   = EventType(6)
+	EventTypeReady             = EventType(7)
+	EventTypeHeartbeatFailed   = EventType(8)
+	EventTypeMessagesDiscarded = EventType(9)
+	EventTypeReconnectStopped  = EventType(10)
+	EventTypeListening         = EventType(11)
+	EventTypeBindFailed        = EventType(12)
+	EventTypeClosed            = EventType(13)
+	EventTypeConnectRetried    = EventType(14)
+
+	// EventTypeHandshakeSucceeded is the libzmq name for EventTypeReady.
+	EventTypeHandshakeSucceeded = EventTypeReady
 )
 
 func (e EventType) String() string {
@@ -31,6 +41,20 @@ func (e EventType) String() string {
 		return "Failed handshake"
 	case EventTypeReady:
 		return "Ready"
+	case EventTypeHeartbeatFailed:
+		return "Heartbeat failed"
+	case EventTypeMessagesDiscarded:
+		return "Messages discarded"
+	case EventTypeReconnectStopped:
+		return "Reconnect stopped"
+	case EventTypeListening:
+		return "Listening"
+	case EventTypeBindFailed:
+		return "Bind failed"
+	case EventTypeClosed:
+		return "Closed"
+	case EventTypeConnectRetried:
+		return "Connect retried"
 	}
 
 	return ""
@@ -41,6 +65,34 @@ type Event struct {
 	LocalAddr  string
 	RemoteAddr string
 	Notes      string
+
+	// SocketType and Mechanism are those of the socket posting the event.
+	SocketType string
+	Mechanism  string
+
+	// Err is the error behind a failure event, for use with errors.Is and
+	// errors.As. Notes holds its text.
+	Err error
+}
+
+// EventMask is a set of event types.
+type EventMask uint64
+
+// EventAll matches every event type.
+const EventAll = ^EventMask(0)
+
+// Events returns the mask matching the given event types.
+func Events(types ...EventType) EventMask {
+	var mask EventMask
+	for _, typ := range types {
+		mask |= 1 << typ
+	}
+	return mask
+}
+
+// Has reports whether the mask matches the event type.
+func (m EventMask) Has(typ EventType) bool {
+	return m&(1<<typ) != 0
 }
 
 type EventBus interface {
@@ -2,9 +2,9 @@ module github.com/workspace-9/gomq
 
 go 1.21.1
 
-require github.com/pebbe/zmq4 v1.2.10
-
 require (
-	golang.org/x/crypto v0.19.0 // indirect
-	golang.org/x/sys v0.17.0 // indirect
+	github.com/pebbe/zmq4 v1.2.10
+	golang.org/x/crypto v0.19.0
 )
+
+require golang.org/x/sys v0.17.0
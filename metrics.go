@@ -0,0 +1,254 @@
+package gomq
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// MetricKind says how a Sample's value behaves over time.
+type MetricKind int
+
+const (
+	// MetricCounter values only go up.
+	MetricCounter MetricKind = iota
+	// MetricGauge values are the current level of something.
+	MetricGauge
+)
+
+// Label is a name and value distinguishing samples of the same metric.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is one measurement of a metric.
+type Sample struct {
+	Name   string
+	Help   string
+	Kind   MetricKind
+	Labels []Label
+	Value  float64
+}
+
+// Collector is anything which can report samples, such as a Socket or a
+// whole Context.
+type Collector interface {
+	// Collect calls emit with each current sample.
+	Collect(emit func(Sample))
+}
+
+// QueueDepth is how many messages are queued and how many fit.
+type QueueDepth struct {
+	Len int
+	Cap int
+}
+
+// QueueReporter is implemented by socket drivers which queue messages in
+// channels sized by the high-water marks.
+type QueueReporter interface {
+	// QueueDepths returns the totals of the socket's send and receive
+	// queues.
+	QueueDepths() (send, recv QueueDepth)
+}
+
+// EndpointMetrics counts traffic through one endpoint of a socket: the URL
+// it connected to or bound.
+type EndpointMetrics struct {
+	MessagesSent      atomic.Uint64
+	BytesSent         atomic.Uint64
+	MessagesReceived  atomic.Uint64
+	BytesReceived     atomic.Uint64
+	HandshakeFailures atomic.Uint64
+	Reconnects        atomic.Uint64
+	SendErrors        atomic.Uint64
+}
+
+// CountSent counts one frame sent, and a message once its last frame is.
+// It does nothing on a nil receiver.
+func (e *EndpointMetrics) CountSent(msg zmtp.Message) {
+	if e == nil {
+		return
+	}
+
+	e.BytesSent.Add(uint64(len(msg.Body)))
+	if !msg.More {
+		e.MessagesSent.Add(1)
+	}
+}
+
+// CountHandshakeFailure counts a failed greeting or handshake like
+// CountSent.
+func (e *EndpointMetrics) CountHandshakeFailure() {
+	if e != nil {
+		e.HandshakeFailures.Add(1)
+	}
+}
+
+// CountReconnect counts a reconnect attempt scheduled like CountSent.
+func (e *EndpointMetrics) CountReconnect() {
+	if e != nil {
+		e.Reconnects.Add(1)
+	}
+}
+
+// CountSendError counts a message which could not be sent like CountSent.
+func (e *EndpointMetrics) CountSendError() {
+	if e != nil {
+		e.SendErrors.Add(1)
+	}
+}
+
+// CountReceived counts one frame received like CountSent.
+func (e *EndpointMetrics) CountReceived(msg zmtp.Message) {
+	if e == nil {
+		return
+	}
+
+	e.BytesReceived.Add(uint64(len(msg.Body)))
+	if !msg.More {
+		e.MessagesReceived.Add(1)
+	}
+}
+
+// SocketMetrics holds the endpoint metrics of a socket. The zero value is
+// ready for use.
+type SocketMetrics struct {
+	lock      sync.Mutex
+	endpoints map[string]*EndpointMetrics
+}
+
+type socketMetricsKey struct{}
+
+// WithSocketMetrics returns a context carrying the metrics of the socket
+// whose driver runs under it, where the driver's endpoints find them.
+func WithSocketMetrics(ctx context.Context, m *SocketMetrics) context.Context {
+	return context.WithValue(ctx, socketMetricsKey{}, m)
+}
+
+// SocketMetricsFrom returns the socket metrics carried by ctx, or nil if
+// there are none, on which Endpoint returns nil metrics counting nothing.
+func SocketMetricsFrom(ctx context.Context) *SocketMetrics {
+	m, _ := ctx.Value(socketMetricsKey{}).(*SocketMetrics)
+	return m
+}
+
+// Endpoint returns the metrics of the endpoint, creating them if need be.
+// It returns nil on a nil receiver.
+func (m *SocketMetrics) Endpoint(url string) *EndpointMetrics {
+	if m == nil {
+		return nil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.endpoints == nil {
+		m.endpoints = make(map[string]*EndpointMetrics)
+	}
+
+	endpoint, ok := m.endpoints[url]
+	if !ok {
+		endpoint = &EndpointMetrics{}
+		m.endpoints[url] = endpoint
+	}
+	return endpoint
+}
+
+var endpointCounters = []struct {
+	name, help string
+	value      func(*EndpointMetrics) uint64
+}{
+	{"gomq_messages_sent_total", "Whole messages sent.", func(e *EndpointMetrics) uint64 { return e.MessagesSent.Load() }},
+	{"gomq_bytes_sent_total", "Message body bytes sent.", func(e *EndpointMetrics) uint64 { return e.BytesSent.Load() }},
+	{"gomq_messages_received_total", "Whole messages received.", func(e *EndpointMetrics) uint64 { return e.MessagesReceived.Load() }},
+	{"gomq_bytes_received_total", "Message body bytes received.", func(e *EndpointMetrics) uint64 { return e.BytesReceived.Load() }},
+	{"gomq_handshake_failures_total", "Failed greetings and handshakes.", func(e *EndpointMetrics) uint64 { return e.HandshakeFailures.Load() }},
+	{"gomq_reconnects_total", "Reconnect attempts scheduled.", func(e *EndpointMetrics) uint64 { return e.Reconnects.Load() }},
+	{"gomq_send_errors_total", "Messages which failed to send.", func(e *EndpointMetrics) uint64 { return e.SendErrors.Load() }},
+}
+
+// collect emits a sample of each counter of each endpoint, in order of
+// endpoint, labelled with labels and the endpoint.
+func (m *SocketMetrics) collect(labels []Label, emit func(Sample)) {
+	m.lock.Lock()
+	urls := make([]string, 0, len(m.endpoints))
+	for url := range m.endpoints {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	endpoints := make([]*EndpointMetrics, len(urls))
+	for idx, url := range urls {
+		endpoints[idx] = m.endpoints[url]
+	}
+	m.lock.Unlock()
+
+	for _, counter := range endpointCounters {
+		for idx, url := range urls {
+			emit(Sample{
+				Name:   counter.name,
+				Help:   counter.help,
+				Kind:   MetricCounter,
+				Labels: append(labels[:len(labels):len(labels)], Label{"endpoint", url}),
+				Value:  float64(counter.value(endpoints[idx])),
+			})
+		}
+	}
+}
+
+// Collect emits the socket's traffic counters for each endpoint and, if the
+// socket type queues messages, the depth of its queues. Samples are
+// labelled with the socket's id within its context, type and mechanism.
+func (s Socket) Collect(emit func(Sample)) {
+	labels := []Label{
+		{"socket_id", strconv.FormatUint(s.id, 10)},
+		{"socket_type", s.bus.socketType},
+		{"mechanism", s.bus.mechanism},
+	}
+	s.metrics.collect(labels, emit)
+
+	reporter, ok := s.driver.(QueueReporter)
+	if !ok {
+		return
+	}
+
+	send, recv := reporter.QueueDepths()
+	for _, queue := range []struct {
+		direction string
+		depth     QueueDepth
+	}{{"send", send}, {"recv", recv}} {
+		queueLabels := append(labels[:len(labels):len(labels)], Label{"direction", queue.direction})
+		emit(Sample{
+			Name:   "gomq_queue_messages",
+			Help:   "Messages waiting in queues.",
+			Kind:   MetricGauge,
+			Labels: queueLabels,
+			Value:  float64(queue.depth.Len),
+		})
+		emit(Sample{
+			Name:   "gomq_queue_capacity",
+			Help:   "Messages the queues can hold.",
+			Kind:   MetricGauge,
+			Labels: queueLabels,
+			Value:  float64(queue.depth.Cap),
+		})
+	}
+}
+
+// Collect emits the samples of every open socket made by the context.
+func (c *Context) Collect(emit func(Sample)) {
+	c.RLock()
+	sockets := make([]*Socket, 0, len(c.sockets))
+	for _, sock := range c.sockets {
+		sockets = append(sockets, sock)
+	}
+	c.RUnlock()
+	sort.Slice(sockets, func(i, j int) bool { return sockets[i].id < sockets[j].id })
+
+	for _, sock := range sockets {
+		sock.Collect(emit)
+	}
+}
@@ -0,0 +1,106 @@
+// Package metrics exposes samples from a gomq.Collector, such as a
+// gomq.Context, in the Prometheus text format or through expvar.
+package metrics
+
+import (
+	"bufio"
+	"expvar"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/workspace-9/gomq"
+)
+
+// family is the samples of one metric.
+type family struct {
+	name    string
+	help    string
+	kind    gomq.MetricKind
+	samples []gomq.Sample
+}
+
+// gather collects the samples, grouped by metric in the order each metric
+// was first seen.
+func gather(c gomq.Collector) []*family {
+	var families []*family
+	byName := map[string]*family{}
+	c.Collect(func(sample gomq.Sample) {
+		fam, ok := byName[sample.Name]
+		if !ok {
+			fam = &family{name: sample.Name, help: sample.Help, kind: sample.Kind}
+			byName[sample.Name] = fam
+			families = append(families, fam)
+		}
+		fam.samples = append(fam.samples, sample)
+	})
+	return families
+}
+
+// WritePrometheus writes the collector's samples in the Prometheus text
+// exposition format.
+func WritePrometheus(w io.Writer, c gomq.Collector) error {
+	buf := bufio.NewWriter(w)
+	for _, fam := range gather(c) {
+		if fam.help != "" {
+			buf.WriteString("# HELP " + fam.name + " " + helpEscaper.Replace(fam.help) + "\n")
+		}
+		kind := "counter"
+		if fam.kind == gomq.MetricGauge {
+			kind = "gauge"
+		}
+		buf.WriteString("# TYPE " + fam.name + " " + kind + "\n")
+
+		for _, sample := range fam.samples {
+			buf.WriteString(sample.Name)
+			if len(sample.Labels) > 0 {
+				buf.WriteByte('{')
+				for idx, label := range sample.Labels {
+					if idx > 0 {
+						buf.WriteByte(',')
+					}
+					buf.WriteString(label.Name + `="` + labelEscaper.Replace(label.Value) + `"`)
+				}
+				buf.WriteByte('}')
+			}
+			buf.WriteString(" " + strconv.FormatFloat(sample.Value, 'g', -1, 64) + "\n")
+		}
+	}
+
+	return buf.Flush()
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// Handler serves the collector's samples for a Prometheus scraper.
+func Handler(c gomq.Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WritePrometheus(w, c)
+	})
+}
+
+// Var returns an expvar.Var reporting the collector's samples as a map from
+// metric name to a list of labels and values, for use with expvar.Publish.
+func Var(c gomq.Collector) expvar.Var {
+	return expvar.Func(func() any {
+		out := map[string][]map[string]any{}
+		for _, fam := range gather(c) {
+			for _, sample := range fam.samples {
+				labels := make(map[string]string, len(sample.Labels))
+				for _, label := range sample.Labels {
+					labels[label.Name] = label.Value
+				}
+				out[fam.name] = append(out[fam.name], map[string]any{
+					"labels": labels,
+					"value":  sample.Value,
+				})
+			}
+		}
+		return out
+	})
+}
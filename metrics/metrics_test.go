@@ -0,0 +1,95 @@
+package metrics_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/metrics"
+)
+
+type samples []gomq.Sample
+
+func (s samples) Collect(emit func(gomq.Sample)) {
+	for _, sample := range s {
+		emit(sample)
+	}
+}
+
+var testSamples = samples{
+	{
+		Name:   "gomq_messages_sent_total",
+		Help:   "Whole messages sent.",
+		Kind:   gomq.MetricCounter,
+		Labels: []gomq.Label{{Name: "socket_type", Value: "PUSH"}, {Name: "endpoint", Value: `tcp://"a"`}},
+		Value:  3,
+	},
+	{
+		Name:   "gomq_queue_messages",
+		Help:   "Messages waiting\nin queues.",
+		Kind:   gomq.MetricGauge,
+		Labels: []gomq.Label{{Name: "direction", Value: "send"}},
+		Value:  1.5,
+	},
+	{
+		Name:   "gomq_messages_sent_total",
+		Help:   "Whole messages sent.",
+		Kind:   gomq.MetricCounter,
+		Labels: []gomq.Label{{Name: "socket_type", Value: "PUB"}, {Name: "endpoint", Value: `tcp://b`}},
+		Value:  4,
+	},
+}
+
+// TestWritePrometheus checks that samples are grouped by metric in the
+// order first seen, and that help and labels are escaped.
+func TestWritePrometheus(t *testing.T) {
+	var out strings.Builder
+	if err := metrics.WritePrometheus(&out, testSamples); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP gomq_messages_sent_total Whole messages sent.
+# TYPE gomq_messages_sent_total counter
+gomq_messages_sent_total{socket_type="PUSH",endpoint="tcp://\"a\""} 3
+gomq_messages_sent_total{socket_type="PUB",endpoint="tcp://b"} 4
+# HELP gomq_queue_messages Messages waiting\nin queues.
+# TYPE gomq_queue_messages gauge
+gomq_queue_messages{direction="send"} 1.5
+`
+	if out.String() != want {
+		t.Fatalf("expected\n%s\ngot\n%s", want, out.String())
+	}
+}
+
+// TestHandler checks that the handler serves the text format.
+func TestHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	metrics.Handler(testSamples).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), `gomq_queue_messages{direction="send"} 1.5`) {
+		t.Fatalf("missing sample in\n%s", rec.Body.String())
+	}
+}
+
+// TestVar checks that the expvar lists each metric's samples in order.
+func TestVar(t *testing.T) {
+	var out map[string][]struct {
+		Labels map[string]string
+		Value  float64
+	}
+	if err := json.Unmarshal([]byte(metrics.Var(testSamples).String()), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := out["gomq_messages_sent_total"]
+	if len(sent) != 2 || sent[0].Labels["socket_type"] != "PUSH" || sent[0].Value != 3 || sent[1].Value != 4 {
+		t.Fatalf("unexpected gomq_messages_sent_total %+v", sent)
+	}
+	if queued := out["gomq_queue_messages"]; len(queued) != 1 || queued[0].Labels["direction"] != "send" {
+		t.Fatalf("unexpected gomq_queue_messages %+v", queued)
+	}
+}
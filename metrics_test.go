@@ -0,0 +1,153 @@
+package gomq_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	_ "github.com/workspace-9/gomq/transport/inproc"
+	_ "github.com/workspace-9/gomq/types/dealer"
+	_ "github.com/workspace-9/gomq/types/pair"
+	_ "github.com/workspace-9/gomq/types/pub"
+	_ "github.com/workspace-9/gomq/types/pull"
+	_ "github.com/workspace-9/gomq/types/push"
+	_ "github.com/workspace-9/gomq/types/router"
+	_ "github.com/workspace-9/gomq/types/sub"
+	_ "github.com/workspace-9/gomq/types/xsub"
+	_ "github.com/workspace-9/gomq/zmtp/null"
+)
+
+// sampleValue returns the value of the named sample carrying the label, and
+// whether there was one.
+func sampleValue(c gomq.Collector, name string, label gomq.Label) (value float64, ok bool) {
+	c.Collect(func(sample gomq.Sample) {
+		if sample.Name != name {
+			return
+		}
+		for _, candidate := range sample.Labels {
+			if candidate == label {
+				value, ok = sample.Value, true
+			}
+		}
+	})
+	return value, ok
+}
+
+// eventually fails the test unless the named sample carrying the label
+// reaches want within a second.
+func eventually(t *testing.T, c gomq.Collector, name string, label gomq.Label, want float64) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		got, _ := sampleValue(c, name, label)
+		if got >= want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %s{%s=%q} to reach %v, got %v", name, label.Name, label.Value, want, got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestMetricsCountTraffic checks that messages and bytes are counted on
+// both ends of a connection, and that reconnects after it is lost are
+// counted.
+func TestMetricsCountTraffic(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	push, err := ctx.NewSocket("PUSH", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer push.Close()
+	push.SetOption(gomq.OptionReconnectIvl, 10*time.Millisecond)
+	if err := push.Connect("inproc://metrics"); err != nil {
+		t.Fatal(err)
+	}
+	endpoint := gomq.Label{Name: "endpoint", Value: "inproc://metrics"}
+
+	pull, err := ctx.NewSocket("PULL", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pull.Close()
+	if err := pull.Bind("inproc://metrics"); err != nil {
+		t.Fatal(err)
+	}
+
+	for idx := 0; idx < 2; idx++ {
+		if err := push.Send([][]byte{[]byte("hel"), []byte("lo")}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pull.Recv(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	eventually(t, push, "gomq_messages_sent_total", endpoint, 2)
+	eventually(t, pull, "gomq_messages_received_total", endpoint, 2)
+	for _, check := range []struct {
+		sock *gomq.Socket
+		name string
+		want float64
+	}{
+		{push, "gomq_messages_sent_total", 2},
+		{push, "gomq_bytes_sent_total", 10},
+		{pull, "gomq_messages_received_total", 2},
+		{pull, "gomq_bytes_received_total", 10},
+	} {
+		if got, _ := sampleValue(check.sock, check.name, endpoint); got != check.want {
+			t.Errorf("expected %s %v, got %v", check.name, check.want, got)
+		}
+	}
+
+	if _, ok := sampleValue(ctx, "gomq_queue_capacity", gomq.Label{Name: "socket_type", Value: "PULL"}); !ok {
+		t.Error("expected the context to report the PULL socket's queues")
+	}
+
+	pull.Close()
+	eventually(t, push, "gomq_reconnects_total", endpoint, 1)
+}
+
+// TestQueueDepthsReported checks that socket types which queue received
+// messages report the capacity of their queues.
+func TestQueueDepthsReported(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	for _, pair := range []struct{ typ, peer string }{
+		{"SUB", "PUB"},
+		{"XSUB", "PUB"},
+		{"REQ", "REP"},
+		{"REP", "REQ"},
+		{"ROUTER", "DEALER"},
+		{"PAIR", "PAIR"},
+	} {
+		sock, err := ctx.NewSocket(pair.typ, "NULL")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sock.Close()
+		sock.SetOption(gomq.OptionRecvHWM, 7)
+		addr := "inproc://depths-" + pair.typ
+		if err := sock.Bind(addr); err != nil {
+			t.Fatal(err)
+		}
+
+		peer, err := ctx.NewSocket(pair.peer, "NULL")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer peer.Close()
+		if err := peer.Connect(addr); err != nil {
+			t.Fatal(err)
+		}
+
+		t.Run(pair.typ, func(t *testing.T) {
+			eventually(t, sock, "gomq_queue_capacity", gomq.Label{Name: "direction", Value: "recv"}, 7)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package gomq
+
+import "sync"
+
+// MonitorQueueLen is how many events a monitor channel buffers. Events
+// posted while the buffer is full are dropped rather than stalling the
+// socket.
+const MonitorQueueLen = 128
+
+// monitorBus passes a socket's events on to the context's bus and to every
+// monitor interested in them, marking them with the socket's type and
+// mechanism.
+type monitorBus struct {
+	lock       sync.Mutex
+	next       EventBus
+	socketType string
+	mechanism  string
+	monitors   []monitor
+	stopped    bool
+}
+
+type monitor struct {
+	mask   EventMask
+	events chan Event
+}
+
+func (m *monitorBus) Post(ev Event) {
+	ev.SocketType = m.socketType
+	ev.Mechanism = m.mechanism
+	if m.next != nil {
+		m.next.Post(ev)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, mon := range m.monitors {
+		if !mon.mask.Has(ev.EventType) {
+			continue
+		}
+
+		select {
+		case mon.events <- ev:
+		default:
+		}
+	}
+}
+
+func (m *monitorBus) add(mask EventMask) <-chan Event {
+	events := make(chan Event, MonitorQueueLen)
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.stopped {
+		close(events)
+		return events
+	}
+
+	m.monitors = append(m.monitors, monitor{mask: mask, events: events})
+	return events
+}
+
+// stop closes every monitor channel once the socket is closed.
+func (m *monitorBus) stop() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.stopped {
+		return
+	}
+
+	m.stopped = true
+	for _, mon := range m.monitors {
+		close(mon.events)
+	}
+	m.monitors = nil
+}
@@ -0,0 +1,158 @@
+package gomq_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	_ "github.com/workspace-9/gomq/transport/tcp"
+)
+
+// recordingBus keeps every event posted to it.
+type recordingBus struct {
+	lock   sync.Mutex
+	events []gomq.Event
+}
+
+func (b *recordingBus) Post(ev gomq.Event) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.events = append(b.events, ev)
+}
+
+func (b *recordingBus) types() []gomq.EventType {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	types := make([]gomq.EventType, len(b.events))
+	for idx, ev := range b.events {
+		types[idx] = ev.EventType
+	}
+	return types
+}
+
+// next returns the next event from the monitor, failing the test if none
+// arrives within a few seconds.
+func next(t *testing.T, events <-chan gomq.Event) gomq.Event {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("expected an event, the monitor was closed")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected an event")
+		return gomq.Event{}
+	}
+}
+
+// TestMonitorLifecycle checks that monitors receive only the events in
+// their mask, marked with the socket's type and mechanism, that the bus
+// passed to NewSocketWithEventBus receives them too, and that monitors are
+// closed with the socket.
+func TestMonitorLifecycle(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	bus := &recordingBus{}
+	pull, err := ctx.NewSocketWithEventBus("PULL", "NULL", bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := pull.Monitor(gomq.Events(
+		gomq.EventTypeListening,
+		gomq.EventTypeHandshakeSucceeded,
+		gomq.EventTypeClosed,
+	))
+	if err := pull.Bind("tcp://127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	listening := next(t, events)
+	if listening.EventType != gomq.EventTypeListening || listening.SocketType != "PULL" || listening.Mechanism != "NULL" {
+		t.Fatalf("expected PULL NULL to be listening, got %+v", listening)
+	}
+
+	push, err := ctx.NewSocket("PUSH", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer push.Close()
+	if err := push.Connect(listening.LocalAddr); err != nil {
+		t.Fatal(err)
+	}
+	if ev := next(t, events); ev.EventType != gomq.EventTypeHandshakeSucceeded {
+		t.Fatalf("expected the handshake to succeed, got %s", ev.EventType)
+	}
+
+	pull.Close()
+	if ev := next(t, events); ev.EventType != gomq.EventTypeClosed {
+		t.Fatalf("expected the listener to close, got %s", ev.EventType)
+	}
+	for ev := range events {
+		if ev.EventType != gomq.EventTypeClosed && ev.EventType != gomq.EventTypeHandshakeSucceeded {
+			t.Fatalf("expected only events in the mask, got %s", ev.EventType)
+		}
+	}
+	if _, ok := <-pull.Monitor(gomq.Events(gomq.EventTypeClosed)); ok {
+		t.Fatal("expected monitors of a closed socket to be closed")
+	}
+
+	seen := map[gomq.EventType]bool{}
+	for _, typ := range bus.types() {
+		seen[typ] = true
+	}
+	for _, typ := range []gomq.EventType{gomq.EventTypeListening, gomq.EventTypeAccepted, gomq.EventTypeReady, gomq.EventTypeClosed} {
+		if !seen[typ] {
+			t.Errorf("expected the bus to receive %s", typ)
+		}
+	}
+}
+
+// TestMonitorErrors checks that failure events carry the error behind
+// them, and that retries are reported.
+func TestMonitorErrors(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := "tcp://" + ln.Addr().String()
+
+	pull, err := ctx.NewSocket("PULL", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pull.Close()
+	events := pull.Monitor(gomq.Events(gomq.EventTypeBindFailed))
+	if err := pull.Bind(addr); err == nil {
+		t.Fatal("expected binding a port in use to fail")
+	}
+	if ev := next(t, events); !errors.Is(ev.Err, syscall.EADDRINUSE) {
+		t.Fatalf("expected EADDRINUSE, got %v", ev.Err)
+	}
+	ln.Close()
+
+	push, err := ctx.NewSocket("PUSH", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer push.Close()
+	push.SetOption(gomq.OptionReconnectIvl, 10*time.Millisecond)
+	events = push.Monitor(gomq.Events(gomq.EventTypeConnectFailed, gomq.EventTypeConnectRetried))
+	if err := push.Connect(addr); err != nil {
+		t.Fatal(err)
+	}
+	if ev := next(t, events); ev.EventType != gomq.EventTypeConnectFailed || !errors.Is(ev.Err, syscall.ECONNREFUSED) {
+		t.Fatalf("expected the connect to be refused, got %s %v", ev.EventType, ev.Err)
+	}
+	if ev := next(t, events); ev.EventType != gomq.EventTypeConnectRetried {
+		t.Fatalf("expected a retry, got %s", ev.EventType)
+	}
+}
@@ -0,0 +1,97 @@
+package gomq
+
+const (
+	// OptionXPubVerbose passes every subscription to an XPUB socket's
+	// Recv, not only the first for each topic.
+	OptionXPubVerbose = "xpub_verbose"
+
+	// OptionXPubVerboser additionally passes every unsubscription to an
+	// XPUB socket's Recv, not only the last for each topic.
+	OptionXPubVerboser = "xpub_verboser"
+
+	// OptionXPubManual stops an XPUB socket from applying subscriptions
+	// itself, leaving it to Socket.Subscribe and Socket.Unsubscribe.
+	OptionXPubManual = "xpub_manual"
+
+	// OptionXPubWelcomeMsg is sent by an XPUB socket to each new subscriber.
+	OptionXPubWelcomeMsg = "xpub_welcome_msg"
+
+	// OptionReqCorrelate makes a REQ socket prefix each request with an id
+	// and drop replies which do not carry the id of the latest request.
+	OptionReqCorrelate = "req_correlate"
+
+	// OptionReqRelaxed lets a REQ socket send a new request before the
+	// reply to the previous one arrives, abandoning the previous request.
+	OptionReqRelaxed = "req_relaxed"
+
+	// OptionRoutingID is sent as the Identity property during the handshake
+	// so that a ROUTER peer can address this socket by it.
+	OptionRoutingID = "routing_id"
+
+	// OptionRouterMandatory makes a ROUTER socket fail sends to unknown
+	// routing ids instead of dropping them.
+	OptionRouterMandatory = "router_mandatory"
+
+	// OptionRouterHandover lets a new ROUTER peer take over the routing id
+	// of an existing one instead of being rejected.
+	OptionRouterHandover = "router_handover"
+
+	// OptionConnectRoutingID is the routing id a ROUTER socket assigns to
+	// the peer of its next Connect.
+	OptionConnectRoutingID = "connect_routing_id"
+
+	// OptionHeartbeatIvl sets Config.SetHeartbeatIvl.
+	OptionHeartbeatIvl = "heartbeat_ivl"
+
+	// OptionHeartbeatTimeout sets Config.SetHeartbeatTimeout.
+	OptionHeartbeatTimeout = "heartbeat_timeout"
+
+	// OptionHeartbeatTTL sets Config.SetHeartbeatTTL.
+	OptionHeartbeatTTL = "heartbeat_ttl"
+
+	// OptionSendHWM sets Config.SetSendHWM. It must be at least 1, libzmq's
+	// 0 for no limit is not supported.
+	OptionSendHWM = "sndhwm"
+
+	// OptionRecvHWM sets Config.SetRecvHWM. Like OptionSendHWM, it must be at
+	// least 1.
+	OptionRecvHWM = "rcvhwm"
+
+	// OptionSendTimeout sets Config.SetSendTimeout. An int is taken as
+	// milliseconds, so -1 waits indefinitely as in libzmq.
+	OptionSendTimeout = "sndtimeo"
+
+	// OptionRecvTimeout sets Config.SetRecvTimeout.
+	OptionRecvTimeout = "rcvtimeo"
+
+	// OptionLinger sets Config.SetLinger. An int is taken as milliseconds.
+	OptionLinger = "linger"
+
+	// OptionReconnectIvl sets Config.SetReconnectTimeout.
+	OptionReconnectIvl = "reconnect_ivl"
+
+	// OptionReconnectIvlMax sets Config.SetReconnectIvlMax.
+	OptionReconnectIvlMax = "reconnect_ivl_max"
+
+	// OptionReconnectMaxAttempts sets Config.SetReconnectMaxAttempts.
+	OptionReconnectMaxAttempts = "reconnect_max_attempts"
+
+	// OptionReconnectStop sets Config.SetReconnectStop.
+	OptionReconnectStop = "reconnect_stop"
+)
+
+// OptionSetter is implemented by socket drivers which have options of their
+// own. Options the driver does not recognise are passed on to the mechanism.
+type OptionSetter interface {
+	// SetOption sets an option in the driver, returning ErrUnknownOption
+	// if the driver does not recognise it.
+	SetOption(option string, value any) error
+}
+
+type unknownOption struct{}
+
+func (unknownOption) Error() string {
+	return "Unknown option"
+}
+
+var ErrUnknownOption unknownOption
@@ -0,0 +1,354 @@
+package gomq
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/workspace-9/gomq/types"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// PollEvent is a set of readiness conditions.
+type PollEvent int
+
+const (
+	// PollIn means a message can be received without blocking.
+	PollIn PollEvent = 1 << iota
+	// PollOut means a message can be sent without blocking.
+	PollOut
+)
+
+// Polled reports the events ready on one item registered with a Poller.
+// Exactly one of Socket and Conn is set.
+type Polled struct {
+	Socket *Socket
+	Conn   syscall.Conn
+	Events PollEvent
+}
+
+// Poller waits on several sockets at once, like zmq_poller. Sockets whose
+// drivers implement RecvPoller are watched without receiving. Others are
+// watched by receiving ahead: the first message to arrive is held by the
+// socket and returned by its next Recv, so such a socket should not be
+// received from by another goroutine while it is being polled.
+type Poller struct {
+	lock  sync.Mutex
+	items []*pollItem
+}
+
+type pollItem struct {
+	socket *Socket
+	conn   syscall.Conn
+	watch  *connWatch
+	events PollEvent
+}
+
+// NewPoller returns an empty poller.
+func NewPoller() *Poller {
+	return &Poller{}
+}
+
+// Add the socket to the poller, watching for the given events.
+func (p *Poller) Add(sock *Socket, events PollEvent) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, item := range p.items {
+		if item.socket == sock {
+			return ErrAlreadyPolled
+		}
+	}
+
+	p.items = append(p.items, &pollItem{socket: sock, events: events})
+	return nil
+}
+
+// AddConn adds a raw connection, such as a net.Conn or an *os.File wrapping
+// a file descriptor, watching for the given events. While waiting for the
+// connection to become readable the poller holds its read lock, so a
+// concurrent Read will wait for data as it would anyway.
+func (p *Poller) AddConn(conn syscall.Conn, events PollEvent) error {
+	watch, err := newConnWatch(conn)
+	if err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, item := range p.items {
+		if item.conn == conn {
+			return ErrAlreadyPolled
+		}
+	}
+
+	p.items = append(p.items, &pollItem{conn: conn, watch: watch, events: events})
+	return nil
+}
+
+// Modify the events watched for on a socket or connection already added.
+func (p *Poller) Modify(item any, events PollEvent) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	idx := p.find(item)
+	if idx < 0 {
+		return ErrNotPolled
+	}
+
+	p.items[idx].events = events
+	return nil
+}
+
+// Remove a socket or connection from the poller.
+func (p *Poller) Remove(item any) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	idx := p.find(item)
+	if idx < 0 {
+		return ErrNotPolled
+	}
+
+	p.items = append(p.items[:idx], p.items[idx+1:]...)
+	return nil
+}
+
+func (p *Poller) find(item any) int {
+	for idx, candidate := range p.items {
+		switch item := item.(type) {
+		case *Socket:
+			if candidate.socket == item {
+				return idx
+			}
+		case syscall.Conn:
+			if candidate.conn == item {
+				return idx
+			}
+		}
+	}
+
+	return -1
+}
+
+// Wait until at least one item is ready or the timeout passes, returning the
+// ready items. A zero timeout checks without waiting and a negative timeout
+// waits indefinitely. No items are returned if the timeout passes.
+func (p *Poller) Wait(timeout time.Duration) ([]Polled, error) {
+	p.lock.Lock()
+	items := append([]*pollItem(nil), p.items...)
+	p.lock.Unlock()
+
+	// Background receives started by the wait end with it.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var timer *time.Timer
+	if timeout > 0 {
+		timer = time.NewTimer(timeout)
+		defer timer.Stop()
+	}
+
+	first := true
+	for {
+		var ready []Polled
+		var cases []reflect.SelectCase
+		for _, item := range items {
+			events, changed, err := item.poll(ctx, first)
+			if err != nil {
+				return nil, err
+			}
+
+			if events != 0 {
+				ready = append(ready, Polled{Socket: item.socket, Conn: item.conn, Events: events})
+			}
+
+			for _, ch := range changed {
+				cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+			}
+		}
+		first = false
+
+		if len(ready) > 0 || timeout == 0 {
+			return ready, nil
+		}
+
+		if timer != nil {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(timer.C)})
+		}
+		if len(cases) == 0 {
+			return nil, ErrNothingToPoll
+		}
+
+		chosen, _, _ := reflect.Select(cases)
+		if timer != nil && chosen == len(cases)-1 {
+			return nil, nil
+		}
+	}
+}
+
+// poll returns the events ready on the item, and channels which are closed
+// when that may have changed. A socket receiving ahead starts only on the
+// first poll of a Wait, so a socket whose Recv fails is not retried in a loop,
+// and stops once ctx is done.
+func (item *pollItem) poll(ctx context.Context, first bool) (PollEvent, []<-chan struct{}, error) {
+	if item.conn != nil {
+		return item.watch.poll(item.events)
+	}
+
+	var events PollEvent
+	var changed []<-chan struct{}
+	if item.events&PollIn != 0 {
+		if poller, ok := item.socket.driver.(RecvPoller); ok {
+			if recvReady := poller.RecvReady(); recvReady != nil {
+				select {
+				case <-recvReady:
+					events |= PollIn
+				default:
+					changed = append(changed, recvReady)
+				}
+			}
+		} else if ok, arrived := item.socket.ahead.poll(ctx, item.socket.driver, first); ok {
+			events |= PollIn
+		} else if arrived != nil {
+			changed = append(changed, arrived)
+		}
+	}
+
+	if item.events&PollOut != 0 {
+		poller, ok := item.socket.driver.(SendPoller)
+		if !ok {
+			events |= PollOut
+		} else if sendReady := poller.SendReady(); sendReady != nil {
+			select {
+			case <-sendReady:
+				events |= PollOut
+			default:
+				changed = append(changed, sendReady)
+			}
+		}
+	}
+
+	return events, changed, nil
+}
+
+// readAhead holds at most one message received from a socket's driver in
+// advance of a call to Recv.
+type readAhead struct {
+	lock     sync.Mutex
+	inflight chan struct{}
+	held     []zmtp.Message
+	holding  bool
+}
+
+// poll reports whether a message is held. Otherwise, if start is set and
+// nothing is being received already, it tries to receive without waiting and
+// failing that receives in the background until ctx is done. The returned
+// channel is closed once the background receive finishes.
+func (r *readAhead) poll(ctx context.Context, driver SocketDriver, start bool) (bool, <-chan struct{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.holding {
+		return true, nil
+	}
+
+	if r.inflight != nil || !start {
+		return false, r.inflight
+	}
+
+	// Drivers return a message already queued even once ctx is done.
+	now, cancel := context.WithCancel(ctx)
+	cancel()
+	messages, err := driver.RecvContext(now)
+	if err == nil {
+		r.held = messages
+		r.holding = true
+		return true, nil
+	}
+	// Other errors are not held, the socket's state may have changed by the
+	// time Recv is called.
+	if !errors.Is(err, types.ErrWouldBlock) || ctx.Err() != nil {
+		return false, nil
+	}
+
+	done := make(chan struct{})
+	r.inflight = done
+	go func() {
+		defer close(done)
+		messages, err := driver.RecvContext(ctx)
+
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		r.inflight = nil
+		if err == nil {
+			r.held = messages
+			r.holding = true
+		}
+	}()
+
+	return false, done
+}
+
+// recv returns the held message if there is one, waiting on a background
+// receive in progress so that messages stay in order.
+func (r *readAhead) recv(ctx context.Context, driver SocketDriver) ([]zmtp.Message, error) {
+	r.lock.Lock()
+	for r.inflight != nil {
+		inflight := r.inflight
+		r.lock.Unlock()
+		select {
+		case <-inflight:
+		case <-ctx.Done():
+			return nil, types.WouldBlock(ctx.Err())
+		}
+		r.lock.Lock()
+	}
+
+	if r.holding {
+		held := r.held
+		r.held = nil
+		r.holding = false
+		r.lock.Unlock()
+		return held, nil
+	}
+	r.lock.Unlock()
+
+	return driver.RecvContext(ctx)
+}
+
+type alreadyPolled struct{}
+
+func (alreadyPolled) Error() string {
+	return "Item already added to poller"
+}
+
+var ErrAlreadyPolled alreadyPolled
+
+type notPolled struct{}
+
+func (notPolled) Error() string {
+	return "Item not added to poller"
+}
+
+var ErrNotPolled notPolled
+
+type nothingToPoll struct{}
+
+func (nothingToPoll) Error() string {
+	return "No item can become ready"
+}
+
+var ErrNothingToPoll nothingToPoll
+
+type connPollUnsupported struct{}
+
+func (connPollUnsupported) Error() string {
+	return "Polling raw connections is not supported on this platform"
+}
+
+var ErrConnPollUnsupported connPollUnsupported
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package gomq
+
+import (
+	"syscall"
+)
+
+type connWatch struct{}
+
+func newConnWatch(conn syscall.Conn) (*connWatch, error) {
+	return nil, ErrConnPollUnsupported
+}
+
+func (w *connWatch) poll(events PollEvent) (PollEvent, []<-chan struct{}, error) {
+	return 0, nil, ErrConnPollUnsupported
+}
@@ -0,0 +1,209 @@
+package gomq_test
+
+import (
+	"context"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/types"
+	_ "github.com/workspace-9/gomq/types/rep"
+	_ "github.com/workspace-9/gomq/types/req"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// aheadDriver is a socket driver without RecvReady, so it is polled by
+// receiving ahead.
+type aheadDriver struct {
+	queue     chan []zmtp.Message
+	receiving atomic.Int32
+}
+
+func (d *aheadDriver) Name() string                                { return "AHEAD" }
+func (d *aheadDriver) Connect(transport.Transport, *url.URL) error { return nil }
+func (d *aheadDriver) Disconnect(*url.URL) error                   { return nil }
+func (d *aheadDriver) Bind(transport.Transport, *url.URL) error    { return nil }
+func (d *aheadDriver) Unbind(*url.URL) error                       { return nil }
+func (d *aheadDriver) Close() error                                { return nil }
+
+func (d *aheadDriver) Send(data []zmtp.Message) error {
+	return d.SendContext(context.Background(), data)
+}
+
+func (d *aheadDriver) SendContext(_ context.Context, data []zmtp.Message) error {
+	d.queue <- data
+	return nil
+}
+
+func (d *aheadDriver) Recv() ([]zmtp.Message, error) {
+	return d.RecvContext(context.Background())
+}
+
+func (d *aheadDriver) RecvContext(ctx context.Context) ([]zmtp.Message, error) {
+	d.receiving.Add(1)
+	defer d.receiving.Add(-1)
+	// Like the built in drivers, prefer a queued message to a done ctx.
+	select {
+	case msg := <-d.queue:
+		return msg, nil
+	default:
+	}
+
+	select {
+	case msg := <-d.queue:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, types.WouldBlock(ctx.Err())
+	}
+}
+
+var ahead = &aheadDriver{queue: make(chan []zmtp.Message, 1)}
+
+func init() {
+	gomq.RegisterSocketType("AHEAD", func(context.Context, zmtp.Mechanism, *gomq.Config, gomq.EventBus) (gomq.SocketDriver, error) {
+		return ahead, nil
+	})
+}
+
+// TestPollerWaitZero checks that a zero timeout reports a message already
+// queued, and that polling does not take it from the socket.
+func TestPollerWaitZero(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	pull, err := ctx.NewSocket("PULL", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pull.Close()
+	pull.SetOption(gomq.OptionRecvTimeout, 5*time.Second)
+	if err := pull.Bind("inproc://poll-zero"); err != nil {
+		t.Fatal(err)
+	}
+	push, err := ctx.NewSocket("PUSH", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer push.Close()
+	if err := push.Connect("inproc://poll-zero"); err != nil {
+		t.Fatal(err)
+	}
+
+	poller := gomq.NewPoller()
+	poller.Add(pull, gomq.PollIn)
+	if ready, err := poller.Wait(0); err != nil || len(ready) != 0 {
+		t.Fatalf("expected nothing ready, got %v (%v)", ready, err)
+	}
+
+	if err := push.Send([][]byte{[]byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, pull, "gomq_queue_messages", gomq.Label{Name: "direction", Value: "recv"}, 1)
+
+	for idx := 0; idx < 2; idx++ {
+		ready, err := poller.Wait(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ready) != 1 || ready[0].Socket != pull || ready[0].Events != gomq.PollIn {
+			t.Fatalf("expected the PULL to be readable, got %v", ready)
+		}
+	}
+	if msg, err := pull.Recv(); err != nil || string(msg[0]) != "hello" {
+		t.Fatalf("expected hello, got %q (%v)", msg, err)
+	}
+}
+
+// TestPollerRepState checks that polling a REP socket leaves its requests
+// to Recv, so it stays unwritable until one is received.
+func TestPollerRepState(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	rep, err := ctx.NewSocket("REP", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rep.Close()
+	if err := rep.Bind("inproc://poll-rep"); err != nil {
+		t.Fatal(err)
+	}
+	req, err := ctx.NewSocket("REQ", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer req.Close()
+	if err := req.Connect("inproc://poll-rep"); err != nil {
+		t.Fatal(err)
+	}
+
+	poller := gomq.NewPoller()
+	poller.Add(rep, gomq.PollIn|gomq.PollOut)
+	poller.Add(req, gomq.PollIn)
+	if err := req.Send([][]byte{[]byte("ping")}); err != nil {
+		t.Fatal(err)
+	}
+
+	ready, err := poller.Wait(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ready) != 1 || ready[0].Socket != rep || ready[0].Events != gomq.PollIn {
+		t.Fatalf("expected the REP to be only readable, got %v", ready)
+	}
+
+	if _, err := rep.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	ready, err = poller.Wait(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ready) != 1 || ready[0].Socket != rep || ready[0].Events != gomq.PollOut {
+		t.Fatalf("expected the REP to be only writable, got %v", ready)
+	}
+}
+
+// TestPollerReceiveAhead checks that a socket without RecvReady reports a
+// queued message at once, and stops receiving in the background once the
+// wait is over.
+func TestPollerReceiveAhead(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	sock, err := ctx.NewSocket("AHEAD", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sock.Close()
+
+	poller := gomq.NewPoller()
+	poller.Add(sock, gomq.PollIn)
+	if ready, err := poller.Wait(20 * time.Millisecond); err != nil || len(ready) != 0 {
+		t.Fatalf("expected nothing ready, got %v (%v)", ready, err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for ahead.receiving.Load() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the background receive to stop with the wait")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := sock.Send([][]byte{[]byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+	ready, err := poller.Wait(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ready) != 1 || ready[0].Events != gomq.PollIn {
+		t.Fatalf("expected the socket to be readable, got %v", ready)
+	}
+	if msg, err := sock.Recv(); err != nil || string(msg[0]) != "hello" {
+		t.Fatalf("expected hello, got %q (%v)", msg, err)
+	}
+}
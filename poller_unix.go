@@ -0,0 +1,111 @@
+//go:build unix
+
+package gomq
+
+import (
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// connWatch waits for a raw connection to become ready using the runtime's
+// network poller, keeping at most one waiter per direction in flight.
+type connWatch struct {
+	raw     syscall.RawConn
+	lock    sync.Mutex
+	reading chan struct{}
+	writing chan struct{}
+}
+
+func newConnWatch(conn syscall.Conn) (*connWatch, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	return &connWatch{raw: raw}, nil
+}
+
+func (w *connWatch) poll(events PollEvent) (PollEvent, []<-chan struct{}, error) {
+	var ready PollEvent
+	if err := w.raw.Control(func(fd uintptr) {
+		ready = pollFD(fd, events)
+	}); err != nil {
+		return 0, nil, err
+	}
+
+	if ready != 0 {
+		return ready, nil, nil
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var changed []<-chan struct{}
+	if events&PollIn != 0 {
+		if w.reading == nil {
+			w.reading = w.wait(&w.reading, w.raw.Read, PollIn)
+		}
+		changed = append(changed, w.reading)
+	}
+
+	if events&PollOut != 0 {
+		if w.writing == nil {
+			w.writing = w.wait(&w.writing, w.raw.Write, PollOut)
+		}
+		changed = append(changed, w.writing)
+	}
+
+	return 0, changed, nil
+}
+
+// wait blocks in the background until the event is ready, or the connection
+// fails, and then closes the returned channel.
+func (w *connWatch) wait(
+	slot *chan struct{},
+	block func(func(uintptr) bool) error,
+	event PollEvent,
+) chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		block(func(fd uintptr) bool {
+			return pollFD(fd, event) != 0
+		})
+
+		w.lock.Lock()
+		defer w.lock.Unlock()
+		*slot = nil
+	}()
+
+	return done
+}
+
+// pollFD returns the events ready on fd without waiting. Errors and hangups
+// count as ready, since reading or writing would not block.
+func pollFD(fd uintptr, events PollEvent) PollEvent {
+	pfd := []unix.PollFd{{Fd: int32(fd)}}
+	if events&PollIn != 0 {
+		pfd[0].Events |= unix.POLLIN
+	}
+	if events&PollOut != 0 {
+		pfd[0].Events |= unix.POLLOUT
+	}
+
+	n, err := unix.Poll(pfd, 0)
+	if err != nil || n == 0 {
+		return 0
+	}
+
+	var ready PollEvent
+	failed := pfd[0].Revents&(unix.POLLERR|unix.POLLHUP|unix.POLLNVAL) != 0
+	if events&PollIn != 0 && (pfd[0].Revents&unix.POLLIN != 0 || failed) {
+		ready |= PollIn
+	}
+	if events&PollOut != 0 && (pfd[0].Revents&unix.POLLOUT != 0 || failed) {
+		ready |= PollOut
+	}
+
+	return ready
+}
@@ -0,0 +1,79 @@
+package gomq
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogBus logs each event to a slog.Logger with typed attributes.
+type SlogBus struct {
+	// Logger receives the events, slog.Default() if nil.
+	Logger *slog.Logger
+
+	// Levels overrides DefaultEventLevel for the event types it holds.
+	Levels map[EventType]slog.Level
+}
+
+// DefaultEventLevel is the level SlogBus logs an event type at: failures
+// are warnings, reconnect attempts are debug and the rest is info.
+func DefaultEventLevel(typ EventType) slog.Level {
+	switch typ {
+	case EventTypeConnectFailed, EventTypeAcceptFailed, EventTypeFailedGreeting,
+		EventTypeFailedHandshake, EventTypeHeartbeatFailed, EventTypeMessagesDiscarded,
+		EventTypeReconnectStopped, EventTypeBindFailed:
+		return slog.LevelWarn
+	case EventTypeConnectRetried:
+		return slog.LevelDebug
+	}
+
+	return slog.LevelInfo
+}
+
+// Level returns the level events of the type are logged at.
+func (b SlogBus) Level(typ EventType) slog.Level {
+	if level, ok := b.Levels[typ]; ok {
+		return level
+	}
+
+	return DefaultEventLevel(typ)
+}
+
+func (b SlogBus) Post(ev Event) {
+	logger := b.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	ctx := context.Background()
+	level := b.Level(ev.EventType)
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{slog.String("event", ev.EventType.String())}
+	for _, attr := range []slog.Attr{
+		slog.String("socket_type", ev.SocketType),
+		slog.String("mechanism", ev.Mechanism),
+		slog.String("local", ev.LocalAddr),
+		slog.String("remote", ev.RemoteAddr),
+	} {
+		if attr.Value.String() != "" {
+			attrs = append(attrs, attr)
+		}
+	}
+	if ev.Err != nil {
+		attrs = append(attrs, slog.Any("error", ev.Err))
+	}
+	// Notes repeat the error's text unless they say something more.
+	if ev.Notes != "" && (ev.Err == nil || ev.Notes != ev.Err.Error()) {
+		attrs = append(attrs, slog.String("notes", ev.Notes))
+	}
+
+	logger.LogAttrs(ctx, level, "gomq event", attrs...)
+}
+
+// SetLogger makes sockets created afterwards by NewSocket log their events
+// to logger through a SlogBus.
+func (c *Context) SetLogger(logger *slog.Logger) {
+	c.SetEventBus(SlogBus{Logger: logger})
+}
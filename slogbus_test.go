@@ -0,0 +1,118 @@
+package gomq_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/workspace-9/gomq"
+)
+
+// logRecords decodes the JSON records written by a slog.JSONHandler.
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		record := map[string]any{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// TestSlogBusAttributes checks that events are logged with typed
+// attributes at their level, leaving out empty ones and notes which only
+// repeat the error.
+func TestSlogBusAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	bus := gomq.SlogBus{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}
+
+	err := errors.New("connection refused")
+	bus.Post(gomq.Event{
+		EventType:  gomq.EventTypeConnectFailed,
+		RemoteAddr: "tcp://127.0.0.1:5555",
+		SocketType: "PUSH",
+		Mechanism:  "NULL",
+		Notes:      err.Error(),
+		Err:        err,
+	})
+	bus.Post(gomq.Event{EventType: gomq.EventTypeListening, LocalAddr: "tcp://127.0.0.1:5556", Notes: "ready"})
+
+	records := logRecords(t, &buf)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	want := map[string]any{
+		"level":       "WARN",
+		"msg":         "gomq event",
+		"event":       "Connect failed",
+		"socket_type": "PUSH",
+		"mechanism":   "NULL",
+		"remote":      "tcp://127.0.0.1:5555",
+		"error":       "connection refused",
+	}
+	for key, value := range want {
+		if records[0][key] != value {
+			t.Errorf("expected %s=%v, got %v", key, value, records[0][key])
+		}
+	}
+	for _, key := range []string{"local", "notes"} {
+		if _, ok := records[0][key]; ok {
+			t.Errorf("expected no %s, got %v", key, records[0][key])
+		}
+	}
+	if records[1]["level"] != "INFO" || records[1]["local"] != "tcp://127.0.0.1:5556" || records[1]["notes"] != "ready" {
+		t.Errorf("expected the listening event at info with its notes, got %v", records[1])
+	}
+}
+
+// TestSlogBusLevels checks that per type levels override the defaults, and
+// that events below the logger's level are not logged.
+func TestSlogBusLevels(t *testing.T) {
+	var buf bytes.Buffer
+	bus := gomq.SlogBus{
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		Levels: map[gomq.EventType]slog.Level{gomq.EventTypeAccepted: slog.LevelError},
+	}
+
+	bus.Post(gomq.Event{EventType: gomq.EventTypeConnectRetried})
+	bus.Post(gomq.Event{EventType: gomq.EventTypeAccepted})
+
+	records := logRecords(t, &buf)
+	if len(records) != 1 || records[0]["event"] != "Accepted" || records[0]["level"] != "ERROR" {
+		t.Fatalf("expected only the accepted event at error, got %v", records)
+	}
+}
+
+// TestSetLogger checks that sockets made after SetLogger log their events
+// to the logger.
+func TestSetLogger(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	pull, err := ctx.NewSocket("PULL", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pull.Bind("tcp://127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	pull.Close()
+
+	for _, record := range logRecords(t, &buf) {
+		if record["event"] == "Listening" && record["socket_type"] == "PULL" {
+			return
+		}
+	}
+	t.Fatalf("expected the PULL to log that it is listening, got %s", buf.String())
+}
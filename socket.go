@@ -1,15 +1,27 @@
 package gomq
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/url"
+	"time"
 
+	"github.com/workspace-9/gomq/types"
 	"github.com/workspace-9/gomq/zmtp"
 )
 
 type Socket struct {
 	driver SocketDriver
 	mech   zmtp.Mechanism
+	conf   *Config
 	ctx    *Context
+	ahead  *readAhead
+	bus    *monitorBus
+	id     uint64
+
+	// metrics count the traffic through the socket's endpoints.
+	metrics *SocketMetrics
 }
 
 func (s Socket) Connect(addr string) error {
@@ -56,7 +68,30 @@ func (s Socket) Bind(addr string) error {
 	return nil
 }
 
-func (s Socket) Send(data [][]byte) error {
+// Flag changes how a single Send or Recv behaves.
+type Flag int
+
+const (
+	// DontWait fails the call with types.ErrWouldBlock instead of waiting.
+	DontWait Flag = 1 << iota
+)
+
+// Send the message, waiting up to the send timeout if it is set.
+func (s Socket) Send(data [][]byte, flags ...Flag) error {
+	return s.SendContext(context.Background(), data, flags...)
+}
+
+// SendContext sends the message, waiting until ctx is done or the send
+// timeout passes. Giving up fails with an error matching
+// types.ErrWouldBlock, while closing the socket fails with
+// context.Canceled alone.
+func (s Socket) SendContext(ctx context.Context, data [][]byte, flags ...Flag) error {
+	ctx, cancel := s.callContext(ctx, s.conf.SendTimeout(), flags)
+	defer cancel()
+	return s.driver.SendContext(ctx, toMessages(data))
+}
+
+func toMessages(data [][]byte) []zmtp.Message {
 	messages := make([]zmtp.Message, len(data))
 	for idx, datum := range data {
 		messages[idx] = zmtp.Message{
@@ -64,11 +99,60 @@ func (s Socket) Send(data [][]byte) error {
 		}
 	}
 
-	return s.driver.Send(messages)
+	return messages
+}
+
+// Message is a message of one frame, as used by socket types such as
+// CLIENT, SERVER, RADIO and DISH which do not send multipart messages.
+type Message struct {
+	Body []byte
+
+	// RoutingID names the peer a SERVER received the message from, or sends
+	// it to.
+	RoutingID uint32
+
+	// Group is the group a RADIO sends the message to, or a DISH received it
+	// from.
+	Group string
+}
+
+// SendMsg sends a message of one frame, waiting up to the send timeout if
+// it is set.
+func (s Socket) SendMsg(msg Message, flags ...Flag) error {
+	return s.SendMsgContext(context.Background(), msg, flags...)
+}
+
+// SendMsgContext sends a message of one frame, failing like SendContext.
+func (s Socket) SendMsgContext(ctx context.Context, msg Message, flags ...Flag) error {
+	ctx, cancel := s.callContext(ctx, s.conf.SendTimeout(), flags)
+	defer cancel()
+	return s.driver.SendContext(ctx, []zmtp.Message{{
+		Body: msg.Body, RoutingID: msg.RoutingID, Group: msg.Group,
+	}})
+}
+
+// SendGroup sends body to group, for socket types such as RADIO which send
+// messages to groups.
+func (s Socket) SendGroup(group string, body []byte, flags ...Flag) error {
+	return s.SendMsg(Message{Body: body, Group: group}, flags...)
+}
+
+// SendGroupContext sends body to group, failing like SendContext.
+func (s Socket) SendGroupContext(ctx context.Context, group string, body []byte, flags ...Flag) error {
+	return s.SendMsgContext(ctx, Message{Body: body, Group: group}, flags...)
+}
+
+// Recv the next message, waiting up to the receive timeout if it is set.
+func (s Socket) Recv(flags ...Flag) ([][]byte, error) {
+	return s.RecvContext(context.Background(), flags...)
 }
 
-func (s Socket) Recv() ([][]byte, error) {
-	messages, err := s.driver.Recv()
+// RecvContext receives the next message, waiting until ctx is done or the
+// receive timeout passes, failing like SendContext.
+func (s Socket) RecvContext(ctx context.Context, flags ...Flag) ([][]byte, error) {
+	ctx, cancel := s.callContext(ctx, s.conf.RecvTimeout(), flags)
+	defer cancel()
+	messages, err := s.ahead.recv(ctx, s.driver)
 	if err != nil {
 		return nil, err
 	}
@@ -81,10 +165,84 @@ func (s Socket) Recv() ([][]byte, error) {
 	return data, nil
 }
 
+// RecvMsg receives the next message of one frame, waiting up to the
+// receive timeout if it is set.
+func (s Socket) RecvMsg(flags ...Flag) (Message, error) {
+	return s.RecvMsgContext(context.Background(), flags...)
+}
+
+// RecvMsgContext receives the next message of one frame, failing like
+// RecvContext. Messages of more than one frame fail with
+// types.ErrMultipartUnsupported.
+func (s Socket) RecvMsgContext(ctx context.Context, flags ...Flag) (Message, error) {
+	ctx, cancel := s.callContext(ctx, s.conf.RecvTimeout(), flags)
+	defer cancel()
+	messages, err := s.ahead.recv(ctx, s.driver)
+	if err != nil {
+		return Message{}, err
+	}
+
+	if len(messages) != 1 {
+		return Message{}, fmt.Errorf("%w: %d frames", types.ErrMultipartUnsupported, len(messages))
+	}
+
+	msg := messages[0]
+	return Message{Body: msg.Body, RoutingID: msg.RoutingID, Group: msg.Group}, nil
+}
+
+// RecvGroup receives the next message along with the group it was sent to,
+// for socket types such as DISH which receive messages of groups.
+func (s Socket) RecvGroup(flags ...Flag) (group string, body []byte, err error) {
+	return s.RecvGroupContext(context.Background(), flags...)
+}
+
+// RecvGroupContext receives the next message along with its group, failing
+// like RecvMsgContext.
+func (s Socket) RecvGroupContext(ctx context.Context, flags ...Flag) (group string, body []byte, err error) {
+	msg, err := s.RecvMsgContext(ctx, flags...)
+	return msg.Group, msg.Body, err
+}
+
+// callContext bounds ctx by the timeout, or makes it already done if
+// DontWait is set. A negative timeout waits indefinitely.
+func (s Socket) callContext(ctx context.Context, timeout time.Duration, flags []Flag) (context.Context, context.CancelFunc) {
+	for _, flag := range flags {
+		if flag&DontWait != 0 {
+			timeout = 0
+		}
+	}
+
+	if timeout < 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Close the socket, lingering to send queued messages if the socket type
+// queues them and a linger period is set.
 func (s Socket) Close() error {
+	return s.CloseContext(context.Background())
+}
+
+// CloseContext closes the socket, lingering no longer than until ctx is done.
+func (s Socket) CloseContext(ctx context.Context) error {
+	s.ctx.forget(s.driver)
+	defer s.bus.stop()
+	if closer, ok := s.driver.(GracefulCloser); ok {
+		return closer.CloseContext(ctx)
+	}
+
 	return s.driver.Close()
 }
 
+// Monitor returns a channel receiving the socket's events whose types are
+// in mask. The channel buffers MonitorQueueLen events, dropping any more
+// until it is read, and is closed when the socket is closed.
+func (s Socket) Monitor(mask EventMask) <-chan Event {
+	return s.bus.add(mask)
+}
+
 func (s Socket) Disconnect(addr string) error {
 	url, err := url.Parse(addr)
 	if err != nil {
@@ -101,7 +259,82 @@ func (s Socket) Unbind(addr string) error {
 	return s.driver.Unbind(url)
 }
 
+// Subscribe to messages whose first frame starts with topic.
+func (s Socket) Subscribe(topic []byte) error {
+	sub, ok := s.driver.(Subscriber)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotSubscriber, s.driver.Name())
+	}
+
+	return sub.Subscribe(topic)
+}
+
+// Unsubscribe from a topic previously passed to Subscribe.
+func (s Socket) Unsubscribe(topic []byte) error {
+	sub, ok := s.driver.(Subscriber)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotSubscriber, s.driver.Name())
+	}
+
+	return sub.Unsubscribe(topic)
+}
+
+type notSubscriber struct{}
+
+func (notSubscriber) Error() string {
+	return "Socket type does not support subscriptions"
+}
+
+var ErrNotSubscriber notSubscriber
+
+// Join the group, receiving the messages sent to it.
+func (s Socket) Join(group string) error {
+	joiner, ok := s.driver.(Joiner)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotJoiner, s.driver.Name())
+	}
+
+	return joiner.Join(group)
+}
+
+// Leave a group previously passed to Join.
+func (s Socket) Leave(group string) error {
+	joiner, ok := s.driver.(Joiner)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotJoiner, s.driver.Name())
+	}
+
+	return joiner.Leave(group)
+}
+
+type notJoiner struct{}
+
+func (notJoiner) Error() string {
+	return "Socket type does not support groups"
+}
+
+var ErrNotJoiner notJoiner
+
+// Config returns the socket's config. Changes apply to connections made
+// afterwards.
+func (s Socket) Config() *Config {
+	return s.conf
+}
+
+// SetOption sets an option on the socket's config, or on the socket type if
+// it recognises the option, and on the mechanism otherwise.
 func (s Socket) SetOption(option string, val any) error {
+	if err := s.conf.SetOption(option, val); !errors.Is(err, ErrUnknownOption) {
+		return err
+	}
+
+	if setter, ok := s.driver.(OptionSetter); ok {
+		err := setter.SetOption(option, val)
+		if !errors.Is(err, ErrUnknownOption) {
+			return err
+		}
+	}
+
 	return s.mech.SetOption(option, val)
 }
 
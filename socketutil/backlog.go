@@ -0,0 +1,110 @@
+package socketutil
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// Backlog counts messages accepted for sending but not yet written to a
+// peer, so that a closing socket can linger until they drain. The zero value
+// is ready for use.
+type Backlog struct {
+	lock    sync.Mutex
+	count   int
+	drained chan struct{}
+}
+
+// Add counts n more messages.
+func (b *Backlog) Add(n int) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.count += n
+}
+
+// Done stops counting n messages, because they were written or dropped.
+func (b *Backlog) Done(n int) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.count -= n
+	if b.count <= 0 && b.drained != nil {
+		close(b.drained)
+		b.drained = nil
+	}
+}
+
+// Len returns the number of messages counted.
+func (b *Backlog) Len() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.count
+}
+
+// Wait until no messages are counted or ctx is done.
+func (b *Backlog) Wait(ctx context.Context) error {
+	b.lock.Lock()
+	if b.count <= 0 {
+		b.lock.Unlock()
+		return nil
+	}
+
+	if b.drained == nil {
+		b.drained = make(chan struct{})
+	}
+	drained := b.drained
+	b.lock.Unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Drain empties the queue, no longer counting what it held.
+func (b *Backlog) Drain(queue chan []zmtp.Message) {
+	for {
+		select {
+		case <-queue:
+			b.Done(1)
+		default:
+			return
+		}
+	}
+}
+
+// Linger waits for the backlog to drain for up to linger, or indefinitely if
+// linger is negative, stopping early if ctx is done. It returns how many
+// messages are left.
+func (b *Backlog) Linger(ctx context.Context, linger time.Duration) int {
+	if linger == 0 {
+		return b.Len()
+	}
+
+	if linger > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, linger)
+		defer cancel()
+	}
+
+	b.Wait(ctx)
+	return b.Len()
+}
+
+// PostDiscarded reports messages a closing socket could not send, if any.
+func PostDiscarded(eventBus gomq.EventBus, discarded int) {
+	if discarded <= 0 {
+		return
+	}
+
+	eventBus.Post(gomq.Event{
+		EventType: gomq.EventTypeMessagesDiscarded,
+		Notes:     fmt.Sprintf("%d queued messages discarded on close", discarded),
+	})
+}
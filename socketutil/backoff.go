@@ -0,0 +1,40 @@
+package socketutil
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff computes the delay before each reconnect attempt. Without a Max
+// every delay is Ivl. With one, the delay doubles after each attempt up to
+// Max, and each delay is picked at random from its upper half so that many
+// clients of one server do not retry in lockstep.
+type Backoff struct {
+	Ivl      time.Duration
+	Max      time.Duration
+	attempts int
+}
+
+// Next returns the delay before the next attempt.
+func (b *Backoff) Next() time.Duration {
+	if b.Max <= b.Ivl || b.Ivl <= 0 {
+		return b.Ivl
+	}
+
+	delay := b.Ivl
+	for n := 0; n < b.attempts && delay < b.Max; n++ {
+		delay *= 2
+	}
+	if delay > b.Max {
+		delay = b.Max
+	}
+	b.attempts++
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// Reset the backoff after a successful attempt.
+func (b *Backoff) Reset() {
+	b.attempts = 0
+}
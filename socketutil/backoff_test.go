@@ -0,0 +1,33 @@
+package socketutil_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq/socketutil"
+)
+
+// TestBackoffGrows checks that delays double up to the cap, each picked
+// from the upper half of its interval, and start over after Reset.
+func TestBackoffGrows(t *testing.T) {
+	backoff := socketutil.Backoff{Ivl: 10 * time.Millisecond, Max: 80 * time.Millisecond}
+	for round := 0; round < 2; round++ {
+		for _, upper := range []time.Duration{10, 20, 40, 80, 80, 80} {
+			upper *= time.Millisecond
+			if delay := backoff.Next(); delay < upper/2 || delay > upper {
+				t.Fatalf("expected a delay within [%s, %s], got %s", upper/2, upper, delay)
+			}
+		}
+		backoff.Reset()
+	}
+}
+
+// TestBackoffFixed checks that without a cap every delay is the interval.
+func TestBackoffFixed(t *testing.T) {
+	backoff := socketutil.Backoff{Ivl: 10 * time.Millisecond}
+	for idx := 0; idx < 5; idx++ {
+		if delay := backoff.Next(); delay != 10*time.Millisecond {
+			t.Fatalf("expected 10ms, got %s", delay)
+		}
+	}
+}
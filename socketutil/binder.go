@@ -16,6 +16,7 @@ type BindDriver struct {
 	transport   transport.Transport
 	mechanism   zmtp.Mechanism
 	url         *url.URL
+	config      *gomq.Config
 	handler     SocketHandler
 	eventBus    gomq.EventBus
 	meta        MetadataProvider
@@ -31,6 +32,12 @@ func (b *BindDriver) Close() error {
 		err = b.ln.Close()
 	}
 	<-b.done
+	if b.ln != nil {
+		b.eventBus.Post(gomq.Event{
+			EventType: gomq.EventTypeClosed,
+			LocalAddr: transport.BuildURL(b.ln.Addr(), b.transport),
+		})
+	}
 	return err
 }
 
@@ -39,6 +46,7 @@ func (b *BindDriver) Setup(
 	tp transport.Transport,
 	mech zmtp.Mechanism,
 	url *url.URL,
+	conf *gomq.Config,
 	handler SocketHandler,
 	eventBus gomq.EventBus,
 	meta MetadataProvider,
@@ -50,6 +58,7 @@ func (b *BindDriver) Setup(
 	b.transport = tp
 	b.mechanism = mech
 	b.url = url
+	b.config = conf
 	b.handler = handler
 	b.eventBus = eventBus
 	b.meta = meta
@@ -60,9 +69,19 @@ func (b *BindDriver) Setup(
 func (b *BindDriver) TryBind() error {
 	listener, err := b.transport.Bind(b.url)
 	if err != nil {
+		b.eventBus.Post(gomq.Event{
+			EventType: gomq.EventTypeBindFailed,
+			LocalAddr: b.url.String(),
+			Notes:     err.Error(),
+			Err:       err,
+		})
 		return err
 	}
 	b.ln = listener
+	b.eventBus.Post(gomq.Event{
+		EventType: gomq.EventTypeListening,
+		LocalAddr: transport.BuildURL(listener.Addr(), b.transport),
+	})
 	return nil
 }
 
@@ -86,20 +105,22 @@ func (b *BindDriver) run() error {
 
 		conn, err := b.ln.Accept()
 		if err != nil {
+			if b.ctx.Err() != nil {
+				return b.ctx.Err()
+			}
 			b.eventBus.Post(gomq.Event{
-				gomq.EventTypeAcceptFailed,
-				b.url.String(),
-				"",
-				err.Error(),
+				EventType: gomq.EventTypeAcceptFailed,
+				LocalAddr: b.url.String(),
+				Notes:     err.Error(),
+				Err:       err,
 			})
 			continue
 		}
 
 		b.eventBus.Post(gomq.Event{
-			gomq.EventTypeAccepted,
-			transport.BuildURL(conn.LocalAddr(), b.transport),
-			transport.BuildURL(conn.RemoteAddr(), b.transport),
-			"",
+			EventType:  gomq.EventTypeAccepted,
+			LocalAddr:  transport.BuildURL(conn.LocalAddr(), b.transport),
+			RemoteAddr: transport.BuildURL(conn.RemoteAddr(), b.transport),
 		})
 
 		go b.handleConn(conn)
@@ -107,74 +128,61 @@ func (b *BindDriver) run() error {
 }
 
 func (b *BindDriver) handleConn(conn net.Conn) {
-	greeting := zmtp.NewGreeting()
-	greeting.SetVersionMajor(3)
-	greeting.SetVersionMinor(1)
-	greeting.SetMechanism(b.mechanism.Name())
-	greeting.SetServer(b.mechanism.Server())
-	if _, err := greeting.WriteTo(conn); err != nil {
-		b.eventBus.Post(gomq.Event{
-			gomq.EventTypeFailedGreeting,
-			transport.BuildURL(conn.LocalAddr(), b.transport),
-			transport.BuildURL(conn.RemoteAddr(), b.transport),
-			err.Error(),
-		})
-		return
-	}
-
-	if _, err := greeting.ReadFrom(conn); err != nil {
-		b.eventBus.Post(gomq.Event{
-			gomq.EventTypeFailedGreeting,
-			transport.BuildURL(conn.LocalAddr(), b.transport),
-			transport.BuildURL(conn.RemoteAddr(), b.transport),
-			err.Error(),
-		})
-		return
-	}
-
-	if err := b.mechanism.ValidateGreeting(&greeting); err != nil {
-		b.eventBus.Post(gomq.Event{
-			gomq.EventTypeFailedGreeting,
-			transport.BuildURL(conn.LocalAddr(), b.transport),
-			transport.BuildURL(conn.RemoteAddr(), b.transport),
-			err.Error(),
-		})
-		return
-	}
-
-	sock, meta, err := b.mechanism.Handshake(conn, b.meta())
+	sock, greeting, meta, failed, err := setUp(conn, b.mechanism, b.meta)
 	if err != nil {
 		b.eventBus.Post(gomq.Event{
-			gomq.EventTypeFailedHandshake,
-			transport.BuildURL(conn.LocalAddr(), b.transport),
-			transport.BuildURL(conn.RemoteAddr(), b.transport),
-			err.Error(),
+			EventType:  failed,
+			LocalAddr:  transport.BuildURL(conn.LocalAddr(), b.transport),
+			RemoteAddr: transport.BuildURL(conn.RemoteAddr(), b.transport),
+			Notes:      err.Error(),
+			Err:        err,
 		})
+		b.handshakeFailed(conn)
 		return
 	}
 
 	if err := b.metaHandler(meta); err != nil {
 		b.eventBus.Post(gomq.Event{
-			gomq.EventTypeFailedHandshake,
-			transport.BuildURL(conn.LocalAddr(), b.transport),
-			transport.BuildURL(conn.RemoteAddr(), b.transport),
-			err.Error(),
+			EventType:  gomq.EventTypeFailedHandshake,
+			LocalAddr:  transport.BuildURL(conn.LocalAddr(), b.transport),
+			RemoteAddr: transport.BuildURL(conn.RemoteAddr(), b.transport),
+			Notes:      err.Error(),
+			Err:        err,
 		})
+		b.handshakeFailed(conn)
 		return
 	}
 
 	b.eventBus.Post(gomq.Event{
-		gomq.EventTypeReady,
-		transport.BuildURL(conn.LocalAddr(), b.transport),
-		transport.BuildURL(conn.RemoteAddr(), b.transport),
-		"",
+		EventType:  gomq.EventTypeReady,
+		LocalAddr:  transport.BuildURL(conn.LocalAddr(), b.transport),
+		RemoteAddr: transport.BuildURL(conn.RemoteAddr(), b.transport),
 	})
 
-	b.handler(b.ctx, sock)
-	b.eventBus.Post(gomq.Event{
-		gomq.EventTypeDisconnected,
-		transport.BuildURL(conn.LocalAddr(), b.transport),
-		transport.BuildURL(conn.RemoteAddr(), b.transport),
-		"",
-	})
+	peer := NewPeer(sock, greeting, meta)
+	peer.Metrics = gomq.SocketMetricsFrom(b.ctx).Endpoint(b.url.String())
+	// Handlers blocked reading from the peer only notice the socket closing
+	// once the connection is closed.
+	stopClosing := context.AfterFunc(b.ctx, func() { peer.Close() })
+	defer stopClosing()
+	stopHeartbeat := StartHeartbeat(b.ctx, peer, b.config, b.eventBus, b.transport)
+	err = b.handler(b.ctx, peer)
+	stopHeartbeat()
+	peer.Close()
+	ev := gomq.Event{
+		EventType:  gomq.EventTypeDisconnected,
+		LocalAddr:  transport.BuildURL(conn.LocalAddr(), b.transport),
+		RemoteAddr: transport.BuildURL(conn.RemoteAddr(), b.transport),
+		Err:        err,
+	}
+	if err != nil {
+		ev.Notes = err.Error()
+	}
+	b.eventBus.Post(ev)
+}
+
+// handshakeFailed closes a connection which could not be set up.
+func (b *BindDriver) handshakeFailed(conn net.Conn) {
+	conn.Close()
+	gomq.SocketMetricsFrom(b.ctx).Endpoint(b.url.String()).CountHandshakeFailure()
 }
@@ -2,7 +2,12 @@ package socketutil
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/url"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/workspace-9/gomq"
@@ -10,7 +15,7 @@ import (
 	"github.com/workspace-9/gomq/zmtp"
 )
 
-type SocketHandler func(context.Context, zmtp.Socket) error
+type SocketHandler func(context.Context, *Peer) error
 
 type MetadataProvider func() zmtp.Metadata
 
@@ -19,7 +24,8 @@ type MetadataHandler func(zmtp.Metadata) error
 type ConnectionDriver struct {
 	ctx                context.Context
 	mechanism          zmtp.Mechanism
-	socket             zmtp.Socket
+	socket             *Peer
+	socketLock         sync.Mutex
 	transport          transport.Transport
 	url                *url.URL
 	config             *gomq.Config
@@ -30,6 +36,9 @@ type ConnectionDriver struct {
 	cancelFunc         context.CancelFunc
 	done               chan struct{}
 	lastConnectAttempt time.Time
+	lastConnectErr     error
+	backoff            Backoff
+	failures           int
 }
 
 type ConnectionDriverHandle struct {
@@ -40,96 +49,84 @@ type ConnectionDriverHandle struct {
 func (c *ConnectionDriver) Close() error {
 	c.cancelFunc()
 	var err error
-	if c.socket != nil {
-		err = c.socket.Close()
+	c.socketLock.Lock()
+	socket := c.socket
+	c.socketLock.Unlock()
+	if socket != nil {
+		err = socket.Close()
 	}
 	<-c.done
 	return err
 }
 
+// setSocket replaces the connected peer, which Close reads from another
+// goroutine.
+func (c *ConnectionDriver) setSocket(peer *Peer) {
+	c.socketLock.Lock()
+	defer c.socketLock.Unlock()
+	c.socket = peer
+}
+
+// TryConnect connects to the url, unless the transport knows nothing is
+// bound there yet, in which case the connection is left pending for Run.
 func (c *ConnectionDriver) TryConnect() (fatal bool, err error) {
+	select {
+	case <-c.bound():
+	default:
+		return false, nil
+	}
+
 	c.lastConnectAttempt = time.Now()
+	c.lastConnectErr = nil
 	ctx, cancel := context.WithTimeout(c.ctx, c.config.ConnectTimeout())
 	conn, fatal, err := c.transport.Connect(ctx, c.url)
 	cancel()
 	if err != nil {
 		c.eventBus.Post(gomq.Event{
-			gomq.EventTypeConnectFailed,
-			"",
-			c.url.String(),
-			err.Error(),
+			EventType:  gomq.EventTypeConnectFailed,
+			RemoteAddr: c.url.String(),
+			Notes:      err.Error(),
+			Err:        err,
 		})
+		c.lastConnectErr = err
 		return fatal, err
 	}
 	c.eventBus.Post(gomq.Event{
-		gomq.EventTypeConnected,
-		transport.BuildURL(conn.LocalAddr(), c.transport),
-		transport.BuildURL(conn.RemoteAddr(), c.transport),
-		"",
+		EventType:  gomq.EventTypeConnected,
+		LocalAddr:  transport.BuildURL(conn.LocalAddr(), c.transport),
+		RemoteAddr: transport.BuildURL(conn.RemoteAddr(), c.transport),
 	})
 
-	greeting := zmtp.NewGreeting()
-	greeting.SetVersionMajor(3)
-	greeting.SetVersionMinor(1)
-	greeting.SetMechanism(c.mechanism.Name())
-	greeting.SetServer(c.mechanism.Server())
-	if _, err := greeting.WriteTo(conn); err != nil {
-		c.eventBus.Post(gomq.Event{
-			gomq.EventTypeFailedGreeting,
-			transport.BuildURL(conn.LocalAddr(), c.transport),
-			transport.BuildURL(conn.RemoteAddr(), c.transport),
-			err.Error(),
-		})
-		return false, err
-	}
-
-	if _, err := greeting.ReadFrom(conn); err != nil {
-		c.eventBus.Post(gomq.Event{
-			gomq.EventTypeFailedGreeting,
-			transport.BuildURL(conn.LocalAddr(), c.transport),
-			transport.BuildURL(conn.RemoteAddr(), c.transport),
-			err.Error(),
-		})
-		return false, err
-	}
-
-	if err := c.mechanism.ValidateGreeting(&greeting); err != nil {
-		c.eventBus.Post(gomq.Event{
-			gomq.EventTypeFailedGreeting,
-			transport.BuildURL(conn.LocalAddr(), c.transport),
-			transport.BuildURL(conn.RemoteAddr(), c.transport),
-			err.Error(),
-		})
-		return false, err
-	}
-
-	sock, meta, err := c.mechanism.Handshake(conn, c.meta())
+	sock, greeting, meta, failed, err := setUp(conn, c.mechanism, c.meta)
 	if err != nil {
 		c.eventBus.Post(gomq.Event{
-			gomq.EventTypeFailedHandshake,
-			transport.BuildURL(conn.LocalAddr(), c.transport),
-			transport.BuildURL(conn.RemoteAddr(), c.transport),
-			err.Error(),
+			EventType:  failed,
+			LocalAddr:  transport.BuildURL(conn.LocalAddr(), c.transport),
+			RemoteAddr: transport.BuildURL(conn.RemoteAddr(), c.transport),
+			Notes:      err.Error(),
+			Err:        err,
 		})
-		return false, err
+		return false, c.handshakeFailed(conn, err)
 	}
 
 	if err := c.metaHandler(meta); err != nil {
 		c.eventBus.Post(gomq.Event{
-			gomq.EventTypeFailedHandshake,
-			transport.BuildURL(conn.LocalAddr(), c.transport),
-			transport.BuildURL(conn.RemoteAddr(), c.transport),
-			err.Error(),
+			EventType:  gomq.EventTypeFailedHandshake,
+			LocalAddr:  transport.BuildURL(conn.LocalAddr(), c.transport),
+			RemoteAddr: transport.BuildURL(conn.RemoteAddr(), c.transport),
+			Notes:      err.Error(),
+			Err:        err,
 		})
-		return false, err
+		return false, c.handshakeFailed(conn, err)
 	}
 
-	c.socket = sock
+	peer := NewPeer(sock, greeting, meta)
+	peer.Metrics = gomq.SocketMetricsFrom(c.ctx).Endpoint(c.url.String())
+	c.setSocket(peer)
 	c.eventBus.Post(gomq.Event{
-		gomq.EventTypeReady,
-		transport.BuildURL(conn.LocalAddr(), c.transport),
-		transport.BuildURL(conn.RemoteAddr(), c.transport),
-		"",
+		EventType:  gomq.EventTypeReady,
+		LocalAddr:  transport.BuildURL(conn.LocalAddr(), c.transport),
+		RemoteAddr: transport.BuildURL(conn.RemoteAddr(), c.transport),
 	})
 	return false, nil
 }
@@ -166,32 +163,144 @@ func (c *ConnectionDriver) Run() {
 }
 
 func (c *ConnectionDriver) run() error {
+	c.backoff = Backoff{Ivl: c.config.ReconnectTimeout(), Max: c.config.ReconnectIvlMax()}
+	var retryAt time.Time
+	if c.socket == nil && !c.lastConnectAttempt.IsZero() {
+		// The socket type made the first attempt when Connect was called.
+		if c.connectFailed(c.lastConnectErr) {
+			return c.lastConnectErr
+		}
+		retryAt = c.retry(c.lastConnectAttempt)
+	}
+
 	for {
 		if err := c.ctx.Err(); err != nil {
 			return c.ctx.Err()
 		}
 
 		if c.socket == nil {
-			if !c.lastConnectAttempt.IsZero() {
-				time.Sleep(c.config.ReconnectTimeout() - time.Since(c.lastConnectAttempt))
+			c.sleep(time.Until(retryAt))
+			select {
+			case <-c.bound():
+			case <-c.ctx.Done():
+				return c.ctx.Err()
 			}
-			_, err := c.TryConnect()
-			if err != nil {
-				time.Sleep(c.config.ReconnectTimeout() - time.Since(c.lastConnectAttempt))
+
+			if _, err := c.TryConnect(); err != nil {
+				if c.connectFailed(err) {
+					return err
+				}
+				retryAt = c.retry(c.lastConnectAttempt)
+				continue
+			}
+			if c.socket == nil {
+				// The address was unbound again before the attempt.
 				continue
 			}
+			c.failures = 0
+			c.backoff.Reset()
 		}
 
+		stopHeartbeat := StartHeartbeat(c.ctx, c.socket, c.config, c.eventBus, c.transport)
 		err := c.handler(c.ctx, c.socket)
+		stopHeartbeat()
 		if err != nil {
 			c.eventBus.Post(gomq.Event{
-				gomq.EventTypeDisconnected,
-				transport.BuildURL(c.socket.Net().LocalAddr(), c.transport),
-				transport.BuildURL(c.socket.Net().RemoteAddr(), c.transport),
-				err.Error(),
+				EventType:  gomq.EventTypeDisconnected,
+				LocalAddr:  transport.BuildURL(c.socket.Net().LocalAddr(), c.transport),
+				RemoteAddr: transport.BuildURL(c.socket.Net().RemoteAddr(), c.transport),
+				Notes:      err.Error(),
+				Err:        err,
 			})
-			time.Sleep(c.config.ReconnectTimeout())
-			c.socket = nil
+			c.socket.Close()
+			c.setSocket(nil)
+			if c.ctx.Err() != nil {
+				return err
+			}
+			if c.config.ReconnectStop()&gomq.ReconnectStopAfterDisconnect != 0 {
+				c.stopReconnecting("connection lost", err)
+				return err
+			}
+			retryAt = c.retry(time.Now())
 		}
 	}
 }
+
+// bound returns a channel which is closed once the url can be connected to,
+// which is at once unless the transport is a transport.PendingTransport.
+func (c *ConnectionDriver) bound() <-chan struct{} {
+	if pending, ok := c.transport.(transport.PendingTransport); ok {
+		return pending.Bound(c.url)
+	}
+	return closedChan
+}
+
+// connectFailed counts a failed attempt, returning whether the reconnect
+// policy says to give up.
+func (c *ConnectionDriver) connectFailed(err error) bool {
+	c.failures++
+	stop := c.config.ReconnectStop()
+	switch {
+	case stop&gomq.ReconnectStopConnRefused != 0 && errors.Is(err, syscall.ECONNREFUSED):
+		c.stopReconnecting("connection refused", err)
+	case stop&gomq.ReconnectStopHandshakeFailed != 0 && errors.Is(err, ErrHandshakeFailed):
+		c.stopReconnecting("handshake failed", err)
+	case c.config.ReconnectMaxAttempts() > 0 && c.failures >= c.config.ReconnectMaxAttempts():
+		c.stopReconnecting(fmt.Sprintf("%d attempts failed", c.failures), err)
+	default:
+		return false
+	}
+
+	return true
+}
+
+func (c *ConnectionDriver) stopReconnecting(reason string, err error) {
+	c.eventBus.Post(gomq.Event{
+		EventType:  gomq.EventTypeReconnectStopped,
+		RemoteAddr: c.url.String(),
+		Notes:      reason,
+		Err:        err,
+	})
+}
+
+// retry schedules the next attempt a backoff interval after from.
+func (c *ConnectionDriver) retry(from time.Time) time.Time {
+	delay := c.backoff.Next()
+	gomq.SocketMetricsFrom(c.ctx).Endpoint(c.url.String()).CountReconnect()
+	c.eventBus.Post(gomq.Event{
+		EventType:  gomq.EventTypeConnectRetried,
+		RemoteAddr: c.url.String(),
+		Notes:      fmt.Sprintf("retrying in %s", time.Until(from.Add(delay)).Round(time.Millisecond)),
+	})
+	return from.Add(delay)
+}
+
+// handshakeFailed closes a connection which could not be set up.
+func (c *ConnectionDriver) handshakeFailed(conn net.Conn, err error) error {
+	conn.Close()
+	gomq.SocketMetricsFrom(c.ctx).Endpoint(c.url.String()).CountHandshakeFailure()
+	c.lastConnectErr = fmt.Errorf("%w: %w", ErrHandshakeFailed, err)
+	return c.lastConnectErr
+}
+
+type handshakeFailed struct{}
+
+func (handshakeFailed) Error() string {
+	return "Handshake failed"
+}
+
+var ErrHandshakeFailed handshakeFailed
+
+// sleep for d or until the driver is closed.
+func (c *ConnectionDriver) sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-c.ctx.Done():
+	}
+}
@@ -0,0 +1,206 @@
+package socketutil_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	_ "github.com/workspace-9/gomq/transport/tcp"
+	_ "github.com/workspace-9/gomq/types/pull"
+	_ "github.com/workspace-9/gomq/types/push"
+	_ "github.com/workspace-9/gomq/zmtp/null"
+)
+
+// waitEvent waits for an event of the given type, failing the test after a
+// few seconds.
+func waitEvent(t *testing.T, events <-chan gomq.Event, typ gomq.EventType) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.EventType == typ {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no %s event", typ)
+		}
+	}
+}
+
+// bindAny binds the socket to a free loopback port, returning its address.
+func bindAny(t *testing.T, sock *gomq.Socket) string {
+	t.Helper()
+	events := sock.Monitor(gomq.Events(gomq.EventTypeListening))
+	if err := sock.Bind("tcp://127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+
+	return (<-events).LocalAddr
+}
+
+// TestCloseAfterDisconnect closes a connecting socket just after its peer
+// went away, which the race detector flags if Close reads the peer without
+// synchronising with the run loop clearing it.
+func TestCloseAfterDisconnect(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	pull, err := ctx.NewSocket("PULL", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := bindAny(t, pull)
+
+	push, err := ctx.NewSocket("PUSH", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := push.Monitor(gomq.Events(gomq.EventTypeReady, gomq.EventTypeDisconnected))
+	push.SetOption(gomq.OptionReconnectIvl, time.Hour)
+	if err := push.Connect(addr); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, events, gomq.EventTypeReady)
+
+	pull.Close()
+	waitEvent(t, events, gomq.EventTypeDisconnected)
+	push.Close()
+}
+
+// closedAddr returns the address of a tcp port nothing listens on.
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.Close()
+	return "tcp://" + ln.Addr().String()
+}
+
+// reconnecting makes a PUSH retrying every few milliseconds under the
+// policy, with a monitor of its connection attempts.
+func reconnecting(t *testing.T, ctx *gomq.Context, stop gomq.ReconnectStop, attempts int) (*gomq.Socket, <-chan gomq.Event) {
+	t.Helper()
+	push, err := ctx.NewSocket("PUSH", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { push.Close() })
+	push.SetOption(gomq.OptionReconnectIvl, 5*time.Millisecond)
+	push.SetOption(gomq.OptionReconnectStop, stop)
+	push.SetOption(gomq.OptionReconnectMaxAttempts, attempts)
+	events := push.Monitor(gomq.Events(
+		gomq.EventTypeReady,
+		gomq.EventTypeConnectFailed,
+		gomq.EventTypeFailedHandshake,
+		gomq.EventTypeReconnectStopped,
+	))
+	return push, events
+}
+
+// stopped waits for the reconnect stopped event, failing unless its notes
+// are as expected, and returns how many attempts failed before it.
+func stopped(t *testing.T, events <-chan gomq.Event, notes string) (failures int) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-events:
+			switch ev.EventType {
+			case gomq.EventTypeConnectFailed, gomq.EventTypeFailedHandshake:
+				failures++
+			case gomq.EventTypeReconnectStopped:
+				if ev.Notes != notes {
+					t.Fatalf("expected to stop for %q, got %q", notes, ev.Notes)
+				}
+				return failures
+			}
+		case <-timeout:
+			t.Fatalf("expected to stop reconnecting for %q", notes)
+		}
+	}
+}
+
+// quiet fails the test if another connection attempt is made.
+func quiet(t *testing.T, events <-chan gomq.Event) {
+	t.Helper()
+	select {
+	case ev := <-events:
+		t.Fatalf("expected no more attempts, got %s", ev.EventType)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestReconnectStop checks that each reconnect policy gives up when it
+// should, and that nothing is attempted afterwards.
+func TestReconnectStop(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	t.Run("refused", func(t *testing.T) {
+		push, events := reconnecting(t, ctx, gomq.ReconnectStopConnRefused, 0)
+		if err := push.Connect(closedAddr(t)); err != nil {
+			t.Fatal(err)
+		}
+		if failures := stopped(t, events, "connection refused"); failures != 1 {
+			t.Fatalf("expected to stop after 1 attempt, got %d", failures)
+		}
+		quiet(t, events)
+	})
+
+	t.Run("attempts", func(t *testing.T) {
+		push, events := reconnecting(t, ctx, 0, 3)
+		if err := push.Connect(closedAddr(t)); err != nil {
+			t.Fatal(err)
+		}
+		if failures := stopped(t, events, "3 attempts failed"); failures != 3 {
+			t.Fatalf("expected to stop after 3 attempts, got %d", failures)
+		}
+		quiet(t, events)
+	})
+
+	t.Run("handshake", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				conn.Close()
+			}
+		}()
+
+		push, events := reconnecting(t, ctx, gomq.ReconnectStopHandshakeFailed, 0)
+		if err := push.Connect("tcp://" + ln.Addr().String()); err != nil {
+			t.Fatal(err)
+		}
+		stopped(t, events, "handshake failed")
+		quiet(t, events)
+	})
+
+	t.Run("disconnect", func(t *testing.T) {
+		pull, err := ctx.NewSocket("PULL", "NULL")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := bindAny(t, pull)
+
+		push, events := reconnecting(t, ctx, gomq.ReconnectStopAfterDisconnect, 0)
+		if err := push.Connect(addr); err != nil {
+			t.Fatal(err)
+		}
+		waitEvent(t, events, gomq.EventTypeReady)
+		pull.Close()
+		stopped(t, events, "connection lost")
+		quiet(t, events)
+	})
+}
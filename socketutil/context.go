@@ -0,0 +1,29 @@
+package socketutil
+
+import (
+	"context"
+
+	"github.com/workspace-9/gomq/types"
+)
+
+// MergeContext returns a context which is done when either ctx or the
+// socket's own context is.
+func MergeContext(ctx, sockCtx context.Context) (context.Context, context.CancelFunc) {
+	merged, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(sockCtx, cancel)
+	return merged, func() {
+		stop()
+		cancel()
+	}
+}
+
+// Interrupted returns the error for an operation abandoned because ctx or
+// the socket's own context is done. Closing the socket takes precedence, and
+// otherwise the error wraps both types.ErrWouldBlock and ctx's error.
+func Interrupted(ctx, sockCtx context.Context) error {
+	if err := sockCtx.Err(); err != nil {
+		return err
+	}
+
+	return types.WouldBlock(ctx.Err())
+}
@@ -0,0 +1,157 @@
+package socketutil
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/url"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// maxDatagram is the size of the largest datagram received.
+const maxDatagram = 65535
+
+// EncodeDatagram encodes a message for a datagram transport as the length
+// of its group in one byte, the group and the body.
+func EncodeDatagram(msg zmtp.Message) []byte {
+	datagram := make([]byte, 0, 1+len(msg.Group)+len(msg.Body))
+	datagram = append(datagram, byte(len(msg.Group)))
+	datagram = append(datagram, msg.Group...)
+	return append(datagram, msg.Body...)
+}
+
+// DecodeDatagram decodes a datagram encoded by EncodeDatagram. ok is false
+// if the datagram is too short for its group.
+func DecodeDatagram(datagram []byte) (msg zmtp.Message, ok bool) {
+	if len(datagram) == 0 || len(datagram) < 1+int(datagram[0]) {
+		return msg, false
+	}
+
+	groupLen := int(datagram[0])
+	msg.Group = string(datagram[1 : 1+groupLen])
+	msg.Body = datagram[1+groupLen:]
+	return msg, true
+}
+
+// DatagramSender sends messages to an endpoint of a datagram transport.
+type DatagramSender struct {
+	conn    net.Conn
+	metrics *gomq.EndpointMetrics
+}
+
+// DialDatagrams opens the endpoint at url for sending, counting what is sent
+// in the socket metrics carried by ctx.
+func DialDatagrams(
+	ctx context.Context,
+	tp transport.DatagramTransport,
+	url *url.URL,
+	eventBus gomq.EventBus,
+) (*DatagramSender, error) {
+	conn, err := tp.DialDatagrams(url)
+	if err != nil {
+		return nil, err
+	}
+
+	eventBus.Post(gomq.Event{
+		EventType:  gomq.EventTypeConnected,
+		LocalAddr:  transport.BuildURL(conn.LocalAddr(), tp),
+		RemoteAddr: transport.BuildURL(conn.RemoteAddr(), tp),
+	})
+	return &DatagramSender{conn: conn, metrics: gomq.SocketMetricsFrom(ctx).Endpoint(url.String())}, nil
+}
+
+// Send the message in one datagram. Nothing tells whether it arrives, but
+// failures to send it are counted in the endpoint's metrics.
+func (d *DatagramSender) Send(msg zmtp.Message) error {
+	if _, err := d.conn.Write(EncodeDatagram(msg)); err != nil {
+		d.metrics.CountSendError()
+		return err
+	}
+
+	d.metrics.CountSent(msg)
+	return nil
+}
+
+// Close the endpoint.
+func (d *DatagramSender) Close() error {
+	return d.conn.Close()
+}
+
+// DatagramReceiver receives messages at a bound endpoint of a datagram
+// transport.
+type DatagramReceiver struct {
+	conn      net.PacketConn
+	transport transport.DatagramTransport
+	eventBus  gomq.EventBus
+	done      chan struct{}
+}
+
+// ListenDatagrams binds the endpoint at url, passing each message received
+// to handler until the handler fails or the receiver is closed. Malformed
+// datagrams are dropped.
+func ListenDatagrams(
+	ctx context.Context,
+	tp transport.DatagramTransport,
+	url *url.URL,
+	eventBus gomq.EventBus,
+	handler func(context.Context, zmtp.Message) error,
+) (*DatagramReceiver, error) {
+	conn, err := tp.ListenDatagrams(url)
+	if err != nil {
+		eventBus.Post(gomq.Event{
+			EventType: gomq.EventTypeBindFailed,
+			LocalAddr: url.String(),
+			Notes:     err.Error(),
+			Err:       err,
+		})
+		return nil, err
+	}
+
+	eventBus.Post(gomq.Event{
+		EventType: gomq.EventTypeListening,
+		LocalAddr: transport.BuildURL(conn.LocalAddr(), tp),
+	})
+
+	r := &DatagramReceiver{conn: conn, transport: tp, eventBus: eventBus, done: make(chan struct{})}
+	go r.run(ctx, handler, gomq.SocketMetricsFrom(ctx).Endpoint(url.String()))
+	return r, nil
+}
+
+func (r *DatagramReceiver) run(
+	ctx context.Context,
+	handler func(context.Context, zmtp.Message) error,
+	metrics *gomq.EndpointMetrics,
+) {
+	defer close(r.done)
+	buf := make([]byte, maxDatagram)
+	for {
+		n, _, err := r.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		msg, ok := DecodeDatagram(bytes.Clone(buf[:n]))
+		if !ok {
+			continue
+		}
+
+		metrics.CountReceived(msg)
+		if err := handler(ctx, msg); err != nil {
+			return
+		}
+	}
+}
+
+// Close the endpoint, waiting for the handler to return.
+func (r *DatagramReceiver) Close() error {
+	err := r.conn.Close()
+	<-r.done
+	r.eventBus.Post(gomq.Event{
+		EventType: gomq.EventTypeClosed,
+		LocalAddr: transport.BuildURL(r.conn.LocalAddr(), r.transport),
+	})
+	return err
+}
@@ -0,0 +1,31 @@
+package socketutil_test
+
+import (
+	"testing"
+
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// TestDecodeDatagram checks that datagrams round trip, and that datagrams
+// shorter than their group are rejected.
+func TestDecodeDatagram(t *testing.T) {
+	datagram := socketutil.EncodeDatagram(zmtp.Message{Group: "group", Body: []byte("body")})
+	msg, ok := socketutil.DecodeDatagram(datagram)
+	if !ok || msg.Group != "group" || string(msg.Body) != "body" {
+		t.Fatalf("expected group and body, got %q %q (%v)", msg.Group, msg.Body, ok)
+	}
+
+	msg, ok = socketutil.DecodeDatagram([]byte{})
+	if ok {
+		t.Fatalf("expected an empty datagram to be rejected, got %q", msg.Group)
+	}
+	msg, ok = socketutil.DecodeDatagram([]byte("\x05grp"))
+	if ok {
+		t.Fatalf("expected a datagram shorter than its group to be rejected, got %q", msg.Group)
+	}
+	msg, ok = socketutil.DecodeDatagram([]byte("\x05group"))
+	if !ok || msg.Group != "group" || len(msg.Body) != 0 {
+		t.Fatalf("expected an empty body, got %q %q (%v)", msg.Group, msg.Body, ok)
+	}
+}
@@ -0,0 +1,205 @@
+package socketutil
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/workspace-9/gomq"
+)
+
+// FairQueue receives whole messages from inboxes in turn, one message from
+// each inbox with one waiting, so that a busy peer cannot starve the rest.
+// Messages are usually []zmtp.Message, or PeerMessage for socket types which
+// need to know where they came from. The zero value is ready for use.
+type FairQueue[T any] struct {
+	lock    sync.Mutex
+	ins     []*Inbox[T]
+	next    int
+	changed chan struct{}
+}
+
+// Inbox queues messages read from one peer.
+type Inbox[T any] struct {
+	Queue  chan T
+	queue  *FairQueue[T]
+	closed bool
+}
+
+// Add an inbox holding up to queueLen messages to the end of the rotation.
+func (f *FairQueue[T]) Add(queueLen int) *Inbox[T] {
+	in := &Inbox[T]{Queue: make(chan T, queueLen), queue: f}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.ins = append(f.ins, in)
+	f.signal()
+	return in
+}
+
+// Receive the next message in turn, waiting until ctx or the socket's own
+// context is done if none are waiting. While waiting it receives from every
+// inbox at once, so that inboxes without room to queue still deliver.
+func (f *FairQueue[T]) Receive(ctx, sockCtx context.Context) (T, error) {
+	for {
+		f.lock.Lock()
+		if msg, ok := f.tryTake(); ok {
+			f.lock.Unlock()
+			return msg, nil
+		}
+
+		ins := append([]*Inbox[T](nil), f.ins...)
+		cases := make([]reflect.SelectCase, 0, len(ins)+3)
+		for _, in := range ins {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(in.Queue)})
+		}
+		cases = append(
+			cases,
+			reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(f.waitChanged())},
+			reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sockCtx.Done())},
+		)
+		f.lock.Unlock()
+
+		chosen, val, _ := reflect.Select(cases)
+		switch {
+		case chosen < len(ins):
+			f.lock.Lock()
+			f.advancePast(ins[chosen])
+			f.lock.Unlock()
+			return val.Interface().(T), nil
+		case chosen == len(ins):
+			continue
+		}
+
+		var zero T
+		return zero, Interrupted(ctx, sockCtx)
+	}
+}
+
+// advancePast moves the rotation on to the inbox after in. The lock must be
+// held.
+func (f *FairQueue[T]) advancePast(in *Inbox[T]) {
+	for idx, candidate := range f.ins {
+		if candidate == in {
+			f.next = (idx + 1) % len(f.ins)
+			return
+		}
+	}
+}
+
+// tryTake takes a message from the first inbox with one waiting, starting
+// from the next in the rotation, and drops closed inboxes once they are
+// empty.
+func (f *FairQueue[T]) tryTake() (T, bool) {
+	for offset := 0; offset < len(f.ins); offset++ {
+		idx := (f.next + offset) % len(f.ins)
+		in := f.ins[idx]
+		select {
+		case msg := <-in.Queue:
+			f.next = (idx + 1) % len(f.ins)
+			if in.closed && len(in.Queue) == 0 {
+				f.remove(idx)
+			}
+			return msg, true
+		default:
+		}
+
+		if in.closed {
+			f.remove(idx)
+			offset--
+		}
+	}
+
+	var zero T
+	return zero, false
+}
+
+// remove the inbox at idx from the rotation. The lock must be held.
+func (f *FairQueue[T]) remove(idx int) {
+	f.ins = append(f.ins[:idx], f.ins[idx+1:]...)
+	if idx < f.next {
+		f.next--
+	}
+	if f.next >= len(f.ins) {
+		f.next = 0
+	}
+}
+
+// Depth totals the messages queued in the inboxes and their capacity.
+func (f *FairQueue[T]) Depth() gomq.QueueDepth {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var depth gomq.QueueDepth
+	for _, in := range f.ins {
+		depth.Len += len(in.Queue)
+		depth.Cap += cap(in.Queue)
+	}
+	return depth
+}
+
+// Ready returns a channel which is closed once a message is waiting in one
+// of the inboxes.
+func (f *FairQueue[T]) Ready() <-chan struct{} {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, in := range f.ins {
+		if len(in.Queue) > 0 {
+			ready := make(chan struct{})
+			close(ready)
+			return ready
+		}
+	}
+	return f.waitChanged()
+}
+
+func (f *FairQueue[T]) waitChanged() chan struct{} {
+	if f.changed == nil {
+		f.changed = make(chan struct{})
+	}
+	return f.changed
+}
+
+// signal wakes anything waiting for a message. The lock must be held.
+func (f *FairQueue[T]) signal() {
+	if f.changed != nil {
+		close(f.changed)
+		f.changed = nil
+	}
+}
+
+// Deliver queues a message read from the peer, waiting for room until ctx
+// is done.
+func (i *Inbox[T]) Deliver(ctx context.Context, msg T) error {
+	select {
+	case i.Queue <- msg:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	i.queue.lock.Lock()
+	i.queue.signal()
+	i.queue.lock.Unlock()
+	return nil
+}
+
+// Close the inbox once nothing more will be delivered to it. Messages
+// already queued may still be received, after which the inbox leaves the
+// rotation.
+func (i *Inbox[T]) Close() {
+	i.queue.lock.Lock()
+	defer i.queue.lock.Unlock()
+	i.closed = true
+	if len(i.Queue) > 0 {
+		return
+	}
+
+	for idx, candidate := range i.queue.ins {
+		if candidate == i {
+			i.queue.remove(idx)
+			return
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package socketutil_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/types"
+)
+
+func receive(t *testing.T, fq *socketutil.FairQueue[int]) int {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, err := fq.Receive(ctx, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+// TestFairQueueTakesInTurn checks that a busy inbox does not starve the
+// others.
+func TestFairQueueTakesInTurn(t *testing.T) {
+	var fq socketutil.FairQueue[int]
+	busy, quiet := fq.Add(10), fq.Add(10)
+	for idx := 0; idx < 3; idx++ {
+		busy.Deliver(context.Background(), idx)
+	}
+	quiet.Deliver(context.Background(), 10)
+
+	want := []int{0, 10, 1, 2}
+	for _, msg := range want {
+		if got := receive(t, &fq); got != msg {
+			t.Fatalf("expected %d, got %d", msg, got)
+		}
+	}
+
+	expired, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fq.Receive(expired, context.Background()); !errors.Is(err, types.ErrWouldBlock) {
+		t.Fatalf("expected ErrWouldBlock, got %v", err)
+	}
+}
+
+// TestFairQueueUnbuffered checks that an inbox without room to queue still
+// delivers to a waiting Receive, including one which started waiting before
+// the inbox was added.
+func TestFairQueueUnbuffered(t *testing.T) {
+	var fq socketutil.FairQueue[int]
+	received := make(chan int, 1)
+	go func() {
+		received <- receive(t, &fq)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	in := fq.Add(0)
+	delivered := make(chan error, 1)
+	go func() {
+		delivered <- in.Deliver(context.Background(), 1)
+	}()
+
+	select {
+	case msg := <-received:
+		if msg != 1 {
+			t.Fatalf("expected 1, got %d", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Receive blocked")
+	}
+	if err := <-delivered; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestFairQueueClose checks that messages queued in a closed inbox are still
+// received.
+func TestFairQueueClose(t *testing.T) {
+	var fq socketutil.FairQueue[int]
+	in := fq.Add(2)
+	in.Deliver(context.Background(), 1)
+	in.Deliver(context.Background(), 2)
+	in.Close()
+
+	if a, b := receive(t, &fq), receive(t, &fq); a != 1 || b != 2 {
+		t.Fatalf("expected 1 and 2, got %d and %d", a, b)
+	}
+	if depth := fq.Depth(); depth.Len != 0 || depth.Cap != 0 {
+		t.Fatalf("expected the closed inbox to leave, got depth %d of %d", depth.Len, depth.Cap)
+	}
+}
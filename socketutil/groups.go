@@ -0,0 +1,110 @@
+package socketutil
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/workspace-9/gomq/types"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// Groups tracks the groups a DISH socket has joined and the peers it is
+// connected to, keeping each of them informed of the groups. The zero value
+// is ready for use.
+type Groups struct {
+	lock   sync.RWMutex
+	joined map[string]struct{}
+	peers  map[*Peer]struct{}
+}
+
+// Attach sends the peer every joined group then tracks it until Detach is
+// called, which replays the groups after a reconnect.
+func (g *Groups) Attach(peer *Peer) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	for group := range g.joined {
+		if err := peer.Join(group); err != nil {
+			return err
+		}
+	}
+
+	if g.peers == nil {
+		g.peers = make(map[*Peer]struct{})
+	}
+	g.peers[peer] = struct{}{}
+	return nil
+}
+
+// Detach stops tracking the peer.
+func (g *Groups) Detach(peer *Peer) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	delete(g.peers, peer)
+}
+
+// Join the group, telling every peer.
+func (g *Groups) Join(group string) error {
+	if len(group) > zmtp.MaxGroupLen {
+		return fmt.Errorf("%w: %d bytes", types.ErrGroupTooLong, len(group))
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if _, ok := g.joined[group]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyJoined, group)
+	}
+
+	if g.joined == nil {
+		g.joined = make(map[string]struct{})
+	}
+	g.joined[group] = struct{}{}
+
+	for peer := range g.peers {
+		// A failed send surfaces as a read error in the peer's handler.
+		peer.Join(group)
+	}
+
+	return nil
+}
+
+// Leave the group, telling every peer.
+func (g *Groups) Leave(group string) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if _, ok := g.joined[group]; !ok {
+		return fmt.Errorf("%w: %s", types.ErrNotJoined, group)
+	}
+	delete(g.joined, group)
+
+	for peer := range g.peers {
+		peer.Leave(group)
+	}
+
+	return nil
+}
+
+// Joined returns whether the group is joined.
+func (g *Groups) Joined(group string) bool {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	_, ok := g.joined[group]
+	return ok
+}
+
+// CheckGroupMessage checks that a message can be sent to a group, which
+// needs a single frame and a group no longer than zmtp.MaxGroupLen.
+func CheckGroupMessage(msg []zmtp.Message) error {
+	if err := CheckSingleFrame(msg); err != nil {
+		return err
+	}
+
+	if len(msg[0].Group) > zmtp.MaxGroupLen {
+		return fmt.Errorf("%w: %d bytes", types.ErrGroupTooLong, len(msg[0].Group))
+	}
+
+	return nil
+}
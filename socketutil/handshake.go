@@ -0,0 +1,90 @@
+package socketutil
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// setUp exchanges greetings over conn and runs the mechanism's handshake,
+// falling back to ZMTP 2.0 if the peer speaks it and the mechanism allows.
+// On failure it returns the type of event to report along with the error.
+func setUp(conn net.Conn, mech zmtp.Mechanism, meta MetadataProvider) (
+	zmtp.Socket,
+	zmtp.Greeting,
+	zmtp.Metadata,
+	gomq.EventType,
+	error,
+) {
+	greeting := zmtp.NewGreeting()
+	greeting.SetVersionMajor(3)
+	greeting.SetVersionMinor(1)
+	greeting.SetMechanism(mech.Name())
+	greeting.SetServer(mech.Server())
+	peerGreeting, err := zmtp.ExchangeGreeting(conn, &greeting)
+	if err != nil {
+		return nil, peerGreeting, nil, gomq.EventTypeFailedGreeting, err
+	}
+
+	if peerGreeting.VersionMajor() == zmtp.RevisionV2 {
+		legacy, ok := mech.(zmtp.LegacyMechanism)
+		if !ok {
+			err := fmt.Errorf("%w: ZMTP 2.0 peers cannot use %s", zmtp.ErrUnsupportedVersion, mech.Name())
+			return nil, peerGreeting, nil, gomq.EventTypeFailedGreeting, err
+		}
+
+		sock, peerMeta, err := legacy.HandshakeV2(conn, meta())
+		if err != nil {
+			return nil, peerGreeting, nil, gomq.EventTypeFailedHandshake, err
+		}
+		return sock, peerGreeting, withConnProperties(conn, peerMeta), 0, nil
+	}
+
+	if err := mech.ValidateGreeting(&peerGreeting); err != nil {
+		return nil, peerGreeting, nil, gomq.EventTypeFailedGreeting, err
+	}
+
+	sock, peerMeta, err := mech.Handshake(conn, meta())
+	if err != nil {
+		return nil, peerGreeting, nil, gomq.EventTypeFailedHandshake, err
+	}
+	return sock, peerGreeting, withConnProperties(conn, peerMeta), 0, nil
+}
+
+// withConnProperties puts what the transport learned about the peer ahead
+// of the peer's metadata, where Metadata.Property finds it first, and drops
+// any properties the peer sent under names the transport owns.
+func withConnProperties(conn net.Conn, peerMeta zmtp.Metadata) zmtp.Metadata {
+	propConn, ok := conn.(transport.PeerPropertiesConn)
+	if !ok {
+		return peerMeta
+	}
+
+	props := propConn.PeerProperties()
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var meta zmtp.Metadata
+	for _, name := range names {
+		meta.AddProperty(name, props[name])
+	}
+
+	owned := propConn.PeerPropertyNames()
+	peerMeta.Properties(func(name, value string) {
+		for _, ownedName := range owned {
+			if strings.EqualFold(name, ownedName) {
+				return
+			}
+		}
+		meta.AddProperty(name, value)
+	})
+	return meta
+}
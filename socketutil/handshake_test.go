@@ -0,0 +1,47 @@
+package socketutil
+
+import (
+	"net"
+	"testing"
+
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+type propertiesConn struct {
+	net.Conn
+	props map[string]string
+}
+
+func (c propertiesConn) PeerProperties() map[string]string {
+	return c.props
+}
+
+func (c propertiesConn) PeerPropertyNames() []string {
+	return []string{"Peer-Certificate-Subject"}
+}
+
+// TestConnPropertiesNotForged checks that a peer cannot set properties the
+// transport owns, whether or not the transport learned them.
+func TestConnPropertiesNotForged(t *testing.T) {
+	var peerMeta zmtp.Metadata
+	peerMeta.AddProperty("Socket-Type", "DEALER")
+	peerMeta.AddProperty("peer-certificate-subject", "CN=forged")
+
+	for _, props := range []map[string]string{nil, {"Peer-Certificate-Subject": "CN=verified"}} {
+		meta := withConnProperties(propertiesConn{props: props}, peerMeta)
+
+		var subjects []string
+		meta.Properties(func(name, value string) {
+			if name == "Peer-Certificate-Subject" || name == "peer-certificate-subject" {
+				subjects = append(subjects, value)
+			}
+		})
+		want := props["Peer-Certificate-Subject"]
+		if (want == "" && len(subjects) != 0) || (want != "" && (len(subjects) != 1 || subjects[0] != want)) {
+			t.Fatalf("expected subject %q, got %q", want, subjects)
+		}
+		if socketType, _ := meta.Property("Socket-Type"); socketType != "DEALER" {
+			t.Fatalf("expected Socket-Type DEALER, got %q", socketType)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package socketutil
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+type heartbeatTimeout struct{}
+
+func (heartbeatTimeout) Error() string {
+	return "Heartbeat timed out"
+}
+
+var ErrHeartbeatTimeout heartbeatTimeout
+
+// StartHeartbeat pings the peer at the configured interval until the
+// returned function is called. If nothing is heard within the heartbeat
+// timeout of a PING, a heartbeat failed event is posted and the peer is
+// closed, failing whichever handler is reading from it. Nothing is sent to
+// peers older than ZMTP 3.1, which do not know PING.
+func StartHeartbeat(
+	ctx context.Context,
+	peer *Peer,
+	conf *gomq.Config,
+	eventBus gomq.EventBus,
+	tp transport.Transport,
+) (stop func()) {
+	ivl := conf.HeartbeatIvl()
+	if ivl <= 0 || !peer.SupportsCommands() {
+		return func() {}
+	}
+
+	derived, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err := heartbeat(derived, peer, ivl, conf.HeartbeatTimeout(), conf.HeartbeatTTL())
+		if err == nil {
+			return
+		}
+
+		eventBus.Post(gomq.Event{
+			EventType:  gomq.EventTypeHeartbeatFailed,
+			LocalAddr:  transport.BuildURL(peer.Net().LocalAddr(), tp),
+			RemoteAddr: transport.BuildURL(peer.Net().RemoteAddr(), tp),
+			Notes:      err.Error(),
+			Err:        err,
+		})
+		peer.Close()
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// heartbeat sends a PING every ivl, returning an error if a send fails or
+// nothing arrives within timeout of a PING. It returns nil once ctx is done.
+func heartbeat(ctx context.Context, peer *Peer, ivl, timeout, ttl time.Duration) error {
+	nextPing := time.Now().Add(ivl)
+	// unanswered holds when each PING sent since traffic last arrived went out.
+	var unanswered []time.Time
+	timer := time.NewTimer(ivl)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil
+		}
+
+		now := time.Now()
+		lastHeard := peer.LastHeard()
+		for len(unanswered) > 0 && !unanswered[0].After(lastHeard) {
+			unanswered = unanswered[1:]
+		}
+		if len(unanswered) > 0 && !now.Before(unanswered[0].Add(timeout)) {
+			return fmt.Errorf("%w: nothing heard for %s", ErrHeartbeatTimeout, now.Sub(lastHeard).Round(time.Millisecond))
+		}
+
+		if !now.Before(nextPing) {
+			if err := peer.SendCommand(zmtp.PingCommand(ttl, nil)); err != nil {
+				return err
+			}
+
+			unanswered = append(unanswered, now)
+			nextPing = now.Add(ivl)
+		}
+
+		wake := nextPing
+		if len(unanswered) > 0 && unanswered[0].Add(timeout).Before(wake) {
+			wake = unanswered[0].Add(timeout)
+		}
+		timer.Reset(time.Until(wake))
+	}
+}
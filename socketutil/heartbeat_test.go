@@ -0,0 +1,128 @@
+package socketutil_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/zmtp"
+	"github.com/workspace-9/gomq/zmtp/null"
+)
+
+// silentPeer completes a NULL handshake over conn as socketType, then goes
+// quiet, reading nothing more so that PINGs are never answered.
+func silentPeer(conn net.Conn, socketType string) (zmtp.Socket, error) {
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	greeting := zmtp.NewGreeting()
+	greeting.SetVersionMajor(3)
+	greeting.SetVersionMinor(1)
+	greeting.SetMechanism("NULL")
+	if _, err := zmtp.ExchangeGreeting(conn, &greeting); err != nil {
+		return nil, err
+	}
+
+	meta := zmtp.Metadata{}
+	meta.AddProperty("Socket-Type", socketType)
+	sock, _, err := null.Null{}.Handshake(conn, meta)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return sock, nil
+}
+
+// TestHeartbeatTimeout checks that a connected peer which stops answering
+// PINGs is dropped once the heartbeat timeout passes, and reconnected to.
+func TestHeartbeatTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if _, err := silentPeer(conn, "PULL"); err != nil {
+				t.Error(err)
+			}
+			accepted <- conn
+		}
+	}()
+
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+	push, err := ctx.NewSocket("PUSH", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer push.Close()
+	push.SetOption(gomq.OptionHeartbeatIvl, 20*time.Millisecond)
+	push.SetOption(gomq.OptionHeartbeatTimeout, 50*time.Millisecond)
+	push.SetOption(gomq.OptionReconnectIvl, 10*time.Millisecond)
+	events := push.Monitor(gomq.Events(gomq.EventTypeHeartbeatFailed))
+
+	start := time.Now()
+	if err := push.Connect("tcp://" + ln.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	defer (<-accepted).Close()
+
+	select {
+	case ev := <-events:
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Fatalf("expected the peer to be dropped after the timeout, took %s", elapsed)
+		}
+		if !strings.Contains(ev.Notes, "Heartbeat timed out") {
+			t.Fatalf("expected a heartbeat timeout, got %q", ev.Notes)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the silent peer to be detected")
+	}
+
+	select {
+	case again := <-accepted:
+		again.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the socket to reconnect")
+	}
+}
+
+// TestHeartbeatTTL checks that a bound socket drops a peer which asked for
+// a TTL in its PING and then fell silent for longer.
+func TestHeartbeatTTL(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+	pull, err := ctx.NewSocket("PULL", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pull.Close()
+	events := pull.Monitor(gomq.Events(gomq.EventTypeDisconnected))
+	addr := bindAny(t, pull)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(addr, "tcp://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	sock, err := silentPeer(conn, "PUSH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := sock.SendCommand(zmtp.PingCommand(100*time.Millisecond, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	waitEvent(t, events, gomq.EventTypeDisconnected)
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("expected the peer to be dropped after its TTL, took %s", elapsed)
+	}
+}
@@ -0,0 +1,250 @@
+package socketutil
+
+import (
+	"context"
+	"sync"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// LoadBalancer deals whole messages round-robin across outboxes, skipping
+// any whose queue is full. The deal is deterministic: while every outbox
+// has room, messages go to each in the order they were added. The zero
+// value is ready for use.
+type LoadBalancer struct {
+	lock    sync.Mutex
+	outs    []*Outbox
+	next    int
+	changed chan struct{}
+}
+
+// Outbox queues messages dealt to one peer.
+type Outbox struct {
+	Queue    chan []zmtp.Message
+	balancer *LoadBalancer
+	removed  bool
+}
+
+// Add an outbox holding up to queueLen messages to the end of the rotation.
+func (l *LoadBalancer) Add(queueLen int) *Outbox {
+	out := &Outbox{Queue: make(chan []zmtp.Message, queueLen), balancer: l}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.outs = append(l.outs, out)
+	l.signal()
+	return out
+}
+
+// Remove the outbox from the rotation, returning how many messages were
+// still queued in it. Nothing may receive from the outbox afterwards.
+func (l *LoadBalancer) Remove(out *Outbox) (dropped int) {
+	l.lock.Lock()
+	for idx, candidate := range l.outs {
+		if candidate != out {
+			continue
+		}
+
+		l.outs = append(l.outs[:idx], l.outs[idx+1:]...)
+		if idx < l.next {
+			l.next--
+		}
+		if l.next >= len(l.outs) {
+			l.next = 0
+		}
+		break
+	}
+	out.removed = true
+	l.signal()
+	l.lock.Unlock()
+
+	for {
+		select {
+		case <-out.Queue:
+			dropped++
+		default:
+			return dropped
+		}
+	}
+}
+
+// Send queues the message in the next outbox with room, waiting for room if
+// there is none until ctx or the socket's own context is done.
+func (l *LoadBalancer) Send(ctx, sockCtx context.Context, msg []zmtp.Message) error {
+	for {
+		l.lock.Lock()
+		if l.tryDeal(msg) {
+			l.lock.Unlock()
+			return nil
+		}
+
+		changed := l.waitChanged()
+		l.lock.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return Interrupted(ctx, sockCtx)
+		case <-sockCtx.Done():
+			return Interrupted(ctx, sockCtx)
+		}
+	}
+}
+
+// SendAll queues the message in every outbox with room, counting each copy
+// in backlog. Outboxes whose queue is full miss the message.
+func (l *LoadBalancer) SendAll(msg []zmtp.Message, backlog *Backlog) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for _, out := range l.outs {
+		backlog.Add(1)
+		select {
+		case out.Queue <- msg:
+		default:
+			backlog.Done(1)
+		}
+	}
+}
+
+// tryDeal queues the message in the first outbox with room, starting from
+// the next in the rotation.
+func (l *LoadBalancer) tryDeal(msg []zmtp.Message) bool {
+	for offset := range l.outs {
+		idx := (l.next + offset) % len(l.outs)
+		select {
+		case l.outs[idx].Queue <- msg:
+			l.next = (idx + 1) % len(l.outs)
+			return true
+		default:
+		}
+	}
+
+	return false
+}
+
+// Ready returns a channel which is closed once some outbox has room.
+func (l *LoadBalancer) Ready() <-chan struct{} {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for _, out := range l.outs {
+		if len(out.Queue) < cap(out.Queue) {
+			return closedChan
+		}
+	}
+
+	return l.waitChanged()
+}
+
+// Depth totals the messages queued in the outboxes and their capacity.
+func (l *LoadBalancer) Depth() gomq.QueueDepth {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var depth gomq.QueueDepth
+	for _, out := range l.outs {
+		depth.Len += len(out.Queue)
+		depth.Cap += cap(out.Queue)
+	}
+	return depth
+}
+
+func (l *LoadBalancer) waitChanged() chan struct{} {
+	if l.changed == nil {
+		l.changed = make(chan struct{})
+	}
+	return l.changed
+}
+
+// signal wakes anything waiting for room. The lock must be held.
+func (l *LoadBalancer) signal() {
+	if l.changed != nil {
+		close(l.changed)
+		l.changed = nil
+	}
+}
+
+// Receive the next message dealt to the outbox, waiting until ctx is done.
+func (o *Outbox) Receive(ctx context.Context) ([]zmtp.Message, error) {
+	select {
+	case msg := <-o.Queue:
+		o.balancer.lock.Lock()
+		o.balancer.signal()
+		o.balancer.lock.Unlock()
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Send queues the message in this outbox alone, for socket types which
+// address messages to a peer rather than deal them. It waits for room until
+// ctx or the socket's own context is done, and fails with ErrOutboxRemoved
+// once the outbox leaves its balancer.
+func (o *Outbox) Send(ctx, sockCtx context.Context, msg []zmtp.Message) error {
+	for {
+		o.balancer.lock.Lock()
+		if o.removed {
+			o.balancer.lock.Unlock()
+			return ErrOutboxRemoved
+		}
+
+		select {
+		case o.Queue <- msg:
+			o.balancer.lock.Unlock()
+			return nil
+		default:
+		}
+
+		changed := o.balancer.waitChanged()
+		o.balancer.lock.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return Interrupted(ctx, sockCtx)
+		case <-sockCtx.Done():
+			return Interrupted(ctx, sockCtx)
+		}
+	}
+}
+
+// TrySend queues the message in this outbox if it has room, reporting
+// whether it did.
+func (o *Outbox) TrySend(msg []zmtp.Message) bool {
+	o.balancer.lock.Lock()
+	defer o.balancer.lock.Unlock()
+
+	if o.removed {
+		return false
+	}
+
+	select {
+	case o.Queue <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// Ready returns a channel which is closed once the outbox has room or has
+// been removed.
+func (o *Outbox) Ready() <-chan struct{} {
+	o.balancer.lock.Lock()
+	defer o.balancer.lock.Unlock()
+
+	if o.removed || len(o.Queue) < cap(o.Queue) {
+		return closedChan
+	}
+	return o.balancer.waitChanged()
+}
+
+type outboxRemoved struct{}
+
+func (outboxRemoved) Error() string {
+	return "Peer disconnected before the message could be queued"
+}
+
+var ErrOutboxRemoved outboxRemoved
@@ -0,0 +1,114 @@
+package socketutil_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/types"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// deal sends messages numbered from first to first+count-1.
+func deal(t *testing.T, lb *socketutil.LoadBalancer, first, count int) {
+	t.Helper()
+	for idx := first; idx < first+count; idx++ {
+		msg := []zmtp.Message{{Body: []byte(strconv.Itoa(idx))}}
+		if err := lb.Send(context.Background(), context.Background(), msg); err != nil {
+			t.Fatalf("send %d: %v", idx, err)
+		}
+	}
+}
+
+// drain returns the numbers of the messages queued in the outbox, in order.
+func drain(t *testing.T, out *socketutil.Outbox) []int {
+	t.Helper()
+	var got []int
+	for len(out.Queue) > 0 {
+		msg, err := out.Receive(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		idx, err := strconv.Atoi(string(msg[0].Body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, idx)
+	}
+	return got
+}
+
+func expectDealt(t *testing.T, out *socketutil.Outbox, want ...int) {
+	t.Helper()
+	got := drain(t, out)
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for idx := range want {
+		if got[idx] != want[idx] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+// TestLoadBalancerEvenSplit checks that messages are dealt to each outbox
+// in turn.
+func TestLoadBalancerEvenSplit(t *testing.T) {
+	var lb socketutil.LoadBalancer
+	outs := []*socketutil.Outbox{lb.Add(10), lb.Add(10), lb.Add(10)}
+
+	deal(t, &lb, 0, 12)
+	if depth := lb.Depth(); depth.Len != 12 || depth.Cap != 30 {
+		t.Fatalf("expected depth 12 of 30, got %d of %d", depth.Len, depth.Cap)
+	}
+	expectDealt(t, outs[0], 0, 3, 6, 9)
+	expectDealt(t, outs[1], 1, 4, 7, 10)
+	expectDealt(t, outs[2], 2, 5, 8, 11)
+}
+
+// TestLoadBalancerSkipsFull checks that full outboxes are skipped, and that
+// sending fails with ErrWouldBlock once every outbox is full.
+func TestLoadBalancerSkipsFull(t *testing.T) {
+	var lb socketutil.LoadBalancer
+	outs := []*socketutil.Outbox{lb.Add(1), lb.Add(3), lb.Add(3)}
+
+	deal(t, &lb, 0, 7)
+	select {
+	case <-lb.Ready():
+		t.Fatal("ready with every outbox full")
+	default:
+	}
+
+	expired, cancel := context.WithCancel(context.Background())
+	cancel()
+	msg := []zmtp.Message{{Body: []byte("7")}}
+	if err := lb.Send(expired, context.Background(), msg); !errors.Is(err, types.ErrWouldBlock) {
+		t.Fatalf("expected ErrWouldBlock, got %v", err)
+	}
+
+	expectDealt(t, outs[0], 0)
+	select {
+	case <-lb.Ready():
+	default:
+		t.Fatal("not ready after an outbox was emptied")
+	}
+	expectDealt(t, outs[1], 1, 3, 5)
+	expectDealt(t, outs[2], 2, 4, 6)
+}
+
+// TestLoadBalancerRemove checks that removing an outbox reports the
+// messages dropped with it and leaves the rest of the rotation intact.
+func TestLoadBalancerRemove(t *testing.T) {
+	var lb socketutil.LoadBalancer
+	outs := []*socketutil.Outbox{lb.Add(10), lb.Add(10), lb.Add(10)}
+
+	deal(t, &lb, 0, 3)
+	if dropped := lb.Remove(outs[1]); dropped != 1 {
+		t.Fatalf("expected 1 dropped, got %d", dropped)
+	}
+	deal(t, &lb, 3, 4)
+	expectDealt(t, outs[0], 0, 3, 5)
+	expectDealt(t, outs[2], 2, 4, 6)
+}
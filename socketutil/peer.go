@@ -0,0 +1,146 @@
+package socketutil
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// Peer is an established zmtp.Socket along with what was learned about the
+// remote end during the greeting and handshake. Sends on a Peer are
+// serialized so that it may be written from several goroutines.
+type Peer struct {
+	zmtp.Socket
+	Greeting zmtp.Greeting
+	Meta     zmtp.Metadata
+
+	// RoutingID is assigned by socket types which address peers by id.
+	RoutingID []byte
+
+	// Metrics counts messages to and from the peer, if set.
+	Metrics  *gomq.EndpointMetrics
+	sendLock sync.Mutex
+
+	// lastHeard is when traffic last arrived, in unix nanoseconds, and ttl is
+	// how long the peer asked us to wait for it before giving up.
+	lastHeard atomic.Int64
+	ttl       atomic.Int64
+}
+
+// NewPeer wraps the socket with the peer's greeting and metadata.
+func NewPeer(sock zmtp.Socket, greeting zmtp.Greeting, meta zmtp.Metadata) *Peer {
+	peer := &Peer{Socket: sock, Greeting: greeting, Meta: meta}
+	peer.lastHeard.Store(time.Now().UnixNano())
+	return peer
+}
+
+// Read the next part of traffic from the peer. PING commands are answered
+// and heartbeat commands are not returned. Once the peer has sent a PING
+// with a ttl, Read fails if nothing arrives within it.
+func (p *Peer) Read() (zmtp.CommandOrMessage, error) {
+	for {
+		if ttl := time.Duration(p.ttl.Load()); ttl > 0 {
+			p.Net().SetReadDeadline(time.Now().Add(ttl))
+		}
+
+		next, err := p.Socket.Read()
+		if err != nil {
+			return next, err
+		}
+		p.lastHeard.Store(time.Now().UnixNano())
+
+		if next.IsMessage {
+			p.Metrics.CountReceived(*next.Message)
+			return next, nil
+		}
+
+		switch next.Command.Name {
+		case zmtp.CommandPing:
+			ttl, context, ok := zmtp.ParsePing(*next.Command)
+			if !ok {
+				continue
+			}
+
+			p.ttl.Store(int64(ttl))
+			if err := p.SendCommand(zmtp.PongCommand(context)); err != nil {
+				return next, err
+			}
+		case zmtp.CommandPong:
+		default:
+			return next, nil
+		}
+	}
+}
+
+// LastHeard returns when traffic last arrived from the peer.
+func (p *Peer) LastHeard() time.Time {
+	return time.Unix(0, p.lastHeard.Load())
+}
+
+// SendMessage sends a message to the peer.
+func (p *Peer) SendMessage(msg zmtp.Message) error {
+	p.sendLock.Lock()
+	defer p.sendLock.Unlock()
+	if err := p.Socket.SendMessage(msg); err != nil {
+		return err
+	}
+	p.Metrics.CountSent(msg)
+	return nil
+}
+
+// SendMessages sends all parts of a multipart message without allowing
+// other sends to be interleaved.
+func (p *Peer) SendMessages(msgs []zmtp.Message) error {
+	p.sendLock.Lock()
+	defer p.sendLock.Unlock()
+	for _, msg := range msgs {
+		if err := p.Socket.SendMessage(msg); err != nil {
+			return err
+		}
+		p.Metrics.CountSent(msg)
+	}
+	return nil
+}
+
+// SendCommand sends a command to the peer.
+func (p *Peer) SendCommand(cmd zmtp.Command) error {
+	p.sendLock.Lock()
+	defer p.sendLock.Unlock()
+	return p.Socket.SendCommand(cmd)
+}
+
+// SupportsCommands returns whether the peer speaks ZMTP 3.1 or later, and so
+// expects subscriptions as commands rather than messages.
+func (p *Peer) SupportsCommands() bool {
+	major, minor := p.Greeting.VersionMajor(), p.Greeting.VersionMinor()
+	return major > 3 || (major == 3 && minor >= 1)
+}
+
+// Subscribe tells the peer to send messages starting with topic.
+func (p *Peer) Subscribe(topic []byte) error {
+	if p.SupportsCommands() {
+		return p.SendCommand(zmtp.SubscribeCommand(topic))
+	}
+	return p.SendMessage(zmtp.SubscribeMessage(topic))
+}
+
+// Cancel tells the peer to stop sending messages starting with topic.
+func (p *Peer) Cancel(topic []byte) error {
+	if p.SupportsCommands() {
+		return p.SendCommand(zmtp.CancelCommand(topic))
+	}
+	return p.SendMessage(zmtp.CancelMessage(topic))
+}
+
+// Join tells the peer to send messages of group.
+func (p *Peer) Join(group string) error {
+	return p.SendCommand(zmtp.JoinCommand(group))
+}
+
+// Leave tells the peer to stop sending messages of group.
+func (p *Peer) Leave(group string) error {
+	return p.SendCommand(zmtp.LeaveCommand(group))
+}
@@ -0,0 +1,98 @@
+package socketutil
+
+import (
+	"sync"
+)
+
+// Publishers tracks the peers a subscribing socket is connected to, keeping
+// each of them informed of the socket's subscriptions. The zero value is
+// ready for use.
+type Publishers struct {
+	Subscriptions Subscriptions
+	lock          sync.Mutex
+	peers         map[*Peer]struct{}
+}
+
+// Attach sends the peer every current subscription then tracks it until
+// Detach is called. Calling Attach for each new connection replays the
+// subscriptions after a reconnect.
+func (p *Publishers) Attach(peer *Peer) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, topic := range p.Subscriptions.Topics() {
+		if err := peer.Subscribe(topic); err != nil {
+			return err
+		}
+	}
+
+	if p.peers == nil {
+		p.peers = make(map[*Peer]struct{})
+	}
+	p.peers[peer] = struct{}{}
+	return nil
+}
+
+// Detach stops tracking the peer.
+func (p *Publishers) Detach(peer *Peer) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.peers, peer)
+}
+
+// Subscribe to topic, telling every peer the first time it is subscribed to.
+func (p *Publishers) Subscribe(topic []byte) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.Subscriptions.Add(topic) {
+		return nil
+	}
+
+	for peer := range p.peers {
+		// A failed send surfaces as a read error in the peer's handler.
+		peer.Subscribe(topic)
+	}
+
+	return nil
+}
+
+// Unsubscribe from topic, telling every peer once the last subscription to
+// it is removed.
+func (p *Publishers) Unsubscribe(topic []byte) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.Subscriptions.Remove(topic) {
+		return nil
+	}
+
+	for peer := range p.peers {
+		peer.Cancel(topic)
+	}
+
+	return nil
+}
+
+// Forward a subscription, or its cancellation, to every peer as it is while
+// counting it for local matching. Unlike Subscribe and Unsubscribe, repeats
+// are forwarded too, so that a verbose XPUB upstream of a forwarding XSUB
+// sees every one.
+func (p *Publishers) Forward(subscribe bool, topic []byte) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if subscribe {
+		p.Subscriptions.Add(topic)
+	} else {
+		p.Subscriptions.Remove(topic)
+	}
+
+	for peer := range p.peers {
+		if subscribe {
+			peer.Subscribe(topic)
+		} else {
+			peer.Cancel(topic)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package socketutil
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/workspace-9/gomq/types"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// PeerMessage is a complete message along with the peer it came from.
+type PeerMessage struct {
+	Peer    *Peer
+	Message []zmtp.Message
+}
+
+// ReadMessages queues each complete message read from the peer in the inbox
+// until reading fails or ctx is done. Commands are ignored.
+func ReadMessages(ctx context.Context, peer *Peer, in *Inbox[PeerMessage]) error {
+	built := make([]zmtp.Message, 0)
+	for {
+		next, err := peer.Read()
+		if err != nil {
+			return err
+		}
+
+		if !next.IsMessage {
+			continue
+		}
+
+		built = append(built, *next.Message)
+		if next.Message.More {
+			continue
+		}
+
+		if err := in.Deliver(ctx, PeerMessage{Peer: peer, Message: built}); err != nil {
+			return err
+		}
+		built = make([]zmtp.Message, 0)
+	}
+}
+
+// ReadSingleFrames queues each message of one frame read from the peer in
+// the inbox, stamped with routingID, until reading fails or ctx is done.
+// Multipart messages, which socket types such as CLIENT and SERVER do not
+// allow, are dropped.
+func ReadSingleFrames(ctx context.Context, sock zmtp.Socket, in *Inbox[[]zmtp.Message], routingID uint32) error {
+	frames := 0
+	for {
+		next, err := sock.Read()
+		if err != nil {
+			return err
+		}
+
+		if !next.IsMessage {
+			continue
+		}
+
+		frames++
+		if next.Message.More {
+			continue
+		}
+
+		if frames == 1 {
+			msg := *next.Message
+			msg.RoutingID = routingID
+			if err := in.Deliver(ctx, []zmtp.Message{msg}); err != nil {
+				return err
+			}
+		}
+		frames = 0
+	}
+}
+
+// CheckSingleFrame checks that a message to send has one frame, as socket
+// types such as CLIENT and SERVER require.
+func CheckSingleFrame(msg []zmtp.Message) error {
+	if len(msg) != 1 {
+		return fmt.Errorf("%w: %d frames", types.ErrMultipartUnsupported, len(msg))
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package socketutil
+
+import (
+	"context"
+	"sync"
+)
+
+// RoundRobin hands out its items in turn. The zero value is empty and ready
+// for use.
+type RoundRobin[T comparable] struct {
+	lock  sync.Mutex
+	items []T
+	next  int
+	added chan struct{}
+}
+
+// Add an item to the rotation.
+func (r *RoundRobin[T]) Add(item T) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.items = append(r.items, item)
+	if r.added != nil {
+		close(r.added)
+		r.added = nil
+	}
+}
+
+// Remove an item from the rotation.
+func (r *RoundRobin[T]) Remove(item T) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for idx, candidate := range r.items {
+		if candidate != item {
+			continue
+		}
+
+		r.items = append(r.items[:idx], r.items[idx+1:]...)
+		if idx < r.next {
+			r.next--
+		}
+		if r.next >= len(r.items) {
+			r.next = 0
+		}
+		return
+	}
+}
+
+// Len returns the number of items in the rotation.
+func (r *RoundRobin[T]) Len() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.items)
+}
+
+// Next returns the next item in the rotation, waiting for one to be added if
+// the rotation is empty.
+func (r *RoundRobin[T]) Next(ctx context.Context) (T, error) {
+	for {
+		r.lock.Lock()
+		if len(r.items) > 0 {
+			item := r.items[r.next]
+			r.next = (r.next + 1) % len(r.items)
+			r.lock.Unlock()
+			return item, nil
+		}
+
+		if r.added == nil {
+			r.added = make(chan struct{})
+		}
+		added := r.added
+		r.lock.Unlock()
+
+		select {
+		case <-added:
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// Ready returns a channel which is closed once the rotation holds an item.
+func (r *RoundRobin[T]) Ready() <-chan struct{} {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(r.items) > 0 {
+		return closedChan
+	}
+
+	if r.added == nil {
+		r.added = make(chan struct{})
+	}
+	return r.added
+}
+
+var closedChan = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
@@ -0,0 +1,124 @@
+package socketutil
+
+import (
+	"context"
+	"sync"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// Subscriber is a peer of a publishing socket along with the topics it has
+// subscribed to.
+type Subscriber struct {
+	Subscriptions
+	Peer    *Peer
+	Queue   chan []zmtp.Message
+	backlog *Backlog
+}
+
+// Serve sends queued messages to the peer until ctx is done or an error is
+// received from errs.
+func (s *Subscriber) Serve(ctx context.Context, errs <-chan error) error {
+	for {
+		select {
+		case msg := <-s.Queue:
+			err := s.Peer.SendMessages(msg)
+			s.backlog.Done(1)
+			if err != nil {
+				return err
+			}
+		case err := <-errs:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Subscribers tracks the peers a publishing socket is connected to. The zero
+// value is ready for use.
+type Subscribers struct {
+	// Backlog counts messages queued for subscribers.
+	Backlog Backlog
+	lock    sync.RWMutex
+	subs    map[*Subscriber]struct{}
+}
+
+// Attach starts tracking the peer, queueing up to queueLen messages for it.
+func (s *Subscribers) Attach(peer *Peer, queueLen int) *Subscriber {
+	sub := &Subscriber{Peer: peer, Queue: make(chan []zmtp.Message, queueLen), backlog: &s.Backlog}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.subs == nil {
+		s.subs = make(map[*Subscriber]struct{})
+	}
+	s.subs[sub] = struct{}{}
+	return sub
+}
+
+// Detach stops tracking the subscriber, dropping anything still queued for
+// it. The subscriber must no longer be served.
+func (s *Subscribers) Detach(sub *Subscriber) {
+	s.lock.Lock()
+	delete(s.subs, sub)
+	s.lock.Unlock()
+	s.Backlog.Drain(sub.Queue)
+}
+
+// Depth totals the messages queued for subscribers and their capacity.
+func (s *Subscribers) Depth() gomq.QueueDepth {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var depth gomq.QueueDepth
+	for sub := range s.subs {
+		depth.Len += len(sub.Queue)
+		depth.Cap += cap(sub.Queue)
+	}
+	return depth
+}
+
+// Publish queues the message for every subscriber whose subscriptions match
+// its first frame. Subscribers whose queue is full miss the message.
+func (s *Subscribers) Publish(msg []zmtp.Message) {
+	if len(msg) == 0 {
+		return
+	}
+
+	s.publish(msg, func(sub *Subscriber) bool {
+		return sub.Match(msg[0].Body)
+	})
+}
+
+// PublishGroup queues the single frame message for every subscriber which
+// has joined its group, sending the group as a frame ahead of the body as
+// DISH peers expect. Subscribers whose queue is full miss the message.
+func (s *Subscribers) PublishGroup(msg zmtp.Message) {
+	frames := []zmtp.Message{
+		{More: true, Body: []byte(msg.Group)},
+		{Body: msg.Body},
+	}
+	s.publish(frames, func(sub *Subscriber) bool {
+		return sub.Has([]byte(msg.Group))
+	})
+}
+
+// publish queues the message for every subscriber matching it.
+func (s *Subscribers) publish(msg []zmtp.Message, match func(*Subscriber) bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for sub := range s.subs {
+		if !match(sub) {
+			continue
+		}
+
+		s.Backlog.Add(1)
+		select {
+		case sub.Queue <- msg:
+		default:
+			s.Backlog.Done(1)
+		}
+	}
+}
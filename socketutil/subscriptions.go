@@ -0,0 +1,82 @@
+package socketutil
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Subscriptions is a counted set of topic prefixes. The zero value is an
+// empty set ready for use.
+type Subscriptions struct {
+	sync.RWMutex
+	topics map[string]int
+}
+
+// Add a subscription to topic, returning true if this is the first
+// subscription to it.
+func (s *Subscriptions) Add(topic []byte) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.topics == nil {
+		s.topics = make(map[string]int)
+	}
+
+	s.topics[string(topic)]++
+	return s.topics[string(topic)] == 1
+}
+
+// Remove a subscription to topic, returning true if this was the last
+// subscription to it.
+func (s *Subscriptions) Remove(topic []byte) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	count, ok := s.topics[string(topic)]
+	if !ok {
+		return false
+	}
+
+	if count == 1 {
+		delete(s.topics, string(topic))
+		return true
+	}
+
+	s.topics[string(topic)] = count - 1
+	return false
+}
+
+// Match returns whether any subscribed topic is a prefix of body.
+func (s *Subscriptions) Match(body []byte) bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	for topic := range s.topics {
+		if bytes.HasPrefix(body, []byte(topic)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Has returns whether topic itself is subscribed to.
+func (s *Subscriptions) Has(topic []byte) bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	_, ok := s.topics[string(topic)]
+	return ok
+}
+
+// Topics returns each distinct subscribed topic.
+func (s *Subscriptions) Topics() [][]byte {
+	s.RLock()
+	defer s.RUnlock()
+
+	topics := make([][]byte, 0, len(s.topics))
+	for topic := range s.topics {
+		topics = append(topics, []byte(topic))
+	}
+	return topics
+}
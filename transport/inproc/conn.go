@@ -0,0 +1,228 @@
+package inproc
+
+import (
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// bufferSize is how many bytes may be written to a conn before the writer
+// blocks waiting for its peer to read.
+const bufferSize = 64 * 1024
+
+// buffer carries data in one direction between a pair of conns.
+type buffer struct {
+	lock     sync.Mutex
+	data     []byte
+	closed   bool
+	readable chan struct{}
+	writable chan struct{}
+}
+
+func newBuffer() *buffer {
+	return &buffer{
+		readable: make(chan struct{}, 1),
+		writable: make(chan struct{}, 1),
+	}
+}
+
+func (b *buffer) close() {
+	b.lock.Lock()
+	b.closed = true
+	b.lock.Unlock()
+	signal(b.readable)
+	signal(b.writable)
+}
+
+func signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
+
+// conn is one end of an in-memory net.Conn pair.
+type conn struct {
+	rx, tx        *buffer
+	addr          Addr
+	done          chan struct{}
+	closeOnce     sync.Once
+	readDeadline  deadline
+	writeDeadline deadline
+}
+
+func newConnPair(addr Addr) (*conn, *conn) {
+	ab, ba := newBuffer(), newBuffer()
+	a := &conn{
+		rx: ba, tx: ab, addr: addr, done: make(chan struct{}),
+		readDeadline: makeDeadline(), writeDeadline: makeDeadline(),
+	}
+	b := &conn{
+		rx: ab, tx: ba, addr: addr, done: make(chan struct{}),
+		readDeadline: makeDeadline(), writeDeadline: makeDeadline(),
+	}
+	return a, b
+}
+
+func (c *conn) Read(p []byte) (int, error) {
+	for {
+		switch {
+		case isClosed(c.done):
+			return 0, io.ErrClosedPipe
+		case isClosed(c.readDeadline.wait()):
+			return 0, os.ErrDeadlineExceeded
+		}
+
+		c.rx.lock.Lock()
+		if len(c.rx.data) > 0 {
+			n := copy(p, c.rx.data)
+			c.rx.data = c.rx.data[n:]
+			c.rx.lock.Unlock()
+			signal(c.rx.writable)
+			return n, nil
+		}
+		closed := c.rx.closed
+		c.rx.lock.Unlock()
+		if closed {
+			return 0, io.EOF
+		}
+
+		select {
+		case <-c.rx.readable:
+		case <-c.done:
+		case <-c.readDeadline.wait():
+		}
+	}
+}
+
+func (c *conn) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		switch {
+		case isClosed(c.done):
+			return written, io.ErrClosedPipe
+		case isClosed(c.writeDeadline.wait()):
+			return written, os.ErrDeadlineExceeded
+		}
+
+		c.tx.lock.Lock()
+		if c.tx.closed {
+			c.tx.lock.Unlock()
+			return written, io.ErrClosedPipe
+		}
+		if space := bufferSize - len(c.tx.data); space > 0 {
+			n := min(space, len(p))
+			c.tx.data = append(c.tx.data, p[:n]...)
+			c.tx.lock.Unlock()
+			signal(c.tx.readable)
+			p = p[n:]
+			written += n
+			continue
+		}
+		c.tx.lock.Unlock()
+
+		select {
+		case <-c.tx.writable:
+		case <-c.done:
+		case <-c.writeDeadline.wait():
+		}
+	}
+
+	return written, nil
+}
+
+// Close both directions, the peer reads whatever was already written
+// followed by io.EOF.
+func (c *conn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.rx.close()
+		c.tx.close()
+	})
+	return nil
+}
+
+func (c *conn) LocalAddr() net.Addr {
+	return c.addr
+}
+
+func (c *conn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+func (c *conn) SetDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	c.writeDeadline.set(t)
+	return nil
+}
+
+func (c *conn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	return nil
+}
+
+func (c *conn) SetWriteDeadline(t time.Time) error {
+	c.writeDeadline.set(t)
+	return nil
+}
+
+// deadline is a channel which is closed once a point in time passes.
+type deadline struct {
+	lock   *sync.Mutex
+	timer  *time.Timer
+	cancel chan struct{}
+}
+
+func makeDeadline() deadline {
+	return deadline{lock: &sync.Mutex{}, cancel: make(chan struct{})}
+}
+
+func (d *deadline) set(t time.Time) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.timer != nil && !d.timer.Stop() {
+		<-d.cancel
+	}
+	d.timer = nil
+
+	closed := isClosed(d.cancel)
+	if t.IsZero() {
+		if closed {
+			d.cancel = make(chan struct{})
+		}
+		return
+	}
+
+	if dur := time.Until(t); dur > 0 {
+		if closed {
+			d.cancel = make(chan struct{})
+		}
+		cancel := d.cancel
+		d.timer = time.AfterFunc(dur, func() {
+			close(cancel)
+		})
+		return
+	}
+
+	if !closed {
+		close(d.cancel)
+	}
+}
+
+func (d *deadline) wait() chan struct{} {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.cancel
+}
+
+func isClosed(c chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,183 @@
+package inproc
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sync"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/transport"
+)
+
+func init() {
+	gomq.RegisterTransport("inproc", func() transport.Transport {
+		return &Transport{}
+	})
+}
+
+// Transport implements transport.Transport for sockets within the same
+// gomq.Context, which creates one Transport per context. The zero value is
+// ready for use.
+type Transport struct {
+	lock      sync.Mutex
+	listeners map[string]*Listener
+	pending   map[string]chan struct{}
+}
+
+// Name of the transport is inproc.
+func (*Transport) Name() string {
+	return "inproc"
+}
+
+// Bind to an inproc name.
+func (t *Transport) Bind(url *url.URL) (net.Listener, error) {
+	name := url.Host + url.Path
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.listeners[name]; ok {
+		return nil, &net.OpError{Op: "listen", Net: "inproc", Addr: Addr(name), Err: ErrAddressInUse}
+	}
+
+	ln := &Listener{
+		transport: t,
+		name:      name,
+		conns:     make(chan net.Conn),
+		done:      make(chan struct{}),
+	}
+	if t.listeners == nil {
+		t.listeners = map[string]*Listener{}
+	}
+	t.listeners[name] = ln
+	if bound, ok := t.pending[name]; ok {
+		close(bound)
+		delete(t.pending, name)
+	}
+	return ln, nil
+}
+
+// Bound implements transport.PendingTransport.
+func (t *Transport) Bound(url *url.URL) <-chan struct{} {
+	name := url.Host + url.Path
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.listeners[name]; ok {
+		bound := make(chan struct{})
+		close(bound)
+		return bound
+	}
+
+	bound, ok := t.pending[name]
+	if !ok {
+		if t.pending == nil {
+			t.pending = map[string]chan struct{}{}
+		}
+		bound = make(chan struct{})
+		t.pending[name] = bound
+	}
+	return bound
+}
+
+// Connect to an inproc name. If nothing is bound to the name, Connect fails
+// at once with ErrNotBound, which is not fatal. Sockets wait on Bound before
+// connecting, so their connections stay pending until something is.
+func (t *Transport) Connect(
+	ctx context.Context,
+	url *url.URL,
+) (
+	conn net.Conn,
+	fatal bool,
+	err error,
+) {
+	name := url.Host + url.Path
+	for {
+		t.lock.Lock()
+		ln, ok := t.listeners[name]
+		t.lock.Unlock()
+		if !ok {
+			return nil, false, &net.OpError{Op: "dial", Net: "inproc", Addr: Addr(name), Err: ErrNotBound}
+		}
+
+		client, server := newConnPair(Addr(name))
+		select {
+		case ln.conns <- server:
+			return client, false, nil
+		case <-ln.done:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+}
+
+func (t *Transport) unbind(ln *Listener) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.listeners[ln.name] == ln {
+		delete(t.listeners, ln.name)
+	}
+}
+
+// Listener implements net.Listener for an inproc name.
+type Listener struct {
+	transport *Transport
+	name      string
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Accept the next connection to the name.
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close the listener, freeing the name for another bind.
+func (l *Listener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.done)
+		l.transport.unbind(l)
+	})
+	return nil
+}
+
+// Addr returns the name the listener is bound to.
+func (l *Listener) Addr() net.Addr {
+	return Addr(l.name)
+}
+
+// Addr is an inproc name.
+type Addr string
+
+// Network returns inproc.
+func (Addr) Network() string {
+	return "inproc"
+}
+
+// String returns the name.
+func (a Addr) String() string {
+	return string(a)
+}
+
+type addressInUse struct{}
+
+func (addressInUse) Error() string {
+	return "Address in use"
+}
+
+var ErrAddressInUse addressInUse
+
+type notBound struct{}
+
+func (notBound) Error() string {
+	return "Nothing bound to address"
+}
+
+var ErrNotBound notBound
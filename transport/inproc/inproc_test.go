@@ -0,0 +1,152 @@
+package inproc_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/transport/inproc"
+	_ "github.com/workspace-9/gomq/types/pull"
+	_ "github.com/workspace-9/gomq/types/push"
+	_ "github.com/workspace-9/gomq/zmtp/null"
+)
+
+// TestConnectBeforeBind checks that connecting to an unbound name fails at
+// once without being fatal.
+func TestConnectBeforeBind(t *testing.T) {
+	tp := &inproc.Transport{}
+	url, _ := url.Parse("inproc://unbound")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, fatal, err := tp.Connect(ctx, url)
+	if !errors.Is(err, inproc.ErrNotBound) || fatal {
+		t.Fatalf("expected non-fatal ErrNotBound, got %v (fatal %v)", err, fatal)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("Connect waited %s", elapsed)
+	}
+}
+
+// TestSocketConnectBeforeBind checks that a socket connecting before the
+// bind neither blocks nor loses the message it queued meanwhile.
+func TestSocketConnectBeforeBind(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	push, err := ctx.NewSocket("PUSH", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer push.Close()
+	push.SetOption(gomq.OptionReconnectIvl, 10*time.Millisecond)
+
+	start := time.Now()
+	if err := push.Connect("inproc://later"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("Connect waited %s", elapsed)
+	}
+	if err := push.Send([][]byte{[]byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+
+	pull, err := ctx.NewSocket("PULL", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pull.Close()
+	pull.SetOption(gomq.OptionRecvTimeout, 5*time.Second)
+	if err := pull.Bind("inproc://later"); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := pull.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msg) != 1 || string(msg[0]) != "hello" {
+		t.Fatalf("expected hello, got %q", msg)
+	}
+}
+
+// TestZeroValueBind checks that a zero Transport can be bound, and that a
+// name's Bound channel is closed by the bind.
+func TestZeroValueBind(t *testing.T) {
+	tp := &inproc.Transport{}
+	url, _ := url.Parse("inproc://zero")
+
+	bound := tp.Bound(url)
+	select {
+	case <-bound:
+		t.Fatal("expected the name to be unbound")
+	default:
+	}
+
+	ln, err := tp.Bind(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	select {
+	case <-bound:
+	case <-time.After(time.Second):
+		t.Fatal("expected the bind to close Bound")
+	}
+}
+
+// TestPendingConnect checks that a socket connecting before the bind waits
+// for it without failing, retrying or counting reconnects.
+func TestPendingConnect(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	push, err := ctx.NewSocket("PUSH", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer push.Close()
+	events := push.Monitor(gomq.Events(gomq.EventTypeConnectFailed, gomq.EventTypeConnectRetried))
+	push.SetOption(gomq.OptionReconnectIvl, time.Millisecond)
+	if err := push.Connect("inproc://pending"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	pull, err := ctx.NewSocket("PULL", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pull.Close()
+	pull.SetOption(gomq.OptionRecvTimeout, 5*time.Second)
+	if err := pull.Bind("inproc://pending"); err != nil {
+		t.Fatal(err)
+	}
+	if err := push.Send([][]byte{[]byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pull.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ev := <-events:
+		t.Fatalf("expected the connect to wait for the bind, got %v", ev)
+	default:
+	}
+
+	var reconnects float64
+	push.Collect(func(sample gomq.Sample) {
+		if sample.Name == "gomq_reconnects_total" {
+			reconnects += sample.Value
+		}
+	})
+	if reconnects != 0 {
+		t.Fatalf("expected no reconnects, got %v", reconnects)
+	}
+}
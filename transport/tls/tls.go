@@ -0,0 +1,241 @@
+// Package tls implements the tls transport, ZMTP over TLS on TCP.
+//
+// Endpoints are configured by the query of their URL, on top of the
+// transport's base config or the config set for the endpoint:
+//
+//	cert, key    PEM files holding this side's certificate and private key
+//	ca           PEM file of CAs trusted to sign the peer's certificate
+//	client_auth  none, request, require_any, verify_if_given or require
+//	server_name  the name sent for SNI and checked against the server's
+//	             certificate, by default the host of the URL
+//
+// For example tls://example.com:5555?cert=client.pem&key=client.key&ca=ca.pem.
+package tls
+
+import (
+	"context"
+	stdtls "crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/transport"
+)
+
+func init() {
+	gomq.RegisterTransport("tls", func() transport.Transport {
+		return &Transport{}
+	})
+}
+
+// PropertyPeerSubject is the metadata property holding the subject of the
+// peer's verified certificate.
+const PropertyPeerSubject = "Peer-Certificate-Subject"
+
+// Transport implements transport.Transport. Use gomq.Context.SetTransport
+// to give a context a Transport with its own configs.
+type Transport struct {
+	// Config is the base config of endpoints without one of their own, an
+	// empty config if nil.
+	Config *stdtls.Config
+
+	lock      sync.RWMutex
+	endpoints map[string]*stdtls.Config
+}
+
+// Name of the transport is tls.
+func (*Transport) Name() string {
+	return "tls"
+}
+
+// SetEndpointConfig sets the base config of the endpoint at hostport, in
+// place of Config.
+func (t *Transport) SetEndpointConfig(hostport string, conf *stdtls.Config) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.endpoints == nil {
+		t.endpoints = make(map[string]*stdtls.Config)
+	}
+	t.endpoints[hostport] = conf
+}
+
+// config returns the config of the endpoint with its URL's query applied.
+func (t *Transport) config(url *url.URL) (*stdtls.Config, error) {
+	t.lock.RLock()
+	base, ok := t.endpoints[url.Host]
+	if !ok {
+		base = t.Config
+	}
+	t.lock.RUnlock()
+
+	conf := &stdtls.Config{}
+	if base != nil {
+		conf = base.Clone()
+	}
+
+	query := url.Query()
+	if cert, key := query.Get("cert"), query.Get("key"); cert != "" || key != "" {
+		pair, err := stdtls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, err
+		}
+		conf.Certificates = []stdtls.Certificate{pair}
+	}
+
+	if ca := query.Get("ca"); ca != "" {
+		pem, err := os.ReadFile(ca)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%w: %s", ErrNoCertificates, ca)
+		}
+		conf.RootCAs = pool
+		conf.ClientCAs = pool
+	}
+
+	if mode, ok := query["client_auth"]; ok {
+		auth, ok := clientAuthModes[mode[0]]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownClientAuth, mode[0])
+		}
+		conf.ClientAuth = auth
+	}
+
+	if name := query.Get("server_name"); name != "" {
+		conf.ServerName = name
+	}
+	if conf.ServerName == "" {
+		conf.ServerName = url.Hostname()
+	}
+
+	return conf, nil
+}
+
+var clientAuthModes = map[string]stdtls.ClientAuthType{
+	"none":            stdtls.NoClientCert,
+	"request":         stdtls.RequestClientCert,
+	"require_any":     stdtls.RequireAnyClientCert,
+	"verify_if_given": stdtls.VerifyClientCertIfGiven,
+	"require":         stdtls.RequireAndVerifyClientCert,
+}
+
+// Bind to a tcp address, accepting TLS connections.
+func (t *Transport) Bind(url *url.URL) (net.Listener, error) {
+	conf, err := t.config(url)
+	if err != nil {
+		return nil, err
+	}
+	if len(conf.Certificates) == 0 && conf.GetCertificate == nil && conf.GetConfigForClient == nil {
+		return nil, ErrNoServerCertificate
+	}
+
+	tcpAddr, err := net.ResolveTCPAddr("tcp", url.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	ln, err := net.ListenTCP("tcp", tcpAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return listener{stdtls.NewListener(ln, conf)}, nil
+}
+
+// Connect to a tcp address, completing the TLS handshake.
+func (t *Transport) Connect(
+	ctx context.Context,
+	url *url.URL,
+) (
+	conn net.Conn,
+	fatal bool,
+	err error,
+) {
+	conf, err := t.config(url)
+	if err != nil {
+		return nil, true, err
+	}
+
+	if _, err := net.ResolveTCPAddr("tcp", url.Host); err != nil {
+		return nil, true, err
+	}
+
+	d := stdtls.Dialer{Config: conf}
+	conn, err = d.DialContext(ctx, "tcp", url.Host)
+	if err != nil {
+		return nil, false, err
+	}
+	return &Conn{conn.(*stdtls.Conn)}, false, nil
+}
+
+// Conn is a TLS connection reporting the subject of the peer's verified
+// certificate as a metadata property.
+type Conn struct {
+	*stdtls.Conn
+}
+
+// PeerProperties implements transport.PeerPropertiesConn, completing the
+// handshake if need be.
+func (c *Conn) PeerProperties() map[string]string {
+	if err := c.Handshake(); err != nil {
+		return nil
+	}
+
+	// Certificates accepted without verification, as client_auth request
+	// and require_any allow, prove nothing.
+	chains := c.ConnectionState().VerifiedChains
+	if len(chains) == 0 {
+		return nil
+	}
+	return map[string]string{PropertyPeerSubject: chains[0][0].Subject.String()}
+}
+
+// PeerPropertyNames implements transport.PeerPropertiesConn.
+func (c *Conn) PeerPropertyNames() []string {
+	return []string{PropertyPeerSubject}
+}
+
+type listener struct {
+	net.Listener
+}
+
+func (l listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Conn{conn.(*stdtls.Conn)}, nil
+}
+
+type noServerCertificate struct{}
+
+func (noServerCertificate) Error() string {
+	return "Binding tls needs a certificate"
+}
+
+var ErrNoServerCertificate noServerCertificate
+
+type noCertificates struct{}
+
+func (noCertificates) Error() string {
+	return "No certificates found"
+}
+
+var ErrNoCertificates noCertificates
+
+type unknownClientAuth struct{}
+
+func (unknownClientAuth) Error() string {
+	return "Unknown client_auth mode"
+}
+
+var ErrUnknownClientAuth unknownClientAuth
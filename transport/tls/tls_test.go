@@ -0,0 +1,274 @@
+package tls_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	stdtls "crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/transport/tls"
+	_ "github.com/workspace-9/gomq/types/pull"
+	_ "github.com/workspace-9/gomq/types/push"
+	"github.com/workspace-9/gomq/zap"
+	"github.com/workspace-9/gomq/zmtp"
+	_ "github.com/workspace-9/gomq/zmtp/null"
+)
+
+// pki is a CA and certificates it signed for 127.0.0.1.
+type pki struct {
+	ca     *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	pool   *x509.CertPool
+	serial int64
+}
+
+func newPKI(t *testing.T) *pki {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	return &pki{ca: ca, caKey: key, pool: pool, serial: 1}
+}
+
+// issue a certificate for 127.0.0.1 with the common name, usable by both
+// clients and servers.
+func (p *pki) issue(t *testing.T, name string) stdtls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(p.serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, p.ca, &key.PublicKey, p.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stdtls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// writeFiles writes the certificate, its key and the CA as PEM files,
+// returning their paths.
+func (p *pki) writeFiles(t *testing.T, cert stdtls.Certificate) (certFile, keyFile, caFile string) {
+	t.Helper()
+	dir := t.TempDir()
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []struct {
+		path, typ string
+		der       []byte
+	}{
+		{filepath.Join(dir, "cert.pem"), "CERTIFICATE", cert.Certificate[0]},
+		{filepath.Join(dir, "key.pem"), "PRIVATE KEY", keyDER},
+		{filepath.Join(dir, "ca.pem"), "CERTIFICATE", p.ca.Raw},
+	} {
+		data := pem.EncodeToMemory(&pem.Block{Type: file.typ, Bytes: file.der})
+		if err := os.WriteFile(file.path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem")
+}
+
+// TestMutualTLS checks that a socket bound with client_auth=require learns
+// the subject of the connecting socket's certificate, and that messages
+// pass between them.
+func TestMutualTLS(t *testing.T) {
+	p := newPKI(t)
+	serverConf := &stdtls.Config{
+		Certificates: []stdtls.Certificate{p.issue(t, "server")},
+		ClientCAs:    p.pool,
+		ClientAuth:   stdtls.RequireAndVerifyClientCert,
+	}
+	clientConf := &stdtls.Config{
+		Certificates: []stdtls.Certificate{p.issue(t, "client")},
+		RootCAs:      p.pool,
+	}
+
+	serverCtx := gomq.NewContext(context.Background())
+	serverCtx.SetEventBus(nil)
+	serverCtx.SetTransport("tls", &tls.Transport{Config: serverConf})
+	subjects := make(chan string, 1)
+	serverCtx.SetZAPHandler(zap.HandlerFunc(func(req zap.Request) zap.Response {
+		subjects <- req.Properties[tls.PropertyPeerSubject]
+		return zap.Response{RequestID: req.RequestID, StatusCode: zap.StatusSuccess, StatusText: "OK"}
+	}))
+
+	pull, err := serverCtx.NewSocket("PULL", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pull.Close()
+	pull.SetOption(gomq.OptionRecvTimeout, 5*time.Second)
+	// NULL only consults the handler for sockets with a domain.
+	if err := pull.SetOption(zmtp.OptionZAPDomain, "test"); err != nil {
+		t.Fatal(err)
+	}
+	events := pull.Monitor(gomq.Events(gomq.EventTypeListening))
+	if err := pull.Bind("tls://127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	addr := (<-events).LocalAddr
+
+	clientCtx := gomq.NewContext(context.Background())
+	clientCtx.SetEventBus(nil)
+	clientCtx.SetTransport("tls", &tls.Transport{Config: clientConf})
+	push, err := clientCtx.NewSocket("PUSH", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer push.Close()
+	if err := push.Connect(addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := push.Send([][]byte{[]byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := pull.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msg) != 1 || string(msg[0]) != "hello" {
+		t.Fatalf("expected hello, got %q", msg)
+	}
+	select {
+	case subject := <-subjects:
+		if subject != "CN=client" {
+			t.Fatalf("expected subject CN=client, got %q", subject)
+		}
+	default:
+		t.Fatal("the ZAP handler was not consulted")
+	}
+}
+
+// TestURLConfig checks that endpoints are configured by the query of their
+// URL, and that a client without a certificate is refused by a server
+// requiring one.
+func TestURLConfig(t *testing.T) {
+	p := newPKI(t)
+	serverCert, serverKey, ca := p.writeFiles(t, p.issue(t, "server"))
+	clientCert, clientKey, _ := p.writeFiles(t, p.issue(t, "client"))
+
+	tp := &tls.Transport{}
+	bindURL, _ := url.Parse("tls://127.0.0.1:0?client_auth=require&cert=" + serverCert + "&key=" + serverKey + "&ca=" + ca)
+	ln, err := tp.Bind(bindURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			// Nothing reads from the connection, so complete the handshake
+			// the dialer waits for here.
+			conn.(*tls.Conn).Handshake()
+			accepted <- conn
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	host := ln.Addr().String()
+
+	withCert, _ := url.Parse("tls://" + host + "?cert=" + clientCert + "&key=" + clientKey + "&ca=" + ca)
+	conn, _, err := tp.Connect(ctx, withCert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	server := <-accepted
+	defer server.Close()
+	props := server.(*tls.Conn).PeerProperties()
+	if subject := props[tls.PropertyPeerSubject]; subject != "CN=client" {
+		t.Fatalf("expected subject CN=client, got %q", subject)
+	}
+	if subject := conn.(*tls.Conn).PeerProperties()[tls.PropertyPeerSubject]; subject != "CN=server" {
+		t.Fatalf("expected subject CN=server, got %q", subject)
+	}
+
+	withoutCert, _ := url.Parse("tls://" + host + "?ca=" + ca)
+	conn, _, err = tp.Connect(ctx, withoutCert)
+	if err == nil {
+		// TLS 1.3 clients finish their handshake before the server checks
+		// their certificate, so the refusal arrives on the first read.
+		defer conn.Close()
+		_, err = conn.Read(make([]byte, 1))
+	}
+	if err == nil {
+		t.Fatal("expected a client without a certificate to be refused")
+	}
+}
+
+// TestConfigErrors checks that bad endpoint configs are reported.
+func TestConfigErrors(t *testing.T) {
+	tp := &tls.Transport{}
+	noCert, _ := url.Parse("tls://127.0.0.1:0")
+	if _, err := tp.Bind(noCert); !errors.Is(err, tls.ErrNoServerCertificate) {
+		t.Fatalf("expected ErrNoServerCertificate, got %v", err)
+	}
+
+	badMode, _ := url.Parse("tls://127.0.0.1:0?client_auth=sometimes")
+	if _, fatal, err := tp.Connect(context.Background(), badMode); !errors.Is(err, tls.ErrUnknownClientAuth) || !fatal {
+		t.Fatalf("expected fatal ErrUnknownClientAuth, got %v (fatal %v)", err, fatal)
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	os.WriteFile(empty, nil, 0o600)
+	badCA, _ := url.Parse("tls://127.0.0.1:0?ca=" + empty)
+	if _, _, err := tp.Connect(context.Background(), badCA); !errors.Is(err, tls.ErrNoCertificates) {
+		t.Fatalf("expected ErrNoCertificates, got %v", err)
+	}
+}
@@ -22,6 +22,43 @@ type Transport interface {
 	) (conn net.Conn, fatal bool, err error)
 }
 
+// DatagramTransport is implemented by transports carrying whole messages in
+// datagrams, without connections or ZMTP. Only socket types sending single
+// frames, such as RADIO and DISH, use them.
+type DatagramTransport interface {
+	Transport
+
+	// ListenDatagrams receives datagrams sent to the address.
+	ListenDatagrams(url *url.URL) (net.PacketConn, error)
+
+	// DialDatagrams sends datagrams to the address.
+	DialDatagrams(url *url.URL) (net.Conn, error)
+}
+
+// PendingTransport is implemented by transports which know when an address
+// is bound, such as inproc. Connections to an address nothing is bound to
+// stay pending until it is, rather than failing and retrying.
+type PendingTransport interface {
+	Transport
+
+	// Bound returns a channel which is closed once the address is bound.
+	Bound(url *url.URL) <-chan struct{}
+}
+
+// PeerPropertiesConn is implemented by connections whose transport learns
+// who the peer is, such as from its certificate. The properties are passed
+// to zap handlers and added to the peer's metadata, from which properties of
+// the same names sent by the peer are removed, so the peer can neither
+// override nor forge them.
+type PeerPropertiesConn interface {
+	// PeerProperties returns metadata properties describing the peer.
+	PeerProperties() map[string]string
+
+	// PeerPropertyNames returns the names of every property the transport
+	// owns, including those it learned nothing about for this peer.
+	PeerPropertyNames() []string
+}
+
 // BuildURL builds a URL given a tranposrt and an address.
 func BuildURL(addr net.Addr, tp Transport) string {
 	return fmt.Sprintf("%s://%s", tp.Name(), addr.String())
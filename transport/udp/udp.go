@@ -0,0 +1,124 @@
+// Package udp implements the udp transport, which carries messages from
+// RADIO to DISH sockets in datagrams, losing any that do not arrive. A DISH
+// binds the address to receive on and a RADIO connects to it.
+//
+// Multicast addresses are supported. The interface query parameter names
+// the interface a DISH joins the multicast group on, and a RADIO sends from,
+// in place of the system default, as in udp://239.0.0.1:5555?interface=eth0.
+package udp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/transport"
+)
+
+func init() {
+	gomq.RegisterTransport("udp", func() transport.Transport {
+		return Transport{}
+	})
+}
+
+// Transport implements transport.DatagramTransport.
+type Transport struct{}
+
+// Name of the transport is udp.
+func (Transport) Name() string {
+	return "udp"
+}
+
+// Bind fails, udp only carries datagrams.
+func (Transport) Bind(*url.URL) (net.Listener, error) {
+	return nil, ErrDatagramsOnly
+}
+
+// Connect fails, udp only carries datagrams.
+func (Transport) Connect(context.Context, *url.URL) (net.Conn, bool, error) {
+	return nil, true, ErrDatagramsOnly
+}
+
+// ListenDatagrams receives datagrams sent to a udp address, joining the
+// multicast group if it is one.
+func (Transport) ListenDatagrams(url *url.URL) (net.PacketConn, error) {
+	addr, err := net.ResolveUDPAddr("udp", url.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	if !addr.IP.IsMulticast() {
+		return net.ListenUDP("udp", addr)
+	}
+
+	iface, err := multicastInterface(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return net.ListenMulticastUDP("udp", iface, addr)
+}
+
+// multicastInterface returns the interface named by the url, or nil for
+// the system default.
+func multicastInterface(url *url.URL) (*net.Interface, error) {
+	name := url.Query().Get("interface")
+	if name == "" {
+		return nil, nil
+	}
+
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnknownInterface, err)
+	}
+	return iface, nil
+}
+
+// DialDatagrams sends datagrams to a udp address.
+func (Transport) DialDatagrams(url *url.URL) (net.Conn, error) {
+	addr, err := net.ResolveUDPAddr("udp", url.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil || !addr.IP.IsMulticast() {
+		return conn, err
+	}
+
+	iface, err := multicastInterface(url)
+	if err == nil && iface != nil {
+		err = setMulticastInterface(conn, addr, iface)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+type datagramsOnly struct{}
+
+func (datagramsOnly) Error() string {
+	return "Transport only carries datagrams for RADIO and DISH"
+}
+
+var ErrDatagramsOnly datagramsOnly
+
+type unknownInterface struct{}
+
+func (unknownInterface) Error() string {
+	return "Unknown interface"
+}
+
+var ErrUnknownInterface unknownInterface
+
+type interfaceUnsupported struct{}
+
+func (interfaceUnsupported) Error() string {
+	return "Choosing the multicast interface is not supported"
+}
+
+var ErrInterfaceUnsupported interfaceUnsupported
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package udp
+
+import (
+	"net"
+)
+
+func setMulticastInterface(conn *net.UDPConn, group *net.UDPAddr, iface *net.Interface) error {
+	return ErrInterfaceUnsupported
+}
@@ -0,0 +1,58 @@
+//go:build unix
+
+package udp
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// setMulticastInterface sends the conn's multicast datagrams from iface.
+func setMulticastInterface(conn *net.UDPConn, group *net.UDPAddr, iface *net.Interface) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var opt func(fd int) error
+	if group.IP.To4() == nil {
+		opt = func(fd int) error {
+			return unix.SetsockoptInt(fd, unix.IPPROTO_IPV6, unix.IPV6_MULTICAST_IF, iface.Index)
+		}
+	} else {
+		addr, err := interfaceIPv4(iface)
+		if err != nil {
+			return err
+		}
+		opt = func(fd int) error {
+			return unix.SetsockoptInet4Addr(fd, unix.IPPROTO_IP, unix.IP_MULTICAST_IF, addr)
+		}
+	}
+
+	var optErr error
+	if err := raw.Control(func(fd uintptr) {
+		optErr = opt(int(fd))
+	}); err != nil {
+		return err
+	}
+	return optErr
+}
+
+// interfaceIPv4 returns the first IPv4 address of the interface.
+func interfaceIPv4(iface *net.Interface) ([4]byte, error) {
+	var ip4 [4]byte
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return ip4, err
+	}
+
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+			copy(ip4[:], ipNet.IP.To4())
+			return ip4, nil
+		}
+	}
+	return ip4, fmt.Errorf("%w: %s has no IPv4 address", ErrUnknownInterface, iface.Name)
+}
@@ -0,0 +1,430 @@
+package ws
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// WebSocket opcodes.
+const (
+	opContinuation = 0x0
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// Flags of a ZMTP frame.
+const (
+	zmtpMore    = 0x01
+	zmtpLong    = 0x02
+	zmtpCommand = 0x04
+)
+
+// Flags leading a ZWS message.
+const (
+	zwsFinal   = 0x00
+	zwsMore    = 0x01
+	zwsCommand = 0x02
+)
+
+// closeTimeout bounds how long Close waits to send a close frame.
+const closeTimeout = 100 * time.Millisecond
+
+// Conn carries ZMTP frames in WebSocket binary messages, each message a
+// flags byte followed by the frame's body, while presenting the ZMTP 3.1
+// byte stream to mechanisms. ZWS 2.0 has no greeting and agrees on the
+// mechanism as the WebSocket subprotocol instead, so Conn opens the
+// WebSocket once the greeting has been written to it and answers with a
+// greeting to match.
+type Conn struct {
+	net.Conn
+	client bool
+	// host and path are the target of the upgrade request, or for servers
+	// the path expected of it.
+	host   string
+	path   string
+	reader *bufio.Reader
+
+	lock        sync.Mutex
+	greeting    zmtp.Greeting
+	greetingOut int
+	greetingIn  int
+	established bool
+
+	readLock sync.Mutex
+	pending  []byte
+
+	writeLock sync.Mutex
+	out       []byte
+}
+
+func newConn(conn net.Conn, client bool, host, path string) *Conn {
+	return &Conn{
+		Conn:   conn,
+		client: client,
+		host:   host,
+		path:   path,
+		reader: bufio.NewReader(conn),
+	}
+}
+
+// Read ZMTP bytes, the greeting answering our own and then the frames of
+// the messages received.
+func (c *Conn) Read(p []byte) (int, error) {
+	c.lock.Lock()
+	if c.greetingIn < len(c.greeting) {
+		defer c.lock.Unlock()
+		return c.readGreeting(p)
+	}
+	c.lock.Unlock()
+
+	c.readLock.Lock()
+	defer c.readLock.Unlock()
+
+	for len(c.pending) == 0 {
+		if err := c.readMessage(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// readGreeting reads from the peer's greeting. Up to the version major it
+// is the same for every peer, after that it depends on our greeting, which
+// must have been written. The lock must be held.
+func (c *Conn) readGreeting(p []byte) (int, error) {
+	peer := zmtp.NewGreeting()
+	peer.SetVersionMajor(3)
+	avail := 11
+	if c.established {
+		mech := c.greeting.Mechanism()
+		peer.SetVersionMinor(1)
+		peer.SetMechanism(mech)
+		// NULL has no server, other mechanisms one on either side.
+		peer.SetServer(mech != nullMechanism && !c.greeting.Server())
+		avail = len(peer)
+	}
+
+	if c.greetingIn >= avail {
+		return 0, ErrGreetingUnsent
+	}
+
+	n := copy(p, peer[c.greetingIn:avail])
+	c.greetingIn += n
+	return n, nil
+}
+
+// Write ZMTP bytes, the greeting opening the WebSocket and then frames,
+// each sent as a message once whole.
+func (c *Conn) Write(p []byte) (int, error) {
+	c.lock.Lock()
+	written := 0
+	if !c.established {
+		written = copy(c.greeting[c.greetingOut:], p)
+		c.greetingOut += written
+		if c.greetingOut < len(c.greeting) {
+			c.lock.Unlock()
+			return written, nil
+		}
+
+		if err := c.handshake(); err != nil {
+			c.lock.Unlock()
+			return written, err
+		}
+		c.established = true
+		p = p[written:]
+	}
+	c.lock.Unlock()
+
+	if len(p) == 0 {
+		return written, nil
+	}
+
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	c.out = append(c.out, p...)
+	consumed := 0
+	for {
+		flags, body, size, err := splitFrame(c.out[consumed:])
+		if err != nil {
+			return written, err
+		}
+		if size == 0 {
+			break
+		}
+
+		zwsFlags := byte(zwsFinal)
+		switch {
+		case flags&zmtpCommand != 0:
+			zwsFlags = zwsCommand
+		case flags&zmtpMore != 0:
+			zwsFlags = zwsMore
+		}
+		if err := c.writeFrame(opBinary, []byte{zwsFlags}, body); err != nil {
+			return written, err
+		}
+		consumed += size
+	}
+
+	c.out = c.out[:copy(c.out, c.out[consumed:])]
+	return written + len(p), nil
+}
+
+// splitFrame returns the flags and body of the ZMTP frame at the start of
+// buf along with its size, or a size of zero if the frame is incomplete.
+func splitFrame(buf []byte) (flags byte, body []byte, size int, err error) {
+	if len(buf) < 2 {
+		return 0, nil, 0, nil
+	}
+
+	flags = buf[0]
+	if flags&^(zmtpMore|zmtpLong|zmtpCommand) != 0 {
+		return 0, nil, 0, fmt.Errorf("%w: invalid byte %x", zmtp.ErrInvalidFrameHeader, flags)
+	}
+
+	header, bodyLen := 2, uint64(buf[1])
+	if flags&zmtpLong != 0 {
+		if len(buf) < 9 {
+			return 0, nil, 0, nil
+		}
+		header, bodyLen = 9, binary.BigEndian.Uint64(buf[1:9])
+	}
+
+	if uint64(len(buf)-header) < bodyLen {
+		return 0, nil, 0, nil
+	}
+	size = header + int(bodyLen)
+	return flags, buf[header:size], size, nil
+}
+
+// writeFrame writes a final WebSocket frame whose payload is parts, masked
+// if this is the client. The write lock must be held.
+func (c *Conn) writeFrame(op byte, parts ...[]byte) error {
+	size := 0
+	for _, part := range parts {
+		size += len(part)
+	}
+
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+
+	frame := make([]byte, 0, 14+size)
+	frame = append(frame, 0x80|op)
+	switch {
+	case size < 126:
+		frame = append(frame, maskBit|byte(size))
+	case size <= math.MaxUint16:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(size))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(size))
+	}
+
+	var key [4]byte
+	if c.client {
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		frame = append(frame, key[:]...)
+	}
+
+	start := len(frame)
+	for _, part := range parts {
+		frame = append(frame, part...)
+	}
+	if c.client {
+		mask(frame[start:], key)
+	}
+
+	_, err := c.Conn.Write(frame)
+	return err
+}
+
+// readFrame reads a WebSocket frame, unmasking its payload.
+func (c *Conn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.reader, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, fmt.Errorf("%w: reserved bits set", ErrProtocol)
+	}
+	fin, op = head[0]&0x80 != 0, head[0]&0x0F
+
+	size := uint64(head[1] & 0x7F)
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		size = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(ext[:])
+	}
+	if size > math.MaxInt32 {
+		return false, 0, nil, fmt.Errorf("%w: frame of %d bytes", ErrProtocol, size)
+	}
+
+	var key [4]byte
+	masked := head[1]&0x80 != 0
+	if masked {
+		if _, err := io.ReadFull(c.reader, key[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload = make([]byte, size)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		mask(payload, key)
+	}
+	return fin, op, payload, nil
+}
+
+// readMessage reads the next binary message, answering control frames on
+// the way, and queues it as a ZMTP frame. The read lock must be held.
+func (c *Conn) readMessage() error {
+	var msg []byte
+	started := false
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return err
+		}
+
+		switch op {
+		case opPing:
+			c.writeLock.Lock()
+			err := c.writeFrame(opPong, payload)
+			c.writeLock.Unlock()
+			if err != nil {
+				return err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			c.writeLock.Lock()
+			c.writeFrame(opClose, payload[:min(2, len(payload))])
+			c.writeLock.Unlock()
+			return io.EOF
+		case opBinary:
+			if started {
+				return fmt.Errorf("%w: message interrupted", ErrProtocol)
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				return fmt.Errorf("%w: continuation without a message", ErrProtocol)
+			}
+		default:
+			return fmt.Errorf("%w: opcode %d", ErrProtocol, op)
+		}
+
+		msg = append(msg, payload...)
+		if fin {
+			break
+		}
+	}
+
+	return c.queueFrame(msg)
+}
+
+// queueFrame queues the ZMTP frame carried by a ZWS message. The read lock
+// must be held.
+func (c *Conn) queueFrame(msg []byte) error {
+	if len(msg) == 0 {
+		return fmt.Errorf("%w: empty message", ErrProtocol)
+	}
+
+	var flags byte
+	switch msg[0] {
+	case zwsFinal:
+	case zwsMore:
+		flags = zmtpMore
+	case zwsCommand:
+		flags = zmtpCommand
+	default:
+		return fmt.Errorf("%w: invalid flags %x", ErrProtocol, msg[0])
+	}
+
+	body := msg[1:]
+	frame := c.pending[:0]
+	if len(body) > math.MaxUint8 {
+		frame = append(frame, flags|zmtpLong)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(body)))
+	} else {
+		frame = append(frame, flags, byte(len(body)))
+	}
+	c.pending = append(frame, body...)
+	return nil
+}
+
+// Close sends a close frame if the WebSocket is open and closes the
+// connection.
+func (c *Conn) Close() error {
+	c.lock.Lock()
+	established := c.established
+	c.lock.Unlock()
+
+	if established {
+		// The deadline also frees a write blocked holding the lock.
+		c.Conn.SetWriteDeadline(time.Now().Add(closeTimeout))
+		c.writeLock.Lock()
+		c.writeFrame(opClose, []byte{0x03, 0xE8})
+		c.writeLock.Unlock()
+	}
+
+	return c.Conn.Close()
+}
+
+// PeerProperties passes on the properties of the underlying connection,
+// such as the certificate subject of wss peers.
+func (c *Conn) PeerProperties() map[string]string {
+	if propConn, ok := c.Conn.(transport.PeerPropertiesConn); ok {
+		return propConn.PeerProperties()
+	}
+
+	return nil
+}
+
+// PeerPropertyNames passes on the property names of the underlying
+// connection.
+func (c *Conn) PeerPropertyNames() []string {
+	if propConn, ok := c.Conn.(transport.PeerPropertiesConn); ok {
+		return propConn.PeerPropertyNames()
+	}
+
+	return nil
+}
+
+func mask(b []byte, key [4]byte) {
+	for idx := range b {
+		b[idx] ^= key[idx%4]
+	}
+}
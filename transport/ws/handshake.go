@@ -0,0 +1,161 @@
+package ws
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// protocolPrefix begins the subprotocols of ZWS 2.0, which end in the
+// mechanism. A bare ZWS2.0 means NULL.
+const protocolPrefix = "ZWS2.0"
+
+const nullMechanism = "NULL"
+
+// acceptMagic is appended to the client's key to compute the server's
+// accept header.
+const acceptMagic = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// protocols returns the subprotocols agreeing to mech, most specific
+// first.
+func protocols(mech string) []string {
+	protos := []string{protocolPrefix + "/" + mech}
+	if mech == nullMechanism {
+		protos = append(protos, protocolPrefix)
+	}
+	return protos
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptMagic))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// handshake opens the WebSocket for the mechanism of the greeting written.
+// The lock must be held.
+func (c *Conn) handshake() error {
+	if c.client {
+		return c.clientHandshake(c.greeting.Mechanism())
+	}
+
+	return c.serverHandshake(c.greeting.Mechanism())
+}
+
+func (c *Conn) clientHandshake(mech string) error {
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	offered := protocols(mech)
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &url.URL{Path: c.path},
+		Host:       c.host,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Upgrade":                {"websocket"},
+			"Connection":             {"Upgrade"},
+			"Sec-WebSocket-Key":      {key},
+			"Sec-WebSocket-Version":  {"13"},
+			"Sec-WebSocket-Protocol": {strings.Join(offered, ", ")},
+		},
+	}
+	if err := req.Write(c.Conn); err != nil {
+		return err
+	}
+
+	resp, err := http.ReadResponse(c.reader, req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return fmt.Errorf("%w: %s", ErrHandshakeFailed, resp.Status)
+	}
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") {
+		return fmt.Errorf("%w: not upgraded to websocket", ErrHandshakeFailed)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return fmt.Errorf("%w: wrong Sec-WebSocket-Accept", ErrHandshakeFailed)
+	}
+
+	chosen := resp.Header.Get("Sec-WebSocket-Protocol")
+	for _, proto := range offered {
+		if chosen == proto {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: server chose protocol %q", ErrHandshakeFailed, chosen)
+}
+
+func (c *Conn) serverHandshake(mech string) error {
+	req, err := http.ReadRequest(c.reader)
+	if err != nil {
+		return err
+	}
+	req.Body.Close()
+
+	status, proto, err := c.checkRequest(req, mech)
+	if err != nil {
+		fmt.Fprintf(c.Conn, "HTTP/1.1 %d %s\r\nConnection: close\r\nContent-Length: 0\r\n\r\n", status, http.StatusText(status))
+		return err
+	}
+
+	_, err = fmt.Fprintf(c.Conn,
+		"HTTP/1.1 101 Switching Protocols\r\n"+
+			"Upgrade: websocket\r\n"+
+			"Connection: Upgrade\r\n"+
+			"Sec-WebSocket-Accept: %s\r\n"+
+			"Sec-WebSocket-Protocol: %s\r\n\r\n",
+		acceptKey(req.Header.Get("Sec-WebSocket-Key")), proto)
+	return err
+}
+
+// checkRequest checks the upgrade request, returning the subprotocol to
+// choose or the status to refuse it with.
+func (c *Conn) checkRequest(req *http.Request, mech string) (int, string, error) {
+	if req.URL.Path != c.path {
+		return http.StatusNotFound, "", fmt.Errorf("%w: no socket at %s", ErrBadRequest, req.URL.Path)
+	}
+
+	if req.Method != http.MethodGet ||
+		!headerContains(req.Header, "Connection", "upgrade") ||
+		!headerContains(req.Header, "Upgrade", "websocket") ||
+		req.Header.Get("Sec-WebSocket-Key") == "" {
+		return http.StatusBadRequest, "", fmt.Errorf("%w: not a websocket upgrade", ErrBadRequest)
+	}
+
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		return http.StatusUpgradeRequired, "", fmt.Errorf("%w: websocket version %s", ErrBadRequest, req.Header.Get("Sec-WebSocket-Version"))
+	}
+
+	for _, proto := range protocols(mech) {
+		if headerContains(req.Header, "Sec-WebSocket-Protocol", proto) {
+			return 0, proto, nil
+		}
+	}
+	return http.StatusBadRequest, "", fmt.Errorf("%w: no protocol for %s", ErrBadRequest, mech)
+}
+
+// headerContains reports whether the comma separated lists of the header
+// contain the token, ignoring case.
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, item := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
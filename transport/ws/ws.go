@@ -0,0 +1,161 @@
+// Package ws implements the ws and wss transports, ZMTP over WebSocket as
+// in ZWS 2.0 (RFC 45), for clients that cannot open raw TCP connections,
+// such as browsers or those behind HTTP proxies.
+//
+// The path of the URL is the resource requested in the upgrade, as in
+// ws://example.com:8080/events. A bound socket only accepts upgrades for
+// its own path. The wss transport runs the WebSocket over TLS, configured
+// by the query of the URL in the same way as the tls transport.
+package ws
+
+import (
+	"context"
+	"net"
+	"net/url"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/transport/tcp"
+	"github.com/workspace-9/gomq/transport/tls"
+)
+
+func init() {
+	gomq.RegisterTransport("ws", func() transport.Transport {
+		return &Transport{}
+	})
+	gomq.RegisterTransport("wss", func() transport.Transport {
+		return &Transport{Secure: true, TLS: &tls.Transport{}}
+	})
+}
+
+// Transport implements transport.Transport.
+type Transport struct {
+	// Secure runs the WebSocket over TLS, making this the wss transport.
+	Secure bool
+
+	// TLS configures the TLS of wss endpoints, the defaults of the tls
+	// transport if nil.
+	TLS *tls.Transport
+}
+
+// Name of the transport is ws, or wss if secure.
+func (t *Transport) Name() string {
+	if t.Secure {
+		return "wss"
+	}
+
+	return "ws"
+}
+
+// stream returns the transport carrying the WebSocket.
+func (t *Transport) stream() transport.Transport {
+	switch {
+	case !t.Secure:
+		return tcp.Transport{}
+	case t.TLS == nil:
+		return &tls.Transport{}
+	default:
+		return t.TLS
+	}
+}
+
+// endpoint returns the url with the default port of the scheme if it has
+// none.
+func (t *Transport) endpoint(url *url.URL) *url.URL {
+	if url.Port() != "" {
+		return url
+	}
+
+	port := "80"
+	if t.Secure {
+		port = "443"
+	}
+
+	withPort := *url
+	withPort.Host = net.JoinHostPort(url.Hostname(), port)
+	return &withPort
+}
+
+// requestPath returns the resource named by url.
+func requestPath(url *url.URL) string {
+	if url.Path == "" {
+		return "/"
+	}
+
+	return url.Path
+}
+
+// Bind to a tcp address, accepting WebSocket upgrades for the url's path.
+func (t *Transport) Bind(url *url.URL) (net.Listener, error) {
+	url = t.endpoint(url)
+	ln, err := t.stream().Bind(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return listener{Listener: ln, path: requestPath(url)}, nil
+}
+
+// Connect to a tcp address. The WebSocket is opened with the greeting.
+func (t *Transport) Connect(
+	ctx context.Context,
+	url *url.URL,
+) (
+	conn net.Conn,
+	fatal bool,
+	err error,
+) {
+	url = t.endpoint(url)
+	conn, fatal, err = t.stream().Connect(ctx, url)
+	if err != nil {
+		return nil, fatal, err
+	}
+
+	return newConn(conn, true, url.Host, requestPath(url)), false, nil
+}
+
+type listener struct {
+	net.Listener
+	path string
+}
+
+func (l listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return newConn(conn, false, "", l.path), nil
+}
+
+type greetingUnsent struct{}
+
+func (greetingUnsent) Error() string {
+	return "Greeting must be sent before the peer's is read"
+}
+
+var ErrGreetingUnsent greetingUnsent
+
+type handshakeFailed struct{}
+
+func (handshakeFailed) Error() string {
+	return "WebSocket handshake failed"
+}
+
+var ErrHandshakeFailed handshakeFailed
+
+type badRequest struct{}
+
+func (badRequest) Error() string {
+	return "Bad WebSocket upgrade request"
+}
+
+var ErrBadRequest badRequest
+
+type protocolError struct{}
+
+func (protocolError) Error() string {
+	return "WebSocket protocol error"
+}
+
+var ErrProtocol protocolError
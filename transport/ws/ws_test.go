@@ -0,0 +1,332 @@
+package ws_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	stdtls "crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/transport/tls"
+	"github.com/workspace-9/gomq/transport/ws"
+	_ "github.com/workspace-9/gomq/types/pull"
+	_ "github.com/workspace-9/gomq/types/push"
+	"github.com/workspace-9/gomq/zmtp"
+	_ "github.com/workspace-9/gomq/zmtp/null"
+	_ "github.com/workspace-9/gomq/zmtp/plain"
+)
+
+// selfSigned returns a certificate for 127.0.0.1 and a pool trusting it.
+func selfSigned(t *testing.T) (stdtls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return stdtls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+// TestLoopback checks that messages of every WebSocket length encoding
+// pass between sockets over ws and wss, with NULL and PLAIN.
+func TestLoopback(t *testing.T) {
+	cert, pool := selfSigned(t)
+	bodies := [][]byte{
+		[]byte("short"),
+		bytes.Repeat([]byte("m"), 200),
+		bytes.Repeat([]byte("l"), 70000),
+	}
+
+	for _, scheme := range []string{"ws", "wss"} {
+		for _, mech := range []string{"NULL", "PLAIN"} {
+			t.Run(scheme+"/"+mech, func(t *testing.T) {
+				ctx := gomq.NewContext(context.Background())
+				ctx.SetEventBus(nil)
+				ctx.SetTransport("wss", &ws.Transport{Secure: true, TLS: &tls.Transport{Config: &stdtls.Config{
+					Certificates: []stdtls.Certificate{cert},
+					RootCAs:      pool,
+				}}})
+
+				pull, err := ctx.NewSocket("PULL", mech)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer pull.Close()
+				pull.SetOption(gomq.OptionRecvTimeout, 5*time.Second)
+				push, err := ctx.NewSocket("PUSH", mech)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer push.Close()
+				if mech == "PLAIN" {
+					pull.SetServer(true)
+					push.SetOption(zmtp.OptionPlainUsername, "user")
+					push.SetOption(zmtp.OptionPlainPassword, "secret")
+				}
+
+				events := pull.Monitor(gomq.Events(gomq.EventTypeListening))
+				if err := pull.Bind(scheme + "://127.0.0.1:0/loopback"); err != nil {
+					t.Fatal(err)
+				}
+				addr := (<-events).LocalAddr
+				if err := push.Connect(addr + "/loopback"); err != nil {
+					t.Fatal(err)
+				}
+
+				for _, body := range bodies {
+					if err := push.Send([][]byte{body}); err != nil {
+						t.Fatal(err)
+					}
+					msg, err := pull.Recv()
+					if err != nil {
+						t.Fatal(err)
+					}
+					if len(msg) != 1 || !bytes.Equal(msg[0], body) {
+						t.Fatalf("expected a message of %d bytes, got %d frames", len(body), len(msg))
+					}
+				}
+			})
+		}
+	}
+}
+
+// rawClient is a WebSocket client writing frames by hand.
+type rawClient struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// dialRaw connects to addr and requests an upgrade offering protocol.
+func dialRaw(t *testing.T, addr, protocol string) *rawClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	fmt.Fprintf(conn, "GET / HTTP/1.1\r\n"+
+		"Host: %s\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n"+
+		"Sec-WebSocket-Protocol: %s\r\n\r\n", addr, protocol)
+	return &rawClient{Conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// status reads the response to the upgrade request.
+func (c *rawClient) status(t *testing.T) int {
+	t.Helper()
+	resp, err := http.ReadResponse(c.reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// write a masked frame.
+func (c *rawClient) write(t *testing.T, fin bool, op byte, payload []byte) {
+	t.Helper()
+	head := op
+	if fin {
+		head |= 0x80
+	}
+	key := [4]byte{1, 2, 3, 4}
+	frame := []byte{head, 0x80 | byte(len(payload))}
+	frame = append(frame, key[:]...)
+	for idx, b := range payload {
+		frame = append(frame, b^key[idx%4])
+	}
+	if _, err := c.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// read an unmasked frame of under 126 bytes.
+func (c *rawClient) read(t *testing.T) (op byte, payload []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(c.reader, head[:]); err != nil {
+		t.Fatal(err)
+	}
+	payload = make([]byte, head[1]&0x7F)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		t.Fatal(err)
+	}
+	return head[0] & 0x0F, payload
+}
+
+// bindRaw binds a ws endpoint, returning the address and the first
+// connection accepted.
+func bindRaw(t *testing.T) (string, <-chan net.Conn) {
+	t.Helper()
+	tp := &ws.Transport{}
+	bindURL, _ := url.Parse("ws://127.0.0.1:0")
+	ln, err := tp.Bind(bindURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		t.Cleanup(func() { conn.Close() })
+		accepted <- conn
+	}()
+	return ln.Addr().String(), accepted
+}
+
+// nullGreeting returns the greeting of a NULL socket.
+func nullGreeting() zmtp.Greeting {
+	greeting := zmtp.NewGreeting()
+	greeting.SetVersionMajor(3)
+	greeting.SetVersionMinor(1)
+	greeting.SetMechanism("NULL")
+	return greeting
+}
+
+// TestFrames checks that fragmented messages are reassembled around
+// control frames, that pings are answered, and that a close is echoed and
+// ends the stream.
+func TestFrames(t *testing.T) {
+	addr, accepted := bindRaw(t)
+	client := dialRaw(t, addr, "ZWS2.0/NULL")
+	server := <-accepted
+
+	greeting := nullGreeting()
+	if _, err := greeting.WriteTo(server); err != nil {
+		t.Fatal(err)
+	}
+	if status := client.status(t); status != http.StatusSwitchingProtocols {
+		t.Fatalf("expected the upgrade, got status %d", status)
+	}
+	if _, err := io.ReadFull(server, make([]byte, len(greeting))); err != nil {
+		t.Fatal(err)
+	}
+
+	client.write(t, false, 0x2, []byte("\x00hel"))
+	client.write(t, true, 0x9, []byte("ping"))
+	client.write(t, true, 0x0, []byte("lo"))
+
+	frame := make([]byte, 7)
+	if _, err := io.ReadFull(server, frame); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(frame, []byte("\x00\x05hello")) {
+		t.Fatalf("expected the ZMTP frame of hello, got %q", frame)
+	}
+	if op, payload := client.read(t); op != 0xA || string(payload) != "ping" {
+		t.Fatalf("expected a pong echoing the ping, got opcode %d %q", op, payload)
+	}
+
+	var code [2]byte
+	binary.BigEndian.PutUint16(code[:], 1000)
+	client.write(t, true, 0x8, code[:])
+	if _, err := server.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Fatalf("expected io.EOF after the close, got %v", err)
+	}
+	if op, payload := client.read(t); op != 0x8 || !bytes.Equal(payload, code[:]) {
+		t.Fatalf("expected the close to be echoed, got opcode %d %q", op, payload)
+	}
+}
+
+// TestProtocolMismatch checks that an upgrade offering no subprotocol for
+// the socket's mechanism is refused, as is a server choosing one the client
+// did not offer.
+func TestProtocolMismatch(t *testing.T) {
+	addr, accepted := bindRaw(t)
+	client := dialRaw(t, addr, "ZWS2.0/CURVE")
+	server := <-accepted
+
+	greeting := nullGreeting()
+	if _, err := greeting.WriteTo(server); !errors.Is(err, ws.ErrBadRequest) {
+		t.Fatalf("expected ErrBadRequest, got %v", err)
+	}
+	if status := client.status(t); status != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", status)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		// Answer correctly, but for the wrong mechanism.
+		sum := sha1.Sum([]byte(req.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+		accepted := base64.StdEncoding.EncodeToString(sum[:])
+		fmt.Fprintf(conn, "HTTP/1.1 101 Switching Protocols\r\n"+
+			"Upgrade: websocket\r\n"+
+			"Connection: Upgrade\r\n"+
+			"Sec-WebSocket-Accept: %s\r\n"+
+			"Sec-WebSocket-Protocol: ZWS2.0/PLAIN\r\n\r\n", accepted)
+		io.Copy(io.Discard, conn)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	connectURL, _ := url.Parse("ws://" + ln.Addr().String())
+	conn, _, err := (&ws.Transport{}).Connect(ctx, connectURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := greeting.WriteTo(conn); !errors.Is(err, ws.ErrHandshakeFailed) {
+		t.Fatalf("expected ErrHandshakeFailed, got %v", err)
+	}
+}
@@ -25,7 +25,7 @@ func RegisterTransport(name string, fac TransportFactory) error {
 	}
 
 	if _, ok := registeredTransports.transports[name]; ok {
-		return fmt.Errorf("%w: %s", name)
+		return fmt.Errorf("%w: %s", ErrTransportExists, name)
 	}
 
 	registeredTransports.transports[name] = fac
@@ -29,13 +29,67 @@ type SocketDriver interface {
 	// Send a message over the socket.
 	Send([]zmtp.Message) error
 
+	// SendContext sends a message over the socket, giving up once ctx is
+	// done with an error matching types.ErrWouldBlock. A message which can
+	// be sent without waiting is sent even if ctx is already done.
+	SendContext(ctx context.Context, data []zmtp.Message) error
+
 	// Recv either a command or a message on the socket.
 	Recv() ([]zmtp.Message, error)
 
+	// RecvContext receives a message like Recv, giving up once ctx is done
+	// like SendContext.
+	RecvContext(ctx context.Context) ([]zmtp.Message, error)
+
 	// Close the socket
 	Close() error
 }
 
+// Subscriber is implemented by socket drivers which filter the messages they
+// receive by topic prefix.
+type Subscriber interface {
+	// Subscribe to messages whose first frame starts with topic.
+	Subscribe(topic []byte) error
+
+	// Unsubscribe from a topic previously passed to Subscribe.
+	Unsubscribe(topic []byte) error
+}
+
+// Joiner is implemented by socket drivers which receive the messages of the
+// groups they join.
+type Joiner interface {
+	// Join the group.
+	Join(group string) error
+
+	// Leave a group previously joined.
+	Leave(group string) error
+}
+
+// GracefulCloser is implemented by socket drivers which queue outbound
+// messages, so that closing can linger until they are sent.
+type GracefulCloser interface {
+	// CloseContext closes the socket once its queued messages are sent, the
+	// configured linger period passes or ctx is done, whichever is first.
+	CloseContext(ctx context.Context) error
+}
+
+// SendPoller is implemented by socket drivers whose Send may block, so that a
+// Poller can tell when it would not. Drivers which do not implement it are
+// always considered writable.
+type SendPoller interface {
+	// SendReady returns a channel which is closed once Send would not block.
+	// A nil channel means Send will block until the socket is used to Recv.
+	SendReady() <-chan struct{}
+}
+
+// RecvPoller is implemented by socket drivers which can tell when Recv would
+// not block without receiving, so that a Poller need not receive ahead.
+type RecvPoller interface {
+	// RecvReady returns a channel which is closed once Recv would not block.
+	// A nil channel means Recv will fail until the socket is used to Send.
+	RecvReady() <-chan struct{}
+}
+
 // SocketConstructor constructs a socket.
 type SocketConstructor func(
 	ctx context.Context,
@@ -0,0 +1,240 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/types"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// Client implements the zmq client socket, which deals messages of one
+// frame across its SERVER peers and receives from them in turn.
+type Client struct {
+	context.Context
+	Cancel context.CancelFunc
+	*gomq.Config
+	Mech              zmtp.Mechanism
+	ConnectionDrivers map[string]*socketutil.ConnectionDriver
+	BindDrivers       map[string]*socketutil.BindDriver
+	Outboxes          map[string]*socketutil.Outbox
+	EventBus          gomq.EventBus
+
+	// Balancer deals messages across the outboxes of connected peers, and
+	// of connecting peers so that messages queue while they reconnect.
+	Balancer socketutil.LoadBalancer
+
+	// Backlog counts messages accepted by Send which are yet to be written.
+	Backlog socketutil.Backlog
+
+	// Queue takes messages from the inboxes of peers in turn.
+	Queue socketutil.FairQueue[[]zmtp.Message]
+}
+
+func (c *Client) Name() string {
+	return "CLIENT"
+}
+
+func (c *Client) Connect(tp transport.Transport, url *url.URL) error {
+	if _, ok := c.Outboxes[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyConnected, url.String())
+	}
+
+	var out *socketutil.Outbox
+	driver := &socketutil.ConnectionDriver{}
+	driver.Setup(
+		c.Context,
+		c.Mech,
+		tp,
+		url,
+		c.Config,
+		c.EventBus,
+		func(ctx context.Context, s *socketutil.Peer) error {
+			return c.HandleSock(ctx, s, out)
+		},
+		c.Meta,
+		c.MetaHandler,
+	)
+	fatal, err := driver.TryConnect()
+	if err != nil && fatal {
+		return err
+	}
+	out = c.Balancer.Add(c.Config.SendHWM())
+	c.ConnectionDrivers[url.String()] = driver
+	c.Outboxes[url.String()] = out
+	go driver.Run()
+	return nil
+}
+
+func (c *Client) Disconnect(url *url.URL) error {
+	driver, ok := c.ConnectionDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverConnected, url.String())
+	}
+
+	delete(c.ConnectionDrivers, url.String())
+	err := driver.Close()
+	c.Backlog.Done(c.Balancer.Remove(c.Outboxes[url.String()]))
+	delete(c.Outboxes, url.String())
+	return err
+}
+
+func (c *Client) Bind(tp transport.Transport, url *url.URL) error {
+	if _, ok := c.BindDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyBound, url)
+	}
+
+	driver := &socketutil.BindDriver{}
+	driver.Setup(
+		c.Context,
+		tp,
+		c.Mech,
+		url,
+		c.Config,
+		func(ctx context.Context, s *socketutil.Peer) error {
+			out := c.Balancer.Add(c.Config.SendHWM())
+			err := c.HandleSock(ctx, s, out)
+			c.Backlog.Done(c.Balancer.Remove(out))
+			return err
+		},
+		c.EventBus,
+		c.Meta,
+		c.MetaHandler,
+	)
+	if err := driver.TryBind(); err != nil {
+		return err
+	}
+	c.BindDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (c *Client) Unbind(url *url.URL) error {
+	driver, ok := c.BindDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverBound, url.String())
+	}
+
+	delete(c.BindDrivers, url.String())
+	return driver.Close()
+}
+
+// HandleSock writes messages dealt to the outbox to the peer, and queues
+// the messages it sends in an inbox of its own, until either fails or ctx
+// is done.
+func (c *Client) HandleSock(ctx context.Context, peer *socketutil.Peer, out *socketutil.Outbox) error {
+	in := c.Queue.Add(c.Config.RecvHWM())
+	defer in.Close()
+
+	derived, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go func() {
+		cancel(socketutil.ReadSingleFrames(derived, peer, in, 0))
+	}()
+
+	for {
+		msg, err := out.Receive(derived)
+		if err != nil {
+			return context.Cause(derived)
+		}
+
+		err = peer.SendMessage(msg[0])
+		c.Backlog.Done(1)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) Meta() zmtp.Metadata {
+	meta := zmtp.Metadata{}
+	meta.AddProperty("Socket-Type", "CLIENT")
+	return meta
+}
+
+func (c *Client) MetaHandler(meta zmtp.Metadata) error {
+	var err error
+	meta.Properties(func(name string, value string) {
+		if name == "Socket-Type" && err == nil {
+			if value != "SERVER" {
+				err = fmt.Errorf("Expected server socket to connect, got %s", value)
+			}
+		}
+	})
+
+	return err
+}
+
+func (c *Client) Send(data []zmtp.Message) error {
+	return c.SendContext(context.Background(), data)
+}
+
+// SendContext deals the message of one frame to the next peer in turn with
+// room in its queue, failing with types.ErrWouldBlock if ctx is done before
+// one has room.
+func (c *Client) SendContext(ctx context.Context, data []zmtp.Message) error {
+	if err := socketutil.CheckSingleFrame(data); err != nil {
+		return err
+	}
+
+	c.Backlog.Add(1)
+	err := c.Balancer.Send(ctx, c.Context, data)
+	if err != nil {
+		c.Backlog.Done(1)
+	}
+	return err
+}
+
+// SendReady implements gomq.SendPoller.
+func (c *Client) SendReady() <-chan struct{} {
+	return c.Balancer.Ready()
+}
+
+func (c *Client) Recv() ([]zmtp.Message, error) {
+	return c.RecvContext(context.Background())
+}
+
+// RecvContext receives the next message from the peers in turn, failing
+// with types.ErrWouldBlock if ctx is done first.
+func (c *Client) RecvContext(ctx context.Context) ([]zmtp.Message, error) {
+	return c.Queue.Receive(ctx, c.Context)
+}
+
+// RecvReady implements gomq.RecvPoller.
+func (c *Client) RecvReady() <-chan struct{} {
+	return c.Queue.Ready()
+}
+
+// QueueDepths implements gomq.QueueReporter.
+func (c *Client) QueueDepths() (send, recv gomq.QueueDepth) {
+	return c.Balancer.Depth(), c.Queue.Depth()
+}
+
+func (c *Client) Close() error {
+	return c.CloseContext(context.Background())
+}
+
+// CloseContext implements gomq.GracefulCloser, waiting up to the linger
+// period for queued messages to be written before tearing down.
+func (c *Client) CloseContext(ctx context.Context) error {
+	discarded := c.Backlog.Linger(ctx, c.Config.Linger())
+	c.Cancel()
+	for url, conn := range c.ConnectionDrivers {
+		conn.Close()
+		delete(c.ConnectionDrivers, url)
+	}
+	for url, out := range c.Outboxes {
+		c.Balancer.Remove(out)
+		delete(c.Outboxes, url)
+	}
+	for url, bind := range c.BindDrivers {
+		bind.Close()
+		delete(c.BindDrivers, url)
+	}
+	socketutil.PostDiscarded(c.EventBus, discarded)
+	return nil
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+func init() {
+	gomq.RegisterSocketType(
+		"CLIENT",
+		func(ctx context.Context, mech zmtp.Mechanism, conf *gomq.Config, eventBus gomq.EventBus) (gomq.SocketDriver, error) {
+			derived, cancel := context.WithCancel(ctx)
+			return &Client{
+				Context:           derived,
+				Cancel:            cancel,
+				Config:            conf,
+				Mech:              mech,
+				ConnectionDrivers: map[string]*socketutil.ConnectionDriver{},
+				BindDrivers:       map[string]*socketutil.BindDriver{},
+				Outboxes:          map[string]*socketutil.Outbox{},
+				EventBus:          eventBus,
+			}, nil
+		},
+	)
+}
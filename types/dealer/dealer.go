@@ -0,0 +1,267 @@
+package dealer
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/types"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// Dealer implements the zmq dealer socket.
+type Dealer struct {
+	context.Context
+	Cancel context.CancelFunc
+	*gomq.Config
+	Mech              zmtp.Mechanism
+	ConnectionDrivers map[string]*socketutil.ConnectionDriver
+	BindDrivers       map[string]*socketutil.BindDriver
+	Outboxes          map[string]*socketutil.Outbox
+	EventBus          gomq.EventBus
+
+	// Balancer deals messages across the outboxes of connected peers, and
+	// of connecting peers so that messages queue while they reconnect.
+	Balancer socketutil.LoadBalancer
+
+	// Backlog counts messages accepted by Send which are yet to be written.
+	Backlog socketutil.Backlog
+
+	// Queue takes messages from the inboxes of peers in turn.
+	Queue socketutil.FairQueue[socketutil.PeerMessage]
+
+	lock      sync.Mutex
+	routingID []byte
+}
+
+func (d *Dealer) Name() string {
+	return "DEALER"
+}
+
+func (d *Dealer) Connect(tp transport.Transport, url *url.URL) error {
+	if _, ok := d.ConnectionDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyConnected, url)
+	}
+
+	var out *socketutil.Outbox
+	driver := &socketutil.ConnectionDriver{}
+	driver.Setup(
+		d.Context,
+		d.Mech,
+		tp,
+		url,
+		d.Config,
+		d.EventBus,
+		func(ctx context.Context, peer *socketutil.Peer) error {
+			return d.HandleSock(ctx, peer, out)
+		},
+		d.Meta,
+		d.MetaHandler,
+	)
+	fatal, err := driver.TryConnect()
+	if err != nil && fatal {
+		return err
+	}
+	out = d.Balancer.Add(d.Config.SendHWM())
+	d.ConnectionDrivers[url.String()] = driver
+	d.Outboxes[url.String()] = out
+	go driver.Run()
+	return nil
+}
+
+func (d *Dealer) Disconnect(url *url.URL) error {
+	driver, ok := d.ConnectionDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverConnected, url)
+	}
+
+	delete(d.ConnectionDrivers, url.String())
+	err := driver.Close()
+	d.Backlog.Done(d.Balancer.Remove(d.Outboxes[url.String()]))
+	delete(d.Outboxes, url.String())
+	return err
+}
+
+func (d *Dealer) Bind(tp transport.Transport, url *url.URL) error {
+	if _, ok := d.BindDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyBound, url)
+	}
+
+	driver := &socketutil.BindDriver{}
+	driver.Setup(
+		d.Context,
+		tp,
+		d.Mech,
+		url,
+		d.Config,
+		func(ctx context.Context, peer *socketutil.Peer) error {
+			out := d.Balancer.Add(d.Config.SendHWM())
+			err := d.HandleSock(ctx, peer, out)
+			d.Backlog.Done(d.Balancer.Remove(out))
+			return err
+		},
+		d.EventBus,
+		d.Meta,
+		d.MetaHandler,
+	)
+	if err := driver.TryBind(); err != nil {
+		return err
+	}
+	d.BindDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (d *Dealer) Unbind(url *url.URL) error {
+	driver, ok := d.BindDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverBound, url.String())
+	}
+
+	delete(d.BindDrivers, url.String())
+	return driver.Close()
+}
+
+// HandleSock writes messages dealt to the outbox to the peer, and reads the
+// messages it sends, until either fails or ctx is done.
+func (d *Dealer) HandleSock(ctx context.Context, peer *socketutil.Peer, out *socketutil.Outbox) error {
+	in := d.Queue.Add(d.Config.RecvHWM())
+	derived, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go func() {
+		defer in.Close()
+		cancel(socketutil.ReadMessages(derived, peer, in))
+	}()
+
+	for {
+		msg, err := out.Receive(derived)
+		if err != nil {
+			return context.Cause(derived)
+		}
+
+		err = peer.SendMessages(msg)
+		d.Backlog.Done(1)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (d *Dealer) Meta() zmtp.Metadata {
+	meta := zmtp.Metadata{}
+	meta.AddProperty("Socket-Type", "DEALER")
+	d.lock.Lock()
+	if d.routingID != nil {
+		meta.AddProperty("Identity", string(d.routingID))
+	}
+	d.lock.Unlock()
+	return meta
+}
+
+func (d *Dealer) MetaHandler(meta zmtp.Metadata) error {
+	var err error
+	meta.Properties(func(name string, value string) {
+		if name == "Socket-Type" && err == nil {
+			if value != "REP" && value != "DEALER" && value != "ROUTER" {
+				err = fmt.Errorf("Expected rep, dealer or router socket to connect, got %s", value)
+			}
+		}
+	})
+
+	return err
+}
+
+// SetOption implements gomq.OptionSetter.
+func (d *Dealer) SetOption(option string, val any) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	switch option {
+	case gomq.OptionRoutingID:
+		id, err := types.BytesOption(option, val)
+		if err != nil {
+			return err
+		}
+		d.routingID = id
+	default:
+		return gomq.ErrUnknownOption
+	}
+
+	return nil
+}
+
+// Send the message to the next peer in turn with room in its queue, waiting
+// for room if there is none.
+func (d *Dealer) Send(data []zmtp.Message) error {
+	return d.SendContext(context.Background(), data)
+}
+
+// SendContext deals the message to the next peer in turn with room in its
+// queue, failing with types.ErrWouldBlock if ctx is done before one has room.
+func (d *Dealer) SendContext(ctx context.Context, data []zmtp.Message) error {
+	d.Backlog.Add(1)
+	err := d.Balancer.Send(ctx, d.Context, data)
+	if err != nil {
+		d.Backlog.Done(1)
+	}
+	return err
+}
+
+// SendReady implements gomq.SendPoller.
+func (d *Dealer) SendReady() <-chan struct{} {
+	return d.Balancer.Ready()
+}
+
+// QueueDepths implements gomq.QueueReporter.
+func (d *Dealer) QueueDepths() (send, recv gomq.QueueDepth) {
+	return d.Balancer.Depth(), d.Queue.Depth()
+}
+
+// Recv the next message from any peer.
+func (d *Dealer) Recv() ([]zmtp.Message, error) {
+	return d.RecvContext(context.Background())
+}
+
+// RecvContext implements gomq.SocketDriver.
+func (d *Dealer) RecvContext(ctx context.Context) ([]zmtp.Message, error) {
+	in, err := d.Queue.Receive(ctx, d.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return in.Message, nil
+}
+
+// RecvReady implements gomq.RecvPoller.
+func (d *Dealer) RecvReady() <-chan struct{} {
+	return d.Queue.Ready()
+}
+
+func (d *Dealer) Close() error {
+	return d.CloseContext(context.Background())
+}
+
+// CloseContext implements gomq.GracefulCloser, waiting up to the linger
+// period for queued messages to be written before tearing down.
+func (d *Dealer) CloseContext(ctx context.Context) error {
+	discarded := d.Backlog.Linger(ctx, d.Config.Linger())
+	d.Cancel()
+	for url, conn := range d.ConnectionDrivers {
+		conn.Close()
+		delete(d.ConnectionDrivers, url)
+	}
+	for url, out := range d.Outboxes {
+		d.Balancer.Remove(out)
+		delete(d.Outboxes, url)
+	}
+	for url, bind := range d.BindDrivers {
+		bind.Close()
+		delete(d.BindDrivers, url)
+	}
+	socketutil.PostDiscarded(d.EventBus, discarded)
+	return nil
+}
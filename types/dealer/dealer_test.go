@@ -0,0 +1,114 @@
+package dealer_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	_ "github.com/workspace-9/gomq/transport/inproc"
+	"github.com/workspace-9/gomq/types"
+	_ "github.com/workspace-9/gomq/types/dealer"
+	_ "github.com/workspace-9/gomq/zmtp/null"
+)
+
+// recvQueue returns the depth and capacity the socket reports for its
+// receive queues.
+func recvQueue(sock *gomq.Socket) (depth, capacity float64) {
+	sock.Collect(func(sample gomq.Sample) {
+		for _, label := range sample.Labels {
+			if label.Name != "direction" || label.Value != "recv" {
+				continue
+			}
+			switch sample.Name {
+			case "gomq_queue_messages":
+				depth = sample.Value
+			case "gomq_queue_capacity":
+				capacity = sample.Value
+			}
+		}
+	})
+	return depth, capacity
+}
+
+// TestSendHWM checks that sends queue for a peer which is yet to connect
+// until SNDHWM is reached.
+func TestSendHWM(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	dealer, err := ctx.NewSocket("DEALER", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dealer.Close()
+	if err := dealer.SetOption(gomq.OptionSendHWM, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := dealer.Connect("inproc://nowhere"); err != nil {
+		t.Fatal(err)
+	}
+
+	for idx := 0; idx < 2; idx++ {
+		if err := dealer.Send([][]byte{[]byte("hello")}, gomq.DontWait); err != nil {
+			t.Fatalf("send %d: %v", idx, err)
+		}
+	}
+	if err := dealer.Send([][]byte{[]byte("hello")}, gomq.DontWait); !errors.Is(err, types.ErrWouldBlock) {
+		t.Fatalf("expected ErrWouldBlock, got %v", err)
+	}
+}
+
+// TestRecvHWM checks that RCVHWM bounds the messages queued from a peer,
+// even when set after the socket is made.
+func TestRecvHWM(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	sender, err := ctx.NewSocket("DEALER", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+	if err := sender.Bind("inproc://recvhwm"); err != nil {
+		t.Fatal(err)
+	}
+
+	receiver, err := ctx.NewSocket("DEALER", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Close()
+	if err := receiver.SetOption(gomq.OptionRecvHWM, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := receiver.Connect("inproc://recvhwm"); err != nil {
+		t.Fatal(err)
+	}
+
+	for idx := 0; idx < 5; idx++ {
+		if err := sender.Send([][]byte{[]byte("hello")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		depth, capacity := recvQueue(receiver)
+		if depth == 3 && capacity == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 3 of 3 messages queued, got %v of %v", depth, capacity)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	receiver.SetOption(gomq.OptionRecvTimeout, time.Second)
+	for idx := 0; idx < 5; idx++ {
+		if _, err := receiver.Recv(); err != nil {
+			t.Fatalf("recv %d: %v", idx, err)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package dish
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/types"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// Dish implements the zmq dish socket, receiving the messages RADIO peers
+// send to the groups it has joined.
+type Dish struct {
+	context.Context
+	Cancel context.CancelFunc
+	*gomq.Config
+	Mech              zmtp.Mechanism
+	ConnectionDrivers map[string]*socketutil.ConnectionDriver
+	BindDrivers       map[string]*socketutil.BindDriver
+	EventBus          gomq.EventBus
+	Groups            socketutil.Groups
+
+	// Receivers are the bound endpoints of datagram transports.
+	Receivers map[string]*socketutil.DatagramReceiver
+
+	// Inboxes of connecting peers and datagram endpoints, which persist
+	// across reconnects.
+	Inboxes map[string]*socketutil.Inbox[[]zmtp.Message]
+
+	// Queue takes messages from the inboxes in turn.
+	Queue socketutil.FairQueue[[]zmtp.Message]
+}
+
+func (d *Dish) Name() string {
+	return "DISH"
+}
+
+func (d *Dish) Connect(tp transport.Transport, url *url.URL) error {
+	if _, ok := tp.(transport.DatagramTransport); ok {
+		return fmt.Errorf("%w: DISH binds to receive over %s", types.ErrOperationNotPermitted, tp.Name())
+	}
+
+	if _, ok := d.Inboxes[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyConnected, url)
+	}
+
+	var in *socketutil.Inbox[[]zmtp.Message]
+	driver := &socketutil.ConnectionDriver{}
+	driver.Setup(
+		d.Context,
+		d.Mech,
+		tp,
+		url,
+		d.Config,
+		d.EventBus,
+		func(ctx context.Context, peer *socketutil.Peer) error {
+			return d.HandleSock(ctx, peer, in)
+		},
+		d.Meta,
+		d.MetaHandler,
+	)
+	fatal, err := driver.TryConnect()
+	if err != nil && fatal {
+		return err
+	}
+	in = d.Queue.Add(d.Config.RecvHWM())
+	d.ConnectionDrivers[url.String()] = driver
+	d.Inboxes[url.String()] = in
+	go driver.Run()
+	return nil
+}
+
+func (d *Dish) Disconnect(url *url.URL) error {
+	driver, ok := d.ConnectionDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverConnected, url)
+	}
+
+	delete(d.ConnectionDrivers, url.String())
+	err := driver.Close()
+	d.Inboxes[url.String()].Close()
+	delete(d.Inboxes, url.String())
+	return err
+}
+
+func (d *Dish) Bind(tp transport.Transport, url *url.URL) error {
+	if dtp, ok := tp.(transport.DatagramTransport); ok {
+		return d.bindDatagrams(dtp, url)
+	}
+
+	if _, ok := d.BindDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyBound, url)
+	}
+
+	driver := &socketutil.BindDriver{}
+	driver.Setup(
+		d.Context,
+		tp,
+		d.Mech,
+		url,
+		d.Config,
+		func(ctx context.Context, peer *socketutil.Peer) error {
+			in := d.Queue.Add(d.Config.RecvHWM())
+			defer in.Close()
+			return d.HandleSock(ctx, peer, in)
+		},
+		d.EventBus,
+		d.Meta,
+		d.MetaHandler,
+	)
+	if err := driver.TryBind(); err != nil {
+		return err
+	}
+	d.BindDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+// bindDatagrams receives the datagrams sent to url, queueing those of
+// joined groups.
+func (d *Dish) bindDatagrams(tp transport.DatagramTransport, url *url.URL) error {
+	if _, ok := d.Receivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyBound, url)
+	}
+
+	in := d.Queue.Add(d.Config.RecvHWM())
+	receiver, err := socketutil.ListenDatagrams(
+		d.Context,
+		tp,
+		url,
+		d.EventBus,
+		func(ctx context.Context, msg zmtp.Message) error {
+			if !d.Groups.Joined(msg.Group) {
+				return nil
+			}
+			return in.Deliver(ctx, []zmtp.Message{msg})
+		},
+	)
+	if err != nil {
+		in.Close()
+		return err
+	}
+
+	d.Receivers[url.String()] = receiver
+	d.Inboxes[url.String()] = in
+	return nil
+}
+
+func (d *Dish) Unbind(url *url.URL) error {
+	if receiver, ok := d.Receivers[url.String()]; ok {
+		delete(d.Receivers, url.String())
+		err := receiver.Close()
+		d.Inboxes[url.String()].Close()
+		delete(d.Inboxes, url.String())
+		return err
+	}
+
+	driver, ok := d.BindDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverBound, url.String())
+	}
+
+	delete(d.BindDrivers, url.String())
+	return driver.Close()
+}
+
+// HandleSock tells the peer every joined group, then queues each message it
+// sends to a joined group in its inbox. RADIO peers send the group as a
+// frame ahead of the body.
+func (d *Dish) HandleSock(ctx context.Context, peer *socketutil.Peer, in *socketutil.Inbox[[]zmtp.Message]) error {
+	if err := d.Groups.Attach(peer); err != nil {
+		return err
+	}
+	defer d.Groups.Detach(peer)
+
+	built := make([]zmtp.Message, 0, 2)
+	for {
+		next, err := peer.Read()
+		if err != nil {
+			return err
+		}
+
+		if !next.IsMessage {
+			continue
+		}
+
+		built = append(built, *next.Message)
+		if next.Message.More {
+			continue
+		}
+
+		group := string(built[0].Body)
+		if len(built) == 2 && d.Groups.Joined(group) {
+			msg := []zmtp.Message{{Group: group, Body: built[1].Body}}
+			if err := in.Deliver(ctx, msg); err != nil {
+				return err
+			}
+		}
+		built = built[:0]
+	}
+}
+
+func (d *Dish) Meta() zmtp.Metadata {
+	meta := zmtp.Metadata{}
+	meta.AddProperty("Socket-Type", "DISH")
+	return meta
+}
+
+func (d *Dish) MetaHandler(meta zmtp.Metadata) error {
+	var err error
+	meta.Properties(func(name string, value string) {
+		if name == "Socket-Type" && err == nil {
+			if value != "RADIO" {
+				err = fmt.Errorf("Expected radio socket to connect, got %s", value)
+			}
+		}
+	})
+
+	return err
+}
+
+// Join the group, receiving the messages sent to it.
+func (d *Dish) Join(group string) error {
+	return d.Groups.Join(group)
+}
+
+// Leave a group previously joined.
+func (d *Dish) Leave(group string) error {
+	return d.Groups.Leave(group)
+}
+
+func (d *Dish) Send([]zmtp.Message) error {
+	return types.ErrOperationNotPermitted
+}
+
+func (d *Dish) SendContext(context.Context, []zmtp.Message) error {
+	return types.ErrOperationNotPermitted
+}
+
+func (d *Dish) Recv() ([]zmtp.Message, error) {
+	return d.RecvContext(context.Background())
+}
+
+// RecvContext receives the next message from the peers in turn, failing
+// with types.ErrWouldBlock if ctx is done first.
+func (d *Dish) RecvContext(ctx context.Context) ([]zmtp.Message, error) {
+	return d.Queue.Receive(ctx, d.Context)
+}
+
+// RecvReady implements gomq.RecvPoller.
+func (d *Dish) RecvReady() <-chan struct{} {
+	return d.Queue.Ready()
+}
+
+// QueueDepths implements gomq.QueueReporter.
+func (d *Dish) QueueDepths() (send, recv gomq.QueueDepth) {
+	return gomq.QueueDepth{}, d.Queue.Depth()
+}
+
+func (d *Dish) Close() error {
+	d.Cancel()
+	for url, conn := range d.ConnectionDrivers {
+		conn.Close()
+		delete(d.ConnectionDrivers, url)
+	}
+	for url, receiver := range d.Receivers {
+		receiver.Close()
+		delete(d.Receivers, url)
+	}
+	for url, in := range d.Inboxes {
+		in.Close()
+		delete(d.Inboxes, url)
+	}
+	for url, bind := range d.BindDrivers {
+		bind.Close()
+		delete(d.BindDrivers, url)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package dish_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	_ "github.com/workspace-9/gomq/transport/inproc"
+	_ "github.com/workspace-9/gomq/transport/tcp"
+	_ "github.com/workspace-9/gomq/transport/udp"
+	_ "github.com/workspace-9/gomq/types/dish"
+	_ "github.com/workspace-9/gomq/types/radio"
+	_ "github.com/workspace-9/gomq/zmtp/null"
+)
+
+// newSocket makes a socket closed at the end of the test, whose receives
+// time out quickly so lost messages can be sent again.
+func newSocket(t *testing.T, ctx *gomq.Context, typ string) *gomq.Socket {
+	t.Helper()
+	sock, err := ctx.NewSocket(typ, "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sock.Close() })
+	sock.SetOption(gomq.OptionRecvTimeout, 100*time.Millisecond)
+	sock.SetOption(gomq.OptionReconnectIvl, 10*time.Millisecond)
+	return sock
+}
+
+// bind the socket, returning the address it listens on.
+func bind(t *testing.T, sock *gomq.Socket, addr string) string {
+	t.Helper()
+	events := sock.Monitor(gomq.Events(gomq.EventTypeListening))
+	if err := sock.Bind(addr); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-events:
+		return ev.LocalAddr
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the socket to listen")
+		return ""
+	}
+}
+
+// await sends numbered messages to the group until the dish receives one,
+// failing if it receives a message of another group first. It returns how
+// many messages were sent, every one of which the dish received if the
+// radio had its JOIN, since messages are received in order.
+func await(t *testing.T, radio, dish *gomq.Socket, group string) int {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for idx := 0; time.Now().Before(deadline); idx++ {
+		want := fmt.Sprintf("%s-%d", group, idx)
+		if err := radio.SendGroup(group, []byte(want)); err != nil {
+			t.Fatal(err)
+		}
+
+		for {
+			msg, err := dish.RecvMsg()
+			if err != nil {
+				break
+			}
+			if msg.Group != group {
+				t.Fatalf("expected only group %q, got %q", group, msg.Group)
+			}
+			if string(msg.Body) == want {
+				return idx + 1
+			}
+		}
+	}
+
+	t.Fatalf("expected the dish to receive group %q", group)
+	return 0
+}
+
+// sent totals the messages the socket has written.
+func sent(sock *gomq.Socket) (total float64) {
+	sock.Collect(func(sample gomq.Sample) {
+		if sample.Name == "gomq_messages_sent_total" {
+			total += sample.Value
+		}
+	})
+	return total
+}
+
+// TestUDPUnicast checks that a DISH bound to a unicast udp address receives
+// only the groups it joined.
+func TestUDPUnicast(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	dish := newSocket(t, ctx, "DISH")
+	if err := dish.Join("a"); err != nil {
+		t.Fatal(err)
+	}
+	addr := bind(t, dish, "udp://127.0.0.1:0")
+
+	radio := newSocket(t, ctx, "RADIO")
+	if err := radio.Connect(addr); err != nil {
+		t.Fatal(err)
+	}
+	for idx := 0; idx < 3; idx++ {
+		if err := radio.SendGroup("b", []byte("unwanted")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	await(t, radio, dish, "a")
+}
+
+// TestUDPMulticast checks that a DISH bound to a multicast group receives
+// what a RADIO sends to it, if the host routes multicast.
+func TestUDPMulticast(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	dish := newSocket(t, ctx, "DISH")
+	if err := dish.Join("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dish.Bind("udp://239.255.77.77:47777"); err != nil {
+		t.Skipf("multicast unavailable: %v", err)
+	}
+	radio := newSocket(t, ctx, "RADIO")
+	if err := radio.Connect("udp://239.255.77.77:47777"); err != nil {
+		t.Skipf("multicast unavailable: %v", err)
+	}
+
+	for idx := 0; idx < 50; idx++ {
+		if err := radio.SendGroup("a", []byte("hello")); err != nil {
+			t.Fatal(err)
+		}
+		msg, err := dish.RecvMsg()
+		if err != nil {
+			continue
+		}
+		if msg.Group != "a" || string(msg.Body) != "hello" {
+			t.Fatalf("expected hello to a, got %q to %q", msg.Body, msg.Group)
+		}
+		return
+	}
+	t.Skip("multicast datagrams are not looped back on this host")
+}
+
+// TestJoinLeave checks over connected transports that the RADIO sends a
+// DISH only the groups it joined, and that the DISH joins them again with
+// a RADIO it reconnects to.
+func TestJoinLeave(t *testing.T) {
+	for _, addr := range []string{"tcp://127.0.0.1:0", "inproc://join-leave"} {
+		t.Run(addr, func(t *testing.T) {
+			ctx := gomq.NewContext(context.Background())
+			ctx.SetEventBus(nil)
+
+			radio := newSocket(t, ctx, "RADIO")
+			addr := bind(t, radio, addr)
+			dish := newSocket(t, ctx, "DISH")
+			if err := dish.Join("a"); err != nil {
+				t.Fatal(err)
+			}
+			if err := dish.Connect(addr); err != nil {
+				t.Fatal(err)
+			}
+			await(t, radio, dish, "a")
+
+			// Messages to groups the dish has not joined are never sent.
+			before := sent(radio)
+			for idx := 0; idx < 3; idx++ {
+				if err := radio.SendGroup("b", []byte("unwanted")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want := before + float64(await(t, radio, dish, "a"))
+			if got := sent(radio); got != want {
+				t.Fatalf("expected %v messages sent, got %v", want, got)
+			}
+
+			if err := dish.Leave("a"); err != nil {
+				t.Fatal(err)
+			}
+			if err := dish.Join("b"); err != nil {
+				t.Fatal(err)
+			}
+			await(t, radio, dish, "b")
+			before = sent(radio)
+			for idx := 0; idx < 3; idx++ {
+				if err := radio.SendGroup("a", []byte("unwanted")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want = before + float64(await(t, radio, dish, "b"))
+			if got := sent(radio); got != want {
+				t.Fatalf("expected %v messages sent, got %v", want, got)
+			}
+
+			radio.Close()
+			radio = newSocket(t, ctx, "RADIO")
+			bind(t, radio, addr)
+			await(t, radio, dish, "b")
+		})
+	}
+}
+
+// TestUDPSendErrors checks that datagrams the RADIO fails to send are
+// counted, here because the host refuses them.
+func TestUDPSendErrors(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := "udp://" + conn.LocalAddr().String()
+	conn.Close()
+
+	radio := newSocket(t, ctx, "RADIO")
+	if err := radio.Connect(addr); err != nil {
+		t.Fatal(err)
+	}
+	for idx := 0; idx < 100; idx++ {
+		if err := radio.SendGroup("a", []byte("refused")); err != nil {
+			t.Fatal(err)
+		}
+
+		var failed float64
+		radio.Collect(func(sample gomq.Sample) {
+			if sample.Name == "gomq_send_errors_total" {
+				failed += sample.Value
+			}
+		})
+		if failed > 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("expected refused datagrams to be counted")
+}
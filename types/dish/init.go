@@ -0,0 +1,29 @@
+package dish
+
+import (
+	"context"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+func init() {
+	gomq.RegisterSocketType(
+		"DISH",
+		func(ctx context.Context, mech zmtp.Mechanism, conf *gomq.Config, eventBus gomq.EventBus) (gomq.SocketDriver, error) {
+			derived, cancel := context.WithCancel(ctx)
+			return &Dish{
+				Context:           derived,
+				Cancel:            cancel,
+				Config:            conf,
+				Mech:              mech,
+				ConnectionDrivers: map[string]*socketutil.ConnectionDriver{},
+				BindDrivers:       map[string]*socketutil.BindDriver{},
+				Receivers:         map[string]*socketutil.DatagramReceiver{},
+				Inboxes:           map[string]*socketutil.Inbox[[]zmtp.Message]{},
+				EventBus:          eventBus,
+			}, nil
+		},
+	)
+}
@@ -1,5 +1,7 @@
 package types
 
+import "fmt"
+
 type operationNotPermitted struct{}
 
 func (operationNotPermitted) Error() string {
@@ -47,3 +49,65 @@ func (neverBound) Error() string {
 }
 
 var ErrNeverBound neverBound
+
+type invalidState struct{}
+
+func (invalidState) Error() string {
+	return "Operation not valid in current socket state"
+}
+
+var ErrInvalidState invalidState
+
+type hostUnreachable struct{}
+
+func (hostUnreachable) Error() string {
+	return "Host unreachable"
+}
+
+var ErrHostUnreachable hostUnreachable
+
+type wouldBlock struct{}
+
+func (wouldBlock) Error() string {
+	return "Operation would block"
+}
+
+var ErrWouldBlock wouldBlock
+
+// WouldBlock wraps the reason a blocking operation gave up, such as a
+// context deadline, so that it also matches ErrWouldBlock.
+func WouldBlock(reason error) error {
+	return fmt.Errorf("%w: %w", ErrWouldBlock, reason)
+}
+
+type multipartUnsupported struct{}
+
+func (multipartUnsupported) Error() string {
+	return "Socket type does not support multipart messages"
+}
+
+var ErrMultipartUnsupported multipartUnsupported
+
+type groupTooLong struct{}
+
+func (groupTooLong) Error() string {
+	return "Group too long"
+}
+
+var ErrGroupTooLong groupTooLong
+
+type alreadyJoined struct{}
+
+func (alreadyJoined) Error() string {
+	return "Group already joined"
+}
+
+var ErrAlreadyJoined alreadyJoined
+
+type notJoined struct{}
+
+func (notJoined) Error() string {
+	return "Group not joined"
+}
+
+var ErrNotJoined notJoined
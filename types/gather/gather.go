@@ -0,0 +1,183 @@
+package gather
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/types"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// Gather implements the zmq gather socket, which receives messages of one
+// frame from its SCATTER peers in turn like PULL. Recv may be called from
+// many goroutines at once.
+type Gather struct {
+	context.Context
+	Cancel context.CancelFunc
+	*gomq.Config
+	Mech              zmtp.Mechanism
+	ConnectionDrivers map[string]*socketutil.ConnectionDriver
+	BindDrivers       map[string]*socketutil.BindDriver
+	Inboxes           map[string]*socketutil.Inbox[[]zmtp.Message]
+	EventBus          gomq.EventBus
+
+	// Queue takes messages from the inboxes of peers in turn. Inboxes of
+	// connecting peers persist across reconnects.
+	Queue socketutil.FairQueue[[]zmtp.Message]
+}
+
+func (g *Gather) Name() string {
+	return "GATHER"
+}
+
+func (g *Gather) Connect(tp transport.Transport, url *url.URL) error {
+	if _, ok := g.Inboxes[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyConnected, url)
+	}
+
+	var in *socketutil.Inbox[[]zmtp.Message]
+	driver := &socketutil.ConnectionDriver{}
+	driver.Setup(
+		g.Context,
+		g.Mech,
+		tp,
+		url,
+		g.Config,
+		g.EventBus,
+		func(ctx context.Context, peer *socketutil.Peer) error {
+			return socketutil.ReadSingleFrames(ctx, peer, in, 0)
+		},
+		g.Meta,
+		g.MetaHandler,
+	)
+	fatal, err := driver.TryConnect()
+	if err != nil && fatal {
+		return err
+	}
+	in = g.Queue.Add(g.Config.RecvHWM())
+	g.ConnectionDrivers[url.String()] = driver
+	g.Inboxes[url.String()] = in
+	go driver.Run()
+	return nil
+}
+
+func (g *Gather) Disconnect(url *url.URL) error {
+	driver, ok := g.ConnectionDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverConnected, url)
+	}
+
+	delete(g.ConnectionDrivers, url.String())
+	err := driver.Close()
+	g.Inboxes[url.String()].Close()
+	delete(g.Inboxes, url.String())
+	return err
+}
+
+func (g *Gather) Bind(tp transport.Transport, url *url.URL) error {
+	if _, ok := g.BindDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyBound, url)
+	}
+
+	driver := &socketutil.BindDriver{}
+	driver.Setup(
+		g.Context,
+		tp,
+		g.Mech,
+		url,
+		g.Config,
+		func(ctx context.Context, peer *socketutil.Peer) error {
+			in := g.Queue.Add(g.Config.RecvHWM())
+			defer in.Close()
+			return socketutil.ReadSingleFrames(ctx, peer, in, 0)
+		},
+		g.EventBus,
+		g.Meta,
+		g.MetaHandler,
+	)
+	if err := driver.TryBind(); err != nil {
+		return err
+	}
+	g.BindDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (g *Gather) Unbind(url *url.URL) error {
+	driver, ok := g.BindDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverBound, url.String())
+	}
+
+	delete(g.BindDrivers, url.String())
+	err := driver.Close()
+	return err
+}
+
+func (g *Gather) Meta() zmtp.Metadata {
+	meta := zmtp.Metadata{}
+	meta.AddProperty("Socket-Type", "GATHER")
+	return meta
+}
+
+func (g *Gather) MetaHandler(meta zmtp.Metadata) error {
+	var err error
+	meta.Properties(func(name string, value string) {
+		if name == "Socket-Type" && err == nil {
+			if value != "SCATTER" {
+				err = fmt.Errorf("Expected scatter socket to connect, got %s", value)
+			}
+		}
+	})
+
+	return err
+}
+
+func (g *Gather) Send([]zmtp.Message) error {
+	return types.ErrOperationNotPermitted
+}
+
+func (g *Gather) SendContext(context.Context, []zmtp.Message) error {
+	return types.ErrOperationNotPermitted
+}
+
+func (g *Gather) Recv() ([]zmtp.Message, error) {
+	return g.RecvContext(context.Background())
+}
+
+// RecvContext receives the next message from the peers in turn, failing
+// with types.ErrWouldBlock if ctx is done first.
+func (g *Gather) RecvContext(ctx context.Context) ([]zmtp.Message, error) {
+	return g.Queue.Receive(ctx, g.Context)
+}
+
+// RecvReady implements gomq.RecvPoller.
+func (g *Gather) RecvReady() <-chan struct{} {
+	return g.Queue.Ready()
+}
+
+// QueueDepths implements gomq.QueueReporter.
+func (g *Gather) QueueDepths() (send, recv gomq.QueueDepth) {
+	return gomq.QueueDepth{}, g.Queue.Depth()
+}
+
+func (g *Gather) Close() error {
+	g.Cancel()
+	for url, conn := range g.ConnectionDrivers {
+		conn.Close()
+		delete(g.ConnectionDrivers, url)
+	}
+	for url, in := range g.Inboxes {
+		in.Close()
+		delete(g.Inboxes, url)
+	}
+	for url, bind := range g.BindDrivers {
+		bind.Close()
+		delete(g.BindDrivers, url)
+	}
+	return nil
+}
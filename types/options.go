@@ -0,0 +1,28 @@
+package types
+
+import "fmt"
+
+// BoolOption returns the value of an option which must be a bool.
+func BoolOption(option string, val any) (bool, error) {
+	on, ok := val.(bool)
+	if !ok {
+		return false, fmt.Errorf("Value for option %s must be bool, got %T", option, val)
+	}
+
+	return on, nil
+}
+
+// BytesOption returns the value of an option which may be given as a string
+// or []byte.
+func BytesOption(option string, val any) ([]byte, error) {
+	switch data := val.(type) {
+	case []byte:
+		return data, nil
+	case string:
+		return []byte(data), nil
+	case nil:
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("Value for option %s must be string or []byte, got %T", option, val)
+}
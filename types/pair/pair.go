@@ -0,0 +1,253 @@
+package pair
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/types"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// Pair implements the zmq pair socket.
+type Pair struct {
+	context.Context
+	Cancel context.CancelFunc
+	*gomq.Config
+	Mech              zmtp.Mechanism
+	ConnectionDrivers map[string]*socketutil.ConnectionDriver
+	BindDrivers       map[string]*socketutil.BindDriver
+	EventBus          gomq.EventBus
+
+	// Queue takes messages from the inboxes of peers in turn.
+	Queue socketutil.FairQueue[socketutil.PeerMessage]
+
+	// Balancer holds the outbox of the connected peer, if any, so that Send
+	// can wait for one.
+	Balancer socketutil.LoadBalancer
+
+	// Backlog counts messages accepted by Send which are yet to be written.
+	Backlog socketutil.Backlog
+
+	lock   sync.Mutex
+	paired bool
+}
+
+func (p *Pair) Name() string {
+	return "PAIR"
+}
+
+func (p *Pair) Connect(tp transport.Transport, url *url.URL) error {
+	if _, ok := p.ConnectionDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyConnected, url)
+	}
+
+	driver := &socketutil.ConnectionDriver{}
+	driver.Setup(
+		p.Context,
+		p.Mech,
+		tp,
+		url,
+		p.Config,
+		p.EventBus,
+		p.HandleSock,
+		p.Meta,
+		p.MetaHandler,
+	)
+	fatal, err := driver.TryConnect()
+	if err != nil && fatal {
+		return err
+	}
+	p.ConnectionDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (p *Pair) Disconnect(url *url.URL) error {
+	driver, ok := p.ConnectionDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverConnected, url)
+	}
+
+	delete(p.ConnectionDrivers, url.String())
+	return driver.Close()
+}
+
+func (p *Pair) Bind(tp transport.Transport, url *url.URL) error {
+	if _, ok := p.BindDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyBound, url)
+	}
+
+	driver := &socketutil.BindDriver{}
+	driver.Setup(
+		p.Context,
+		tp,
+		p.Mech,
+		url,
+		p.Config,
+		p.HandleSock,
+		p.EventBus,
+		p.Meta,
+		p.MetaHandler,
+	)
+	if err := driver.TryBind(); err != nil {
+		return err
+	}
+	p.BindDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (p *Pair) Unbind(url *url.URL) error {
+	driver, ok := p.BindDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverBound, url.String())
+	}
+
+	delete(p.BindDrivers, url.String())
+	return driver.Close()
+}
+
+// HandleSock claims the pair for the peer and exchanges messages with it,
+// freeing the pair for another peer once it disconnects. The pair is only
+// claimed here, once the connection is set up, so that a connection which
+// fails first never holds it.
+func (p *Pair) HandleSock(ctx context.Context, peer *socketutil.Peer) error {
+	p.lock.Lock()
+	if p.paired {
+		p.lock.Unlock()
+		return fmt.Errorf("%w: pair socket already has a peer", types.ErrAlreadyConnected)
+	}
+	p.paired = true
+	p.lock.Unlock()
+	defer func() {
+		p.lock.Lock()
+		p.paired = false
+		p.lock.Unlock()
+	}()
+
+	out := p.Balancer.Add(p.Config.SendHWM())
+	defer func() {
+		p.Backlog.Done(p.Balancer.Remove(out))
+	}()
+
+	in := p.Queue.Add(p.Config.RecvHWM())
+	derived, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go func() {
+		defer in.Close()
+		cancel(socketutil.ReadMessages(derived, peer, in))
+	}()
+
+	for {
+		msg, err := out.Receive(derived)
+		if err != nil {
+			return context.Cause(derived)
+		}
+
+		err = peer.SendMessages(msg)
+		p.Backlog.Done(1)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p *Pair) Meta() zmtp.Metadata {
+	meta := zmtp.Metadata{}
+	meta.AddProperty("Socket-Type", "PAIR")
+	return meta
+}
+
+// MetaHandler ensures the peer is a pair socket, rejecting it early if
+// another peer is already connected.
+func (p *Pair) MetaHandler(meta zmtp.Metadata) error {
+	var err error
+	meta.Properties(func(name string, value string) {
+		if name == "Socket-Type" && err == nil {
+			if value != "PAIR" {
+				err = fmt.Errorf("Expected pair socket to connect, got %s", value)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.paired {
+		return fmt.Errorf("%w: pair socket already has a peer", types.ErrAlreadyConnected)
+	}
+	return nil
+}
+
+// Send the message to the peer, waiting for one to connect if there is none.
+func (p *Pair) Send(data []zmtp.Message) error {
+	return p.SendContext(context.Background(), data)
+}
+
+// SendContext queues the message for the peer, failing with
+// types.ErrWouldBlock if ctx is done before there is one with room in its
+// queue.
+func (p *Pair) SendContext(ctx context.Context, data []zmtp.Message) error {
+	p.Backlog.Add(1)
+	err := p.Balancer.Send(ctx, p.Context, data)
+	if err != nil {
+		p.Backlog.Done(1)
+	}
+	return err
+}
+
+// SendReady implements gomq.SendPoller.
+func (p *Pair) SendReady() <-chan struct{} {
+	return p.Balancer.Ready()
+}
+
+// Recv the next message from the peer.
+func (p *Pair) Recv() ([]zmtp.Message, error) {
+	return p.RecvContext(context.Background())
+}
+
+// RecvContext implements gomq.SocketDriver.
+func (p *Pair) RecvContext(ctx context.Context) ([]zmtp.Message, error) {
+	in, err := p.Queue.Receive(ctx, p.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return in.Message, nil
+}
+
+// RecvReady implements gomq.RecvPoller.
+func (p *Pair) RecvReady() <-chan struct{} {
+	return p.Queue.Ready()
+}
+
+// QueueDepths implements gomq.QueueReporter.
+func (p *Pair) QueueDepths() (send, recv gomq.QueueDepth) {
+	return p.Balancer.Depth(), p.Queue.Depth()
+}
+
+func (p *Pair) Close() error {
+	return p.CloseContext(context.Background())
+}
+
+// CloseContext implements gomq.GracefulCloser, waiting up to the linger
+// period for queued messages to be written before tearing down.
+func (p *Pair) CloseContext(ctx context.Context) error {
+	discarded := p.Backlog.Linger(ctx, p.Config.Linger())
+	p.Cancel()
+	for _, conn := range p.ConnectionDrivers {
+		conn.Close()
+	}
+	for _, bind := range p.BindDrivers {
+		bind.Close()
+	}
+	socketutil.PostDiscarded(p.EventBus, discarded)
+	return nil
+}
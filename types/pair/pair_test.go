@@ -0,0 +1,158 @@
+package pair_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	_ "github.com/workspace-9/gomq/transport/inproc"
+	"github.com/workspace-9/gomq/types"
+	_ "github.com/workspace-9/gomq/types/pair"
+	_ "github.com/workspace-9/gomq/zmtp/null"
+)
+
+// newSocket makes a socket closed at the end of the test, whose receives
+// time out.
+func newSocket(t *testing.T, ctx *gomq.Context, typ string) *gomq.Socket {
+	t.Helper()
+	sock, err := ctx.NewSocket(typ, "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sock.Close() })
+	sock.SetOption(gomq.OptionRecvTimeout, 5*time.Second)
+	return sock
+}
+
+// TestSendHWM checks that sends fail rather than wait when asked not to,
+// both before there is a peer and once the peer's queue is full, and that
+// nothing queued is lost.
+func TestSendHWM(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	sender := newSocket(t, ctx, "PAIR")
+	sender.SetOption(gomq.OptionSendHWM, 2)
+	if err := sender.Bind("inproc://sendhwm"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.Send([][]byte{[]byte("hello")}, gomq.DontWait); !errors.Is(err, types.ErrWouldBlock) {
+		t.Fatalf("expected ErrWouldBlock without a peer, got %v", err)
+	}
+
+	receiver := newSocket(t, ctx, "PAIR")
+	receiver.SetOption(gomq.OptionRecvHWM, 1)
+	if err := receiver.Connect("inproc://sendhwm"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.Send([][]byte{[]byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Large messages fill the connection's buffer quickly.
+	body := make([]byte, 16*1024)
+	sent := 1
+	for ; sent < 1000; sent++ {
+		err := sender.Send([][]byte{body}, gomq.DontWait)
+		if errors.Is(err, types.ErrWouldBlock) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sent == 1000 {
+		t.Fatal("expected sends to fail once the queue was full")
+	}
+
+	for idx := 0; idx < sent; idx++ {
+		if _, err := receiver.Recv(); err != nil {
+			t.Fatalf("recv %d of %d: %v", idx, sent, err)
+		}
+	}
+}
+
+// TestOnePeer checks that a second peer is refused while the first is
+// connected, and is paired once the first leaves.
+func TestOnePeer(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	bound := newSocket(t, ctx, "PAIR")
+	if err := bound.Bind("inproc://onepeer"); err != nil {
+		t.Fatal(err)
+	}
+	first := newSocket(t, ctx, "PAIR")
+	if err := first.Connect("inproc://onepeer"); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Send([][]byte{[]byte("first")}); err != nil {
+		t.Fatal(err)
+	}
+	if msg, err := bound.Recv(); err != nil || string(msg[0]) != "first" {
+		t.Fatalf("expected first, got %q (%v)", msg, err)
+	}
+
+	second := newSocket(t, ctx, "PAIR")
+	second.SetOption(gomq.OptionReconnectIvl, 10*time.Millisecond)
+	if err := second.Connect("inproc://onepeer"); err != nil {
+		t.Fatal(err)
+	}
+	// The second peer's own end is set up before the bound end refuses it,
+	// so what it sends meanwhile is lost.
+	second.Send([][]byte{[]byte("second")}, gomq.DontWait)
+	bound.SetOption(gomq.OptionRecvTimeout, 100*time.Millisecond)
+	if msg, err := bound.Recv(); !errors.Is(err, types.ErrWouldBlock) {
+		t.Fatalf("expected the second peer to be refused, got %q (%v)", msg, err)
+	}
+
+	first.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		second.Send([][]byte{[]byte("second")}, gomq.DontWait)
+		msg, err := bound.Recv()
+		if err == nil && string(msg[0]) == "second" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the second peer once the first left, got %q (%v)", msg, err)
+		}
+	}
+}
+
+// TestDisconnectBeforeHandled checks that a connection dropped before its
+// peer is handled does not keep the pair claimed.
+func TestDisconnectBeforeHandled(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	dropped := newSocket(t, ctx, "PAIR")
+	if err := dropped.Bind("inproc://dropped"); err != nil {
+		t.Fatal(err)
+	}
+	kept := newSocket(t, ctx, "PAIR")
+	if err := kept.Bind("inproc://kept"); err != nil {
+		t.Fatal(err)
+	}
+
+	sock := newSocket(t, ctx, "PAIR")
+	sock.SetOption(gomq.OptionReconnectIvl, 10*time.Millisecond)
+	sock.SetOption(gomq.OptionSendTimeout, 5*time.Second)
+	if err := sock.Connect("inproc://dropped"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sock.Disconnect("inproc://dropped"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sock.Connect("inproc://kept"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sock.Send([][]byte{[]byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+	if msg, err := kept.Recv(); err != nil || string(msg[0]) != "hello" {
+		t.Fatalf("expected hello, got %q (%v)", msg, err)
+	}
+}
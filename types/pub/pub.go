@@ -0,0 +1,203 @@
+package pub
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/types"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// Pub implements the zmq pub socket.
+type Pub struct {
+	context.Context
+	Cancel context.CancelFunc
+	*gomq.Config
+	Mech              zmtp.Mechanism
+	ConnectionDrivers map[string]*socketutil.ConnectionDriver
+	BindDrivers       map[string]*socketutil.BindDriver
+	EventBus          gomq.EventBus
+	Subscribers       socketutil.Subscribers
+}
+
+func (p *Pub) Name() string {
+	return "PUB"
+}
+
+func (p *Pub) Connect(tp transport.Transport, url *url.URL) error {
+	if _, ok := p.ConnectionDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyConnected, url)
+	}
+
+	driver := &socketutil.ConnectionDriver{}
+	driver.Setup(
+		p.Context,
+		p.Mech,
+		tp,
+		url,
+		p.Config,
+		p.EventBus,
+		p.HandleSock,
+		p.Meta,
+		p.MetaHandler,
+	)
+	fatal, err := driver.TryConnect()
+	if err != nil && fatal {
+		return err
+	}
+	p.ConnectionDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (p *Pub) Disconnect(url *url.URL) error {
+	driver, ok := p.ConnectionDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverConnected, url)
+	}
+
+	delete(p.ConnectionDrivers, url.String())
+	return driver.Close()
+}
+
+func (p *Pub) Bind(tp transport.Transport, url *url.URL) error {
+	if _, ok := p.BindDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyBound, url)
+	}
+
+	driver := &socketutil.BindDriver{}
+	driver.Setup(
+		p.Context,
+		tp,
+		p.Mech,
+		url,
+		p.Config,
+		p.HandleSock,
+		p.EventBus,
+		p.Meta,
+		p.MetaHandler,
+	)
+	if err := driver.TryBind(); err != nil {
+		return err
+	}
+	p.BindDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (p *Pub) Unbind(url *url.URL) error {
+	driver, ok := p.BindDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverBound, url.String())
+	}
+
+	delete(p.BindDrivers, url.String())
+	return driver.Close()
+}
+
+// HandleSock registers the peer as a subscriber for as long as it remains
+// connected, sending it every published message matching its subscriptions.
+func (p *Pub) HandleSock(ctx context.Context, peer *socketutil.Peer) error {
+	sub := p.Subscribers.Attach(peer, p.Config.SendHWM())
+	defer p.Subscribers.Detach(sub)
+
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- ReadSubscriptions(peer, &sub.Subscriptions)
+	}()
+
+	return sub.Serve(ctx, readErr)
+}
+
+// ReadSubscriptions applies subscriptions and cancellations sent by the peer
+// until reading from it fails.
+func ReadSubscriptions(sock zmtp.Socket, subs *socketutil.Subscriptions) error {
+	for {
+		next, err := sock.Read()
+		if err != nil {
+			return err
+		}
+
+		subscribe, topic, ok := zmtp.ParseSubscription(next)
+		if !ok {
+			continue
+		}
+
+		if subscribe {
+			subs.Add(topic)
+		} else {
+			subs.Remove(topic)
+		}
+	}
+}
+
+func (p *Pub) Meta() zmtp.Metadata {
+	meta := zmtp.Metadata{}
+	meta.AddProperty("Socket-Type", "PUB")
+	return meta
+}
+
+func (p *Pub) MetaHandler(meta zmtp.Metadata) error {
+	var err error
+	meta.Properties(func(name string, value string) {
+		if name == "Socket-Type" && err == nil {
+			if value != "SUB" && value != "XSUB" {
+				err = fmt.Errorf("Expected sub or xsub socket to connect, got %s", value)
+			}
+		}
+	})
+
+	return err
+}
+
+// Send queues the message for every subscriber whose subscriptions match its
+// first frame. Subscribers whose queue is full miss the message.
+func (p *Pub) Send(data []zmtp.Message) error {
+	if err := p.Context.Err(); err != nil {
+		return err
+	}
+
+	p.Subscribers.Publish(data)
+	return nil
+}
+
+// SendContext implements gomq.SocketDriver. Publishing never blocks.
+func (p *Pub) SendContext(_ context.Context, data []zmtp.Message) error {
+	return p.Send(data)
+}
+
+// QueueDepths implements gomq.QueueReporter.
+func (p *Pub) QueueDepths() (send, recv gomq.QueueDepth) {
+	return p.Subscribers.Depth(), gomq.QueueDepth{}
+}
+
+func (p *Pub) Recv() ([]zmtp.Message, error) {
+	return nil, types.ErrOperationNotPermitted
+}
+
+func (p *Pub) RecvContext(context.Context) ([]zmtp.Message, error) {
+	return nil, types.ErrOperationNotPermitted
+}
+
+func (p *Pub) Close() error {
+	return p.CloseContext(context.Background())
+}
+
+// CloseContext implements gomq.GracefulCloser, waiting up to the linger
+// period for messages queued for subscribers to be written.
+func (p *Pub) CloseContext(ctx context.Context) error {
+	discarded := p.Subscribers.Backlog.Linger(ctx, p.Config.Linger())
+	defer socketutil.PostDiscarded(p.EventBus, discarded)
+	p.Cancel()
+	for _, conn := range p.ConnectionDrivers {
+		conn.Close()
+	}
+	for _, bind := range p.BindDrivers {
+		bind.Close()
+	}
+	return nil
+}
@@ -19,9 +19,8 @@ func init() {
 				Config:            conf,
 				Mech:              mech,
 				ConnectionDrivers: map[string]*socketutil.ConnectionDriver{},
-				ConnectionHandles: map[string]socketutil.WaitCloser[struct{}]{},
 				BindDrivers:       map[string]*socketutil.BindDriver{},
-				ReadPoint:         make(chan []zmtp.Message),
+				Inboxes:           map[string]*socketutil.Inbox[[]zmtp.Message]{},
 				EventBus:          eventBus,
 			}, nil
 		},
@@ -19,10 +19,13 @@ type Pull struct {
 	*gomq.Config
 	Mech              zmtp.Mechanism
 	ConnectionDrivers map[string]*socketutil.ConnectionDriver
-	ConnectionHandles map[string]socketutil.WaitCloser[struct{}]
 	BindDrivers       map[string]*socketutil.BindDriver
-	ReadPoint         chan []zmtp.Message
+	Inboxes           map[string]*socketutil.Inbox[[]zmtp.Message]
 	EventBus          gomq.EventBus
+
+	// Queue takes messages from the inboxes of peers in turn. Inboxes of
+	// connecting peers persist across reconnects.
+	Queue socketutil.FairQueue[[]zmtp.Message]
 }
 
 func (p *Pull) Name() string {
@@ -30,11 +33,11 @@ func (p *Pull) Name() string {
 }
 
 func (p *Pull) Connect(tp transport.Transport, url *url.URL) error {
-	if _, ok := p.ConnectionHandles[url.String()]; ok {
+	if _, ok := p.Inboxes[url.String()]; ok {
 		return fmt.Errorf("%w: %s", types.ErrAlreadyConnected, url)
 	}
 
-	var queue chan zmtp.Message
+	var in *socketutil.Inbox[[]zmtp.Message]
 	driver := &socketutil.ConnectionDriver{}
 	driver.Setup(
 		p.Context,
@@ -43,8 +46,8 @@ func (p *Pull) Connect(tp transport.Transport, url *url.URL) error {
 		url,
 		p.Config,
 		p.EventBus,
-		func(ctx context.Context, s zmtp.Socket) error {
-			return HandleSock(ctx, s, queue)
+		func(ctx context.Context, s *socketutil.Peer) error {
+			return HandleSock(ctx, s, in)
 		},
 		p.Meta,
 		p.MetaHandler,
@@ -53,11 +56,9 @@ func (p *Pull) Connect(tp transport.Transport, url *url.URL) error {
 	if err != nil && fatal {
 		return err
 	}
-	queue = make(chan zmtp.Message, p.Config.QueueLen())
-	wc := socketutil.NewWaitCloser[struct{}](p.Context)
-	go PushIntoReadPoint(&wc, queue, p.ReadPoint)
+	in = p.Queue.Add(p.Config.RecvHWM())
 	p.ConnectionDrivers[url.String()] = driver
-	p.ConnectionHandles[url.String()] = wc
+	p.Inboxes[url.String()] = in
 	go driver.Run()
 	return nil
 }
@@ -70,8 +71,8 @@ func (p *Pull) Disconnect(url *url.URL) error {
 
 	delete(p.ConnectionDrivers, url.String())
 	err := driver.Close()
-	p.ConnectionHandles[url.String()].Close()
-	delete(p.ConnectionHandles, url.String())
+	p.Inboxes[url.String()].Close()
+	delete(p.Inboxes, url.String())
 	return err
 }
 
@@ -86,12 +87,11 @@ func (p *Pull) Bind(tp transport.Transport, url *url.URL) error {
 		tp,
 		p.Mech,
 		url,
-		func(ctx context.Context, s zmtp.Socket) error {
-			queue := make(chan zmtp.Message, p.Config.QueueLen())
-			wc := socketutil.NewWaitCloser[struct{}](p.Context)
-			defer wc.Finish(struct{}{})
-			go PushIntoReadPoint(&wc, queue, p.ReadPoint)
-			return HandleSock(ctx, s, queue)
+		p.Config,
+		func(ctx context.Context, s *socketutil.Peer) error {
+			in := p.Queue.Add(p.Config.RecvHWM())
+			defer in.Close()
+			return HandleSock(ctx, s, in)
 		},
 		p.EventBus,
 		p.Meta,
@@ -116,28 +116,10 @@ func (p *Pull) Unbind(url *url.URL) error {
 	return err
 }
 
-func PushIntoReadPoint(wc *socketutil.WaitCloser[struct{}], pull <-chan zmtp.Message, readPoint chan []zmtp.Message) {
-	defer wc.Finish(struct{}{})
+// HandleSock queues each whole message read from the peer in its inbox,
+// pausing reads while the inbox is at the receive high-water mark.
+func HandleSock(ctx context.Context, sock zmtp.Socket, in *socketutil.Inbox[[]zmtp.Message]) (err error) {
 	built := make([]zmtp.Message, 0)
-	for {
-		select {
-		case part := <-pull:
-			built = append(built, part)
-			if !part.More {
-				select {
-				case readPoint <- built:
-					built = make([]zmtp.Message, 0)
-				case <-wc.Done():
-					return
-				}
-			}
-		case <-wc.Done():
-			return
-		}
-	}
-}
-
-func HandleSock(ctx context.Context, sock zmtp.Socket, queue chan<- zmtp.Message) (err error) {
 	for {
 		next, err := sock.Read()
 		if err != nil {
@@ -148,10 +130,15 @@ func HandleSock(ctx context.Context, sock zmtp.Socket, queue chan<- zmtp.Message
 			continue
 		}
 
-		select {
-		case queue <- *next.Message:
-		case <-ctx.Done():
+		built = append(built, *next.Message)
+		if next.Message.More {
+			continue
+		}
+
+		if err := in.Deliver(ctx, built); err != nil {
+			return err
 		}
+		built = make([]zmtp.Message, 0)
 	}
 }
 
@@ -178,22 +165,43 @@ func (p *Pull) Send([]zmtp.Message) error {
 	return types.ErrOperationNotPermitted
 }
 
+func (p *Pull) SendContext(context.Context, []zmtp.Message) error {
+	return types.ErrOperationNotPermitted
+}
+
 func (p *Pull) Recv() ([]zmtp.Message, error) {
-	select {
-	case msg := <-p.ReadPoint:
-		return msg, nil
-	case <-p.Context.Done():
-		return nil, p.Context.Err()
-	}
+	return p.RecvContext(context.Background())
+}
+
+// RecvContext receives the next message from the peers in turn, failing
+// with types.ErrWouldBlock if ctx is done first.
+func (p *Pull) RecvContext(ctx context.Context) ([]zmtp.Message, error) {
+	return p.Queue.Receive(ctx, p.Context)
+}
+
+// RecvReady implements gomq.RecvPoller.
+func (p *Pull) RecvReady() <-chan struct{} {
+	return p.Queue.Ready()
+}
+
+// QueueDepths implements gomq.QueueReporter.
+func (p *Pull) QueueDepths() (send, recv gomq.QueueDepth) {
+	return gomq.QueueDepth{}, p.Queue.Depth()
 }
 
 func (p *Pull) Close() error {
 	p.Cancel()
-	for _, conn := range p.ConnectionDrivers {
+	for url, conn := range p.ConnectionDrivers {
 		conn.Close()
+		delete(p.ConnectionDrivers, url)
+	}
+	for url, in := range p.Inboxes {
+		in.Close()
+		delete(p.Inboxes, url)
 	}
-	for _, bind := range p.BindDrivers {
+	for url, bind := range p.BindDrivers {
 		bind.Close()
+		delete(p.BindDrivers, url)
 	}
 	return nil
 }
@@ -0,0 +1,118 @@
+package pull_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	_ "github.com/workspace-9/gomq/transport/tcp"
+	_ "github.com/workspace-9/gomq/types/pull"
+	_ "github.com/workspace-9/gomq/types/push"
+	_ "github.com/workspace-9/gomq/zmtp/null"
+)
+
+// Socket type codes sent in ZMTP 2.0 greetings.
+const (
+	codePull = 0x07
+	codePush = 0x08
+)
+
+// dialV2 connects to the socket bound at addr as a ZMTP 2.0 peer of the
+// given socket type, and checks the greeting the socket answers with.
+func dialV2(t *testing.T, addr string, code, wantCode byte) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(addr, "tcp://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// Signature, revision 1, socket type and an empty identity.
+	greeting := []byte{0xFF, 0, 0, 0, 0, 0, 0, 0, 0, 0x7F, 0x01, code, 0x00, 0x00}
+	if _, err := conn.Write(greeting); err != nil {
+		t.Fatal(err)
+	}
+
+	// Signature, version major 3, socket type and an empty identity.
+	got := make([]byte, 14)
+	if _, err := io.ReadFull(conn, got); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0xFF, 0, 0, 0, 0, 0, 0, 0, 0, 0x7F, 0x03, wantCode, 0x00, 0x00}
+	if !bytes.Equal(got[:9], want[:9]) || got[9]&0x01 == 0 || !bytes.Equal(got[10:], want[10:]) {
+		t.Fatalf("unexpected greeting %x", got)
+	}
+	return conn
+}
+
+// bind binds the socket to a free port and returns its address.
+func bind(t *testing.T, sock *gomq.Socket) string {
+	t.Helper()
+	events := sock.Monitor(gomq.Events(gomq.EventTypeListening))
+	if err := sock.Bind("tcp://127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	return (<-events).LocalAddr
+}
+
+// TestPullFromV2 checks that a PULL socket receives from a ZMTP 2.0 PUSH
+// peer.
+func TestPullFromV2(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	pull, err := ctx.NewSocket("PULL", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pull.Close()
+	pull.SetOption(gomq.OptionRecvTimeout, 5*time.Second)
+
+	conn := dialV2(t, bind(t, pull), codePush, codePull)
+	defer conn.Close()
+	if _, err := conn.Write([]byte{0x01, 0x03, 'h', 'e', 'l', 0x00, 0x02, 'l', 'o'}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := pull.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msg) != 2 || string(msg[0]) != "hel" || string(msg[1]) != "lo" {
+		t.Fatalf("expected hel, lo, got %q", msg)
+	}
+}
+
+// TestPushToV2 checks that a PUSH socket sends to a ZMTP 2.0 PULL peer,
+// with no commands among the frames.
+func TestPushToV2(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	push, err := ctx.NewSocket("PUSH", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer push.Close()
+	push.SetOption(gomq.OptionHeartbeatIvl, 10*time.Millisecond)
+
+	conn := dialV2(t, bind(t, push), codePull, codePush)
+	defer conn.Close()
+	time.Sleep(50 * time.Millisecond)
+	if err := push.Send([][]byte{[]byte("hel"), []byte("lo")}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]byte, 9)
+	if _, err := io.ReadFull(conn, got); err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0x01, 0x03, 'h', 'e', 'l', 0x00, 0x02, 'l', 'o'}; !bytes.Equal(got, want) {
+		t.Fatalf("expected frames %x, got %x", want, got)
+	}
+}
@@ -20,9 +20,8 @@ func init() {
 				Mech:              mech,
 				ConnectionDrivers: map[string]*socketutil.ConnectionDriver{},
 				BindDrivers:       map[string]*socketutil.BindDriver{},
-				ConnectionHandles: map[string]socketutil.WaitCloser[struct{}]{},
+				Outboxes:          map[string]*socketutil.Outbox{},
 				EventBus:          eventBus,
-				WritePoint:        make(chan []zmtp.Message),
 			}, nil
 		},
 	)
@@ -20,9 +20,15 @@ type Push struct {
 	Mech              zmtp.Mechanism
 	ConnectionDrivers map[string]*socketutil.ConnectionDriver
 	BindDrivers       map[string]*socketutil.BindDriver
-	ConnectionHandles map[string]socketutil.WaitCloser[struct{}]
+	Outboxes          map[string]*socketutil.Outbox
 	EventBus          gomq.EventBus
-	WritePoint        chan []zmtp.Message
+
+	// Balancer deals messages across the outboxes of connected peers, and
+	// of connecting peers so that messages queue while they reconnect.
+	Balancer socketutil.LoadBalancer
+
+	// Backlog counts messages accepted by Send which are yet to be written.
+	Backlog socketutil.Backlog
 }
 
 func (p *Push) Name() string {
@@ -30,11 +36,11 @@ func (p *Push) Name() string {
 }
 
 func (p *Push) Connect(tp transport.Transport, url *url.URL) error {
-	if _, ok := p.ConnectionHandles[url.String()]; ok {
+	if _, ok := p.Outboxes[url.String()]; ok {
 		return fmt.Errorf("%w: %s", types.ErrAlreadyConnected, url.String())
 	}
 
-	var queue chan zmtp.Message
+	var out *socketutil.Outbox
 	driver := &socketutil.ConnectionDriver{}
 	driver.Setup(
 		p.Context,
@@ -43,8 +49,8 @@ func (p *Push) Connect(tp transport.Transport, url *url.URL) error {
 		url,
 		p.Config,
 		p.EventBus,
-		func(ctx context.Context, s zmtp.Socket) error {
-			return HandleSock(ctx, s, queue)
+		func(ctx context.Context, peer *socketutil.Peer) error {
+			return HandleSock(ctx, peer, out, &p.Backlog)
 		},
 		p.Meta,
 		p.MetaHandler,
@@ -53,11 +59,9 @@ func (p *Push) Connect(tp transport.Transport, url *url.URL) error {
 	if err != nil && fatal {
 		return err
 	}
-	queue = make(chan zmtp.Message, p.Config.QueueLen())
-	wc := socketutil.NewWaitCloser[struct{}](p.Context)
-	go PullFromWritePoint(&wc, queue, p.WritePoint)
+	out = p.Balancer.Add(p.Config.SendHWM())
 	p.ConnectionDrivers[url.String()] = driver
-	p.ConnectionHandles[url.String()] = wc
+	p.Outboxes[url.String()] = out
 	go driver.Run()
 	return nil
 }
@@ -70,8 +74,8 @@ func (p *Push) Disconnect(url *url.URL) error {
 
 	delete(p.ConnectionDrivers, url.String())
 	err := driver.Close()
-	p.ConnectionHandles[url.String()].Close()
-	delete(p.ConnectionHandles, url.String())
+	p.Backlog.Done(p.Balancer.Remove(p.Outboxes[url.String()]))
+	delete(p.Outboxes, url.String())
 	return err
 }
 
@@ -82,12 +86,12 @@ func (p *Push) Bind(tp transport.Transport, url *url.URL) error {
 		tp,
 		p.Mech,
 		url,
-		func(ctx context.Context, s zmtp.Socket) error {
-			queue := make(chan zmtp.Message, p.Config.QueueLen())
-			wc := socketutil.NewWaitCloser[struct{}](p.Context)
-			defer wc.Finish(struct{}{})
-			go PullFromWritePoint(&wc, queue, p.WritePoint)
-			return HandleSock(ctx, s, queue)
+		p.Config,
+		func(ctx context.Context, s *socketutil.Peer) error {
+			out := p.Balancer.Add(p.Config.SendHWM())
+			err := HandleSock(ctx, s, out, &p.Backlog)
+			p.Backlog.Done(p.Balancer.Remove(out))
+			return err
 		},
 		p.EventBus,
 		p.Meta,
@@ -112,33 +116,32 @@ func (p *Push) Unbind(url *url.URL) error {
 	return err
 }
 
-func PullFromWritePoint(wc *socketutil.WaitCloser[struct{}], push chan<- zmtp.Message, writePoint chan []zmtp.Message) {
-	defer wc.Finish(struct{}{})
-	for {
-		select {
-		case message := <-writePoint:
-			for _, part := range message {
-				select {
-				case push <- part:
-				case <-wc.Done():
-					return
-				}
+// HandleSock writes messages dealt to the outbox to the peer until writing
+// fails, the peer goes away or ctx is done.
+func HandleSock(ctx context.Context, peer *socketutil.Peer, out *socketutil.Outbox, backlog *socketutil.Backlog) error {
+	// Pull sockets send nothing, but reading notices the peer going away and
+	// answers heartbeats.
+	derived, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go func() {
+		for {
+			if _, err := peer.Read(); err != nil {
+				cancel(err)
+				return
 			}
-		case <-wc.Done():
-			return
 		}
-	}
-}
+	}()
 
-func HandleSock(ctx context.Context, sock zmtp.Socket, queue <-chan zmtp.Message) (err error) {
 	for {
-		select {
-		case msg := <-queue:
-			if err := sock.SendMessage(msg); err != nil {
-				return err
-			}
-		case <-ctx.Done():
-			return ctx.Err()
+		msg, err := out.Receive(derived)
+		if err != nil {
+			return context.Cause(derived)
+		}
+
+		err = peer.SendMessages(msg)
+		backlog.Done(1)
+		if err != nil {
+			return err
 		}
 	}
 }
@@ -163,25 +166,59 @@ func (p *Push) MetaHandler(meta zmtp.Metadata) error {
 }
 
 func (p *Push) Send(data []zmtp.Message) error {
-	select {
-	case p.WritePoint <- data:
-		return nil
-	case <-p.Context.Done():
-		return p.Context.Err()
+	return p.SendContext(context.Background(), data)
+}
+
+// SendContext deals the message to the next peer in turn with room in its
+// queue, failing with types.ErrWouldBlock if ctx is done before one has room.
+func (p *Push) SendContext(ctx context.Context, data []zmtp.Message) error {
+	p.Backlog.Add(1)
+	err := p.Balancer.Send(ctx, p.Context, data)
+	if err != nil {
+		p.Backlog.Done(1)
 	}
+	return err
+}
+
+// SendReady implements gomq.SendPoller.
+func (p *Push) SendReady() <-chan struct{} {
+	return p.Balancer.Ready()
+}
+
+// QueueDepths implements gomq.QueueReporter.
+func (p *Push) QueueDepths() (send, recv gomq.QueueDepth) {
+	return p.Balancer.Depth(), gomq.QueueDepth{}
 }
 
 func (p *Push) Recv() ([]zmtp.Message, error) {
 	return nil, types.ErrOperationNotPermitted
 }
 
+func (p *Push) RecvContext(context.Context) ([]zmtp.Message, error) {
+	return nil, types.ErrOperationNotPermitted
+}
+
 func (p *Push) Close() error {
+	return p.CloseContext(context.Background())
+}
+
+// CloseContext implements gomq.GracefulCloser, waiting up to the linger
+// period for queued messages to be written before tearing down.
+func (p *Push) CloseContext(ctx context.Context) error {
+	discarded := p.Backlog.Linger(ctx, p.Config.Linger())
 	p.Cancel()
-	for _, conn := range p.ConnectionDrivers {
+	for url, conn := range p.ConnectionDrivers {
 		conn.Close()
+		delete(p.ConnectionDrivers, url)
+	}
+	for url, out := range p.Outboxes {
+		p.Balancer.Remove(out)
+		delete(p.Outboxes, url)
 	}
-	for _, conn := range p.BindDrivers {
+	for url, conn := range p.BindDrivers {
 		conn.Close()
+		delete(p.BindDrivers, url)
 	}
+	socketutil.PostDiscarded(p.EventBus, discarded)
 	return nil
 }
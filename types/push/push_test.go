@@ -0,0 +1,126 @@
+package push_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	_ "github.com/workspace-9/gomq/transport/inproc"
+	_ "github.com/workspace-9/gomq/types/pull"
+	_ "github.com/workspace-9/gomq/types/push"
+	_ "github.com/workspace-9/gomq/zmtp/null"
+)
+
+// queued makes a PUSH connected to an address nothing is bound to yet,
+// with the messages queued for it.
+func queued(t *testing.T, ctx *gomq.Context, addr string, linger time.Duration, bodies ...string) *gomq.Socket {
+	t.Helper()
+	push, err := ctx.NewSocket("PUSH", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	push.SetOption(gomq.OptionLinger, linger)
+	if err := push.Connect(addr); err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range bodies {
+		if err := push.Send([][]byte{[]byte(body)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return push
+}
+
+// TestLingerFlushes checks that Close waits for queued messages to be
+// written to a peer which appears within the linger period.
+func TestLingerFlushes(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	push := queued(t, ctx, "inproc://flush", 5*time.Second, "one", "two", "three")
+	events := push.Monitor(gomq.Events(gomq.EventTypeMessagesDiscarded))
+	closed := make(chan error, 1)
+	go func() {
+		closed <- push.Close()
+	}()
+
+	pull, err := ctx.NewSocket("PULL", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pull.Close()
+	pull.SetOption(gomq.OptionRecvTimeout, 5*time.Second)
+	if err := pull.Bind("inproc://flush"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"one", "two", "three"} {
+		msg, err := pull.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(msg[0]) != want {
+			t.Fatalf("expected %q, got %q", want, msg[0])
+		}
+	}
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Close to return once the messages were written")
+	}
+	if ev, ok := <-events; ok {
+		t.Fatalf("expected nothing discarded, got %q", ev.Notes)
+	}
+}
+
+// TestLingerDiscards checks that Close gives up on queued messages once the
+// linger period passes, reporting how many it discarded.
+func TestLingerDiscards(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	for _, linger := range []time.Duration{0, 50 * time.Millisecond} {
+		push := queued(t, ctx, "inproc://discard", linger, "one", "two", "three")
+		events := push.Monitor(gomq.Events(gomq.EventTypeMessagesDiscarded))
+
+		start := time.Now()
+		if err := push.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(start); elapsed < linger || elapsed > linger+time.Second {
+			t.Fatalf("expected Close to linger %s, took %s", linger, elapsed)
+		}
+
+		ev, ok := <-events
+		if !ok || ev.Notes != "3 queued messages discarded on close" {
+			t.Fatalf("expected 3 messages discarded, got %q", ev.Notes)
+		}
+	}
+}
+
+// TestShutdownBoundsLinger checks that Context.Shutdown stops sockets which
+// would linger forever once its ctx is done.
+func TestShutdownBoundsLinger(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	push := queued(t, ctx, "inproc://shutdown", -1, "one")
+	events := push.Monitor(gomq.Events(gomq.EventTypeMessagesDiscarded))
+
+	deadline, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := ctx.Shutdown(deadline); err != nil {
+		t.Fatal(err)
+	}
+	if ev, ok := <-events; !ok || ev.Notes != "1 queued messages discarded on close" {
+		t.Fatalf("expected 1 message discarded, got %q", ev.Notes)
+	}
+	if _, err := ctx.NewSocket("PUSH", "NULL"); !errors.Is(err, gomq.ErrContextShutdown) {
+		t.Fatalf("expected ErrContextShutdown, got %v", err)
+	}
+}
@@ -0,0 +1,265 @@
+package radio
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/types"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// Radio implements the zmq radio socket, sending each message to the DISH
+// peers which joined its group. Over datagram transports such as udp every
+// message is sent and the DISH filters them.
+type Radio struct {
+	context.Context
+	Cancel context.CancelFunc
+	*gomq.Config
+	Mech              zmtp.Mechanism
+	ConnectionDrivers map[string]*socketutil.ConnectionDriver
+	BindDrivers       map[string]*socketutil.BindDriver
+	EventBus          gomq.EventBus
+	Subscribers       socketutil.Subscribers
+
+	// Senders are the connected endpoints of datagram transports, guarded
+	// by the lock as Send ranges over them.
+	Senders map[string]*socketutil.DatagramSender
+	lock    sync.RWMutex
+}
+
+func (r *Radio) Name() string {
+	return "RADIO"
+}
+
+func (r *Radio) Connect(tp transport.Transport, url *url.URL) error {
+	if dtp, ok := tp.(transport.DatagramTransport); ok {
+		return r.connectDatagrams(dtp, url)
+	}
+
+	if _, ok := r.ConnectionDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyConnected, url)
+	}
+
+	driver := &socketutil.ConnectionDriver{}
+	driver.Setup(
+		r.Context,
+		r.Mech,
+		tp,
+		url,
+		r.Config,
+		r.EventBus,
+		r.HandleSock,
+		r.Meta,
+		r.MetaHandler,
+	)
+	fatal, err := driver.TryConnect()
+	if err != nil && fatal {
+		return err
+	}
+	r.ConnectionDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (r *Radio) connectDatagrams(tp transport.DatagramTransport, url *url.URL) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.Senders[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyConnected, url)
+	}
+
+	sender, err := socketutil.DialDatagrams(r.Context, tp, url, r.EventBus)
+	if err != nil {
+		return err
+	}
+	r.Senders[url.String()] = sender
+	return nil
+}
+
+func (r *Radio) Disconnect(url *url.URL) error {
+	r.lock.Lock()
+	sender, ok := r.Senders[url.String()]
+	delete(r.Senders, url.String())
+	r.lock.Unlock()
+	if ok {
+		return sender.Close()
+	}
+
+	driver, ok := r.ConnectionDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverConnected, url)
+	}
+
+	delete(r.ConnectionDrivers, url.String())
+	return driver.Close()
+}
+
+func (r *Radio) Bind(tp transport.Transport, url *url.URL) error {
+	if _, ok := tp.(transport.DatagramTransport); ok {
+		return fmt.Errorf("%w: RADIO connects to send over %s", types.ErrOperationNotPermitted, tp.Name())
+	}
+
+	if _, ok := r.BindDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyBound, url)
+	}
+
+	driver := &socketutil.BindDriver{}
+	driver.Setup(
+		r.Context,
+		tp,
+		r.Mech,
+		url,
+		r.Config,
+		r.HandleSock,
+		r.EventBus,
+		r.Meta,
+		r.MetaHandler,
+	)
+	if err := driver.TryBind(); err != nil {
+		return err
+	}
+	r.BindDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (r *Radio) Unbind(url *url.URL) error {
+	driver, ok := r.BindDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverBound, url.String())
+	}
+
+	delete(r.BindDrivers, url.String())
+	return driver.Close()
+}
+
+// HandleSock tracks the groups the peer joins for as long as it remains
+// connected, sending it every message of those groups.
+func (r *Radio) HandleSock(ctx context.Context, peer *socketutil.Peer) error {
+	sub := r.Subscribers.Attach(peer, r.Config.SendHWM())
+	defer r.Subscribers.Detach(sub)
+
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- ReadGroups(peer, &sub.Subscriptions)
+	}()
+
+	return sub.Serve(ctx, readErr)
+}
+
+// ReadGroups applies the JOIN and LEAVE commands sent by the peer until
+// reading from it fails.
+func ReadGroups(sock zmtp.Socket, groups *socketutil.Subscriptions) error {
+	for {
+		next, err := sock.Read()
+		if err != nil {
+			return err
+		}
+
+		join, group, ok := zmtp.ParseGroupCommand(next)
+		if !ok {
+			continue
+		}
+
+		if join {
+			groups.Add([]byte(group))
+		} else {
+			groups.Remove([]byte(group))
+		}
+	}
+}
+
+func (r *Radio) Meta() zmtp.Metadata {
+	meta := zmtp.Metadata{}
+	meta.AddProperty("Socket-Type", "RADIO")
+	return meta
+}
+
+func (r *Radio) MetaHandler(meta zmtp.Metadata) error {
+	var err error
+	meta.Properties(func(name string, value string) {
+		if name == "Socket-Type" && err == nil {
+			if value != "DISH" {
+				err = fmt.Errorf("Expected dish socket to connect, got %s", value)
+			}
+		}
+	})
+
+	return err
+}
+
+// Send the single frame message to its group. Peers whose queue is full
+// miss the message, as do datagrams which are lost. Datagrams which fail to
+// send are counted in gomq_send_errors_total rather than failing Send, so
+// one bad endpoint does not stop the others.
+func (r *Radio) Send(data []zmtp.Message) error {
+	if err := r.Context.Err(); err != nil {
+		return err
+	}
+
+	if err := socketutil.CheckGroupMessage(data); err != nil {
+		return err
+	}
+
+	r.Subscribers.PublishGroup(data[0])
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for _, sender := range r.Senders {
+		// Counted by the sender.
+		_ = sender.Send(data[0])
+	}
+
+	return nil
+}
+
+// SendContext implements gomq.SocketDriver. Sending never blocks.
+func (r *Radio) SendContext(_ context.Context, data []zmtp.Message) error {
+	return r.Send(data)
+}
+
+// QueueDepths implements gomq.QueueReporter.
+func (r *Radio) QueueDepths() (send, recv gomq.QueueDepth) {
+	return r.Subscribers.Depth(), gomq.QueueDepth{}
+}
+
+func (r *Radio) Recv() ([]zmtp.Message, error) {
+	return nil, types.ErrOperationNotPermitted
+}
+
+func (r *Radio) RecvContext(context.Context) ([]zmtp.Message, error) {
+	return nil, types.ErrOperationNotPermitted
+}
+
+func (r *Radio) Close() error {
+	return r.CloseContext(context.Background())
+}
+
+// CloseContext implements gomq.GracefulCloser, waiting up to the linger
+// period for messages queued for peers to be written.
+func (r *Radio) CloseContext(ctx context.Context) error {
+	discarded := r.Subscribers.Backlog.Linger(ctx, r.Config.Linger())
+	defer socketutil.PostDiscarded(r.EventBus, discarded)
+
+	r.Cancel()
+	for _, conn := range r.ConnectionDrivers {
+		conn.Close()
+	}
+	for _, bind := range r.BindDrivers {
+		bind.Close()
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for url, sender := range r.Senders {
+		sender.Close()
+		delete(r.Senders, url)
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package rep
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/types"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// Rep implements the zmq rep socket.
+type Rep struct {
+	context.Context
+	Cancel context.CancelFunc
+	*gomq.Config
+	Mech              zmtp.Mechanism
+	ConnectionDrivers map[string]*socketutil.ConnectionDriver
+	BindDrivers       map[string]*socketutil.BindDriver
+	EventBus          gomq.EventBus
+
+	// Queue takes messages from the inboxes of peers in turn.
+	Queue socketutil.FairQueue[socketutil.PeerMessage]
+
+	// Outboxes maps connected peers to their outboxes.
+	Outboxes map[*socketutil.Peer]*socketutil.Outbox
+
+	// Outgoing holds the outbox of every peer. Replies are addressed to one
+	// outbox rather than dealt across them.
+	Outgoing socketutil.LoadBalancer
+
+	// Backlog counts replies accepted by Send which are yet to be written.
+	Backlog socketutil.Backlog
+
+	// sendLock serialises senders while they wait for room, which must not
+	// hold up lock.
+	sendLock    sync.Mutex
+	lock        sync.Mutex
+	mustReply   bool
+	replyOut    *socketutil.Outbox
+	replyHeader []zmtp.Message
+}
+
+func (r *Rep) Name() string {
+	return "REP"
+}
+
+func (r *Rep) Connect(tp transport.Transport, url *url.URL) error {
+	if _, ok := r.ConnectionDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyConnected, url)
+	}
+
+	driver := &socketutil.ConnectionDriver{}
+	driver.Setup(
+		r.Context,
+		r.Mech,
+		tp,
+		url,
+		r.Config,
+		r.EventBus,
+		r.HandleSock,
+		r.Meta,
+		r.MetaHandler,
+	)
+	fatal, err := driver.TryConnect()
+	if err != nil && fatal {
+		return err
+	}
+	r.ConnectionDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (r *Rep) Disconnect(url *url.URL) error {
+	driver, ok := r.ConnectionDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverConnected, url)
+	}
+
+	delete(r.ConnectionDrivers, url.String())
+	return driver.Close()
+}
+
+func (r *Rep) Bind(tp transport.Transport, url *url.URL) error {
+	if _, ok := r.BindDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyBound, url)
+	}
+
+	driver := &socketutil.BindDriver{}
+	driver.Setup(
+		r.Context,
+		tp,
+		r.Mech,
+		url,
+		r.Config,
+		r.HandleSock,
+		r.EventBus,
+		r.Meta,
+		r.MetaHandler,
+	)
+	if err := driver.TryBind(); err != nil {
+		return err
+	}
+	r.BindDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (r *Rep) Unbind(url *url.URL) error {
+	driver, ok := r.BindDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverBound, url.String())
+	}
+
+	delete(r.BindDrivers, url.String())
+	return driver.Close()
+}
+
+// HandleSock reads requests from the peer and writes the replies queued in
+// its outbox, until either fails or ctx is done.
+func (r *Rep) HandleSock(ctx context.Context, peer *socketutil.Peer) error {
+	out := r.Outgoing.Add(r.Config.SendHWM())
+	r.lock.Lock()
+	r.Outboxes[peer] = out
+	r.lock.Unlock()
+	defer func() {
+		r.lock.Lock()
+		delete(r.Outboxes, peer)
+		r.lock.Unlock()
+		r.Backlog.Done(r.Outgoing.Remove(out))
+	}()
+
+	in := r.Queue.Add(r.Config.RecvHWM())
+	derived, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go func() {
+		defer in.Close()
+		cancel(socketutil.ReadMessages(derived, peer, in))
+	}()
+
+	for {
+		msg, err := out.Receive(derived)
+		if err != nil {
+			return context.Cause(derived)
+		}
+
+		err = peer.SendMessages(msg)
+		r.Backlog.Done(1)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (r *Rep) Meta() zmtp.Metadata {
+	meta := zmtp.Metadata{}
+	meta.AddProperty("Socket-Type", "REP")
+	return meta
+}
+
+func (r *Rep) MetaHandler(meta zmtp.Metadata) error {
+	var err error
+	meta.Properties(func(name string, value string) {
+		if name == "Socket-Type" && err == nil {
+			if value != "REQ" && value != "DEALER" {
+				err = fmt.Errorf("Expected req or dealer socket to connect, got %s", value)
+			}
+		}
+	})
+
+	return err
+}
+
+// Send the reply to the latest request back to the peer which sent it. The
+// reply is dropped if that peer has since disconnected.
+func (r *Rep) Send(data []zmtp.Message) error {
+	return r.SendContext(context.Background(), data)
+}
+
+// SendContext queues the reply for the peer, failing with
+// types.ErrWouldBlock if ctx is done before its queue has room. The reply
+// may then be sent again.
+func (r *Rep) SendContext(ctx context.Context, data []zmtp.Message) error {
+	r.sendLock.Lock()
+	defer r.sendLock.Unlock()
+
+	r.lock.Lock()
+	if !r.mustReply {
+		r.lock.Unlock()
+		return fmt.Errorf("%w: must receive request before replying", types.ErrInvalidState)
+	}
+
+	reply := make([]zmtp.Message, 0, len(r.replyHeader)+len(data))
+	reply = append(reply, r.replyHeader...)
+	reply = append(reply, data...)
+	out := r.replyOut
+	r.lock.Unlock()
+
+	if out != nil {
+		r.Backlog.Add(1)
+		err := out.Send(ctx, r.Context, reply)
+		if err != nil {
+			r.Backlog.Done(1)
+		}
+		if err != nil && !errors.Is(err, socketutil.ErrOutboxRemoved) {
+			return err
+		}
+	}
+
+	r.lock.Lock()
+	r.mustReply = false
+	r.replyOut = nil
+	r.replyHeader = nil
+	r.lock.Unlock()
+	return nil
+}
+
+// SendReady implements gomq.SendPoller.
+func (r *Rep) SendReady() <-chan struct{} {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !r.mustReply {
+		return nil
+	}
+	if r.replyOut != nil {
+		return r.replyOut.Ready()
+	}
+
+	ready := make(chan struct{})
+	close(ready)
+	return ready
+}
+
+// Recv the next request from any peer. Requests without an envelope are
+// dropped.
+func (r *Rep) Recv() ([]zmtp.Message, error) {
+	return r.RecvContext(context.Background())
+}
+
+// RecvContext implements gomq.SocketDriver.
+func (r *Rep) RecvContext(ctx context.Context) ([]zmtp.Message, error) {
+	r.lock.Lock()
+	mustReply := r.mustReply
+	r.lock.Unlock()
+	if mustReply {
+		return nil, fmt.Errorf("%w: must reply before receiving", types.ErrInvalidState)
+	}
+
+	for {
+		in, err := r.Queue.Receive(ctx, r.Context)
+		if err != nil {
+			return nil, err
+		}
+
+		header, request, ok := SplitEnvelope(in.Message)
+		if !ok {
+			continue
+		}
+
+		r.lock.Lock()
+		r.mustReply = true
+		r.replyOut = r.Outboxes[in.Peer]
+		r.replyHeader = header
+		r.lock.Unlock()
+		return request, nil
+	}
+}
+
+// RecvReady implements gomq.RecvPoller. Requests which Recv would drop for
+// lacking an envelope still count as ready.
+func (r *Rep) RecvReady() <-chan struct{} {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.mustReply {
+		return nil
+	}
+	return r.Queue.Ready()
+}
+
+// QueueDepths implements gomq.QueueReporter.
+func (r *Rep) QueueDepths() (send, recv gomq.QueueDepth) {
+	return r.Outgoing.Depth(), r.Queue.Depth()
+}
+
+// SplitEnvelope splits a message into its envelope, every frame up to and
+// including the empty delimiter, and its body.
+func SplitEnvelope(msg []zmtp.Message) (header, body []zmtp.Message, ok bool) {
+	for idx, part := range msg {
+		if len(part.Body) == 0 && part.More {
+			return msg[:idx+1], msg[idx+1:], true
+		}
+	}
+
+	return nil, nil, false
+}
+
+func (r *Rep) Close() error {
+	return r.CloseContext(context.Background())
+}
+
+// CloseContext implements gomq.GracefulCloser, waiting up to the linger
+// period for queued replies to be written before tearing down.
+func (r *Rep) CloseContext(ctx context.Context) error {
+	discarded := r.Backlog.Linger(ctx, r.Config.Linger())
+	r.Cancel()
+	for _, conn := range r.ConnectionDrivers {
+		conn.Close()
+	}
+	for _, bind := range r.BindDrivers {
+		bind.Close()
+	}
+	socketutil.PostDiscarded(r.EventBus, discarded)
+	return nil
+}
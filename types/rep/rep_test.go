@@ -0,0 +1,83 @@
+package rep_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	_ "github.com/workspace-9/gomq/transport/inproc"
+	"github.com/workspace-9/gomq/types"
+	_ "github.com/workspace-9/gomq/types/dealer"
+	_ "github.com/workspace-9/gomq/types/rep"
+	_ "github.com/workspace-9/gomq/zmtp/null"
+)
+
+// TestReplyWouldBlock checks that a reply to a peer whose queue is full
+// fails rather than waits when asked not to, and may be sent again once
+// the peer catches up.
+func TestReplyWouldBlock(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	rep, err := ctx.NewSocket("REP", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rep.Close()
+	rep.SetOption(gomq.OptionSendHWM, 1)
+	rep.SetOption(gomq.OptionRecvTimeout, 5*time.Second)
+	if err := rep.Bind("inproc://reply"); err != nil {
+		t.Fatal(err)
+	}
+
+	dealer, err := ctx.NewSocket("DEALER", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dealer.Close()
+	dealer.SetOption(gomq.OptionRecvHWM, 1)
+	dealer.SetOption(gomq.OptionRecvTimeout, 5*time.Second)
+	if err := dealer.Connect("inproc://reply"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Large replies fill the connection's buffer quickly.
+	body := make([]byte, 16*1024)
+	replied := 0
+	for ; replied < 1000; replied++ {
+		if err := dealer.Send([][]byte{nil, []byte("ping")}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := rep.Recv(); err != nil {
+			t.Fatal(err)
+		}
+
+		err := rep.Send([][]byte{body}, gomq.DontWait)
+		if errors.Is(err, types.ErrWouldBlock) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if replied == 1000 {
+		t.Fatal("expected replies to fail once the queue was full")
+	}
+
+	if _, err := rep.Recv(); !errors.Is(err, types.ErrInvalidState) {
+		t.Fatalf("expected the reply to be still owed, got %v", err)
+	}
+	if _, err := dealer.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	if err := rep.Send([][]byte{body}); err != nil {
+		t.Fatal(err)
+	}
+	for idx := 1; idx <= replied; idx++ {
+		if _, err := dealer.Recv(); err != nil {
+			t.Fatalf("recv %d of %d: %v", idx, replied+1, err)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package req
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/types"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// Req implements the zmq req socket.
+type Req struct {
+	context.Context
+	Cancel context.CancelFunc
+	*gomq.Config
+	Mech              zmtp.Mechanism
+	ConnectionDrivers map[string]*socketutil.ConnectionDriver
+	BindDrivers       map[string]*socketutil.BindDriver
+	EventBus          gomq.EventBus
+	Peers             socketutil.RoundRobin[*socketutil.Peer]
+
+	// Queue takes messages from the inboxes of peers in turn.
+	Queue socketutil.FairQueue[socketutil.PeerMessage]
+
+	// sendLock serialises senders while they wait for a peer and write,
+	// which must not hold up lock.
+	sendLock      sync.Mutex
+	lock          sync.Mutex
+	correlate     bool
+	relaxed       bool
+	awaitingReply bool
+	replyPeer     *socketutil.Peer
+	requestID     uint32
+}
+
+func (r *Req) Name() string {
+	return "REQ"
+}
+
+func (r *Req) Connect(tp transport.Transport, url *url.URL) error {
+	if _, ok := r.ConnectionDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyConnected, url)
+	}
+
+	driver := &socketutil.ConnectionDriver{}
+	driver.Setup(
+		r.Context,
+		r.Mech,
+		tp,
+		url,
+		r.Config,
+		r.EventBus,
+		r.HandleSock,
+		r.Meta,
+		r.MetaHandler,
+	)
+	fatal, err := driver.TryConnect()
+	if err != nil && fatal {
+		return err
+	}
+	r.ConnectionDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (r *Req) Disconnect(url *url.URL) error {
+	driver, ok := r.ConnectionDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverConnected, url)
+	}
+
+	delete(r.ConnectionDrivers, url.String())
+	return driver.Close()
+}
+
+func (r *Req) Bind(tp transport.Transport, url *url.URL) error {
+	if _, ok := r.BindDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyBound, url)
+	}
+
+	driver := &socketutil.BindDriver{}
+	driver.Setup(
+		r.Context,
+		tp,
+		r.Mech,
+		url,
+		r.Config,
+		r.HandleSock,
+		r.EventBus,
+		r.Meta,
+		r.MetaHandler,
+	)
+	if err := driver.TryBind(); err != nil {
+		return err
+	}
+	r.BindDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (r *Req) Unbind(url *url.URL) error {
+	driver, ok := r.BindDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverBound, url.String())
+	}
+
+	delete(r.BindDrivers, url.String())
+	return driver.Close()
+}
+
+// HandleSock puts the peer in the request rotation and reads its replies for
+// as long as it remains connected.
+func (r *Req) HandleSock(ctx context.Context, peer *socketutil.Peer) error {
+	r.Peers.Add(peer)
+	defer r.Peers.Remove(peer)
+	in := r.Queue.Add(r.Config.RecvHWM())
+	defer in.Close()
+	return socketutil.ReadMessages(ctx, peer, in)
+}
+
+func (r *Req) Meta() zmtp.Metadata {
+	meta := zmtp.Metadata{}
+	meta.AddProperty("Socket-Type", "REQ")
+	return meta
+}
+
+func (r *Req) MetaHandler(meta zmtp.Metadata) error {
+	var err error
+	meta.Properties(func(name string, value string) {
+		if name == "Socket-Type" && err == nil {
+			if value != "REP" && value != "ROUTER" {
+				err = fmt.Errorf("Expected rep or router socket to connect, got %s", value)
+			}
+		}
+	})
+
+	return err
+}
+
+// SetOption implements gomq.OptionSetter.
+func (r *Req) SetOption(option string, val any) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	switch option {
+	case gomq.OptionReqCorrelate, gomq.OptionReqRelaxed:
+		on, err := types.BoolOption(option, val)
+		if err != nil {
+			return err
+		}
+
+		if option == gomq.OptionReqCorrelate {
+			r.correlate = on
+		} else {
+			r.relaxed = on
+		}
+	default:
+		return gomq.ErrUnknownOption
+	}
+
+	return nil
+}
+
+// Send a request to the next peer in the rotation. Unless OptionReqRelaxed
+// is set, the reply to the previous request must be received first.
+func (r *Req) Send(data []zmtp.Message) error {
+	return r.SendContext(context.Background(), data)
+}
+
+// SendContext implements gomq.SocketDriver.
+func (r *Req) SendContext(ctx context.Context, data []zmtp.Message) error {
+	r.sendLock.Lock()
+	defer r.sendLock.Unlock()
+
+	r.lock.Lock()
+	err := r.checkCanSend()
+	r.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	merged, cancel := socketutil.MergeContext(ctx, r.Context)
+	defer cancel()
+	peer, err := r.Peers.Next(merged)
+	if err != nil {
+		return socketutil.Interrupted(ctx, r.Context)
+	}
+
+	request, err := r.startRequest(peer, data)
+	if err != nil {
+		return err
+	}
+
+	if err := peer.SendMessages(request); err != nil {
+		r.lock.Lock()
+		r.awaitingReply = false
+		r.replyPeer = nil
+		r.lock.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+// checkCanSend fails unless a request may be sent. The lock must be held.
+func (r *Req) checkCanSend() error {
+	if r.awaitingReply && !r.relaxed {
+		return fmt.Errorf("%w: must receive reply before sending", types.ErrInvalidState)
+	}
+	return nil
+}
+
+// startRequest wraps data in the envelope of the next request and awaits
+// its reply from peer. The state is checked again since OptionReqRelaxed
+// may have been cleared while waiting for a peer.
+func (r *Req) startRequest(peer *socketutil.Peer, data []zmtp.Message) ([]zmtp.Message, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if err := r.checkCanSend(); err != nil {
+		return nil, err
+	}
+
+	r.requestID++
+	request := make([]zmtp.Message, 0, len(data)+2)
+	if r.correlate {
+		id := make([]byte, 4)
+		binary.BigEndian.PutUint32(id, r.requestID)
+		request = append(request, zmtp.Message{More: true, Body: id})
+	}
+	request = append(request, zmtp.Message{More: true})
+	request = append(request, data...)
+
+	r.awaitingReply = true
+	r.replyPeer = peer
+	return request, nil
+}
+
+// SendReady implements gomq.SendPoller.
+func (r *Req) SendReady() <-chan struct{} {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.awaitingReply && !r.relaxed {
+		return nil
+	}
+	return r.Peers.Ready()
+}
+
+// Recv the reply to the latest request, dropping anything sent by other
+// peers or, with OptionReqCorrelate, carrying another request's id.
+func (r *Req) Recv() ([]zmtp.Message, error) {
+	return r.RecvContext(context.Background())
+}
+
+// RecvContext implements gomq.SocketDriver.
+func (r *Req) RecvContext(ctx context.Context) ([]zmtp.Message, error) {
+	for {
+		r.lock.Lock()
+		awaitingReply := r.awaitingReply
+		r.lock.Unlock()
+		if !awaitingReply {
+			return nil, fmt.Errorf("%w: must send request before receiving", types.ErrInvalidState)
+		}
+
+		in, err := r.Queue.Receive(ctx, r.Context)
+		if err != nil {
+			return nil, err
+		}
+
+		if reply, ok := r.acceptReply(in); ok {
+			return reply, nil
+		}
+	}
+}
+
+// RecvReady implements gomq.RecvPoller. Replies which Recv would drop, from
+// other peers or for earlier requests, still count as ready.
+func (r *Req) RecvReady() <-chan struct{} {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !r.awaitingReply {
+		return nil
+	}
+	return r.Queue.Ready()
+}
+
+// QueueDepths implements gomq.QueueReporter.
+func (r *Req) QueueDepths() (send, recv gomq.QueueDepth) {
+	return gomq.QueueDepth{}, r.Queue.Depth()
+}
+
+// acceptReply checks the message answers the latest request, which may have
+// changed while waiting with OptionReqRelaxed set.
+func (r *Req) acceptReply(in socketutil.PeerMessage) ([]zmtp.Message, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !r.awaitingReply || in.Peer != r.replyPeer {
+		return nil, false
+	}
+
+	reply, ok := r.stripEnvelope(in.Message)
+	if !ok {
+		return nil, false
+	}
+
+	r.awaitingReply = false
+	r.replyPeer = nil
+	return reply, true
+}
+
+func (r *Req) stripEnvelope(msg []zmtp.Message) ([]zmtp.Message, bool) {
+	if r.correlate {
+		if len(msg) == 0 || len(msg[0].Body) != 4 {
+			return nil, false
+		}
+
+		id := make([]byte, 4)
+		binary.BigEndian.PutUint32(id, r.requestID)
+		if !bytes.Equal(msg[0].Body, id) {
+			return nil, false
+		}
+		msg = msg[1:]
+	}
+
+	if len(msg) < 2 || len(msg[0].Body) != 0 {
+		return nil, false
+	}
+
+	return msg[1:], true
+}
+
+func (r *Req) Close() error {
+	r.Cancel()
+	for _, conn := range r.ConnectionDrivers {
+		conn.Close()
+	}
+	for _, bind := range r.BindDrivers {
+		bind.Close()
+	}
+	return nil
+}
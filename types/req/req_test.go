@@ -0,0 +1,99 @@
+package req_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	_ "github.com/workspace-9/gomq/transport/tcp"
+	"github.com/workspace-9/gomq/types"
+	_ "github.com/workspace-9/gomq/types/rep"
+	_ "github.com/workspace-9/gomq/types/req"
+	_ "github.com/workspace-9/gomq/zmtp/null"
+)
+
+// within fails the test unless f returns within a second.
+func within(t *testing.T, what string, f func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("%s blocked", what)
+	}
+}
+
+// TestSendWaitingForPeer checks that a Send waiting for a peer does not hold
+// up polling, options or Recv.
+func TestSendWaitingForPeer(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	req, err := ctx.NewSocket("REQ", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer req.Close()
+	events := req.Monitor(gomq.Events(gomq.EventTypeListening))
+	if err := req.Bind("tcp://127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	addr := (<-events).LocalAddr
+
+	sent := make(chan error, 1)
+	go func() {
+		sent <- req.Send([][]byte{[]byte("ping")})
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	within(t, "Poller.Wait", func() {
+		poller := gomq.NewPoller()
+		poller.Add(req, gomq.PollOut)
+		poller.Wait(10 * time.Millisecond)
+	})
+	within(t, "SetOption", func() {
+		if err := req.SetOption(gomq.OptionReqCorrelate, true); err != nil {
+			t.Error(err)
+		}
+	})
+	within(t, "Recv", func() {
+		if _, err := req.Recv(); !errors.Is(err, types.ErrInvalidState) {
+			t.Errorf("expected ErrInvalidState, got %v", err)
+		}
+	})
+
+	rep, err := ctx.NewSocket("REP", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rep.Close()
+	if err := rep.Connect(addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-sent; err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := rep.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rep.Send([][]byte{[]byte("pong")}); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := req.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(request) != 1 || !bytes.Equal(request[0], []byte("ping")) || len(reply) != 1 || !bytes.Equal(reply[0], []byte("pong")) {
+		t.Fatalf("expected ping and pong, got %q and %q", request, reply)
+	}
+}
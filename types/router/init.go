@@ -0,0 +1,31 @@
+package router
+
+import (
+	"context"
+	"math/rand"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+func init() {
+	gomq.RegisterSocketType(
+		"ROUTER",
+		func(ctx context.Context, mech zmtp.Mechanism, conf *gomq.Config, eventBus gomq.EventBus) (gomq.SocketDriver, error) {
+			derived, cancel := context.WithCancel(ctx)
+			return &Router{
+				Context:           derived,
+				Cancel:            cancel,
+				Config:            conf,
+				Mech:              mech,
+				ConnectionDrivers: map[string]*socketutil.ConnectionDriver{},
+				BindDrivers:       map[string]*socketutil.BindDriver{},
+				EventBus:          eventBus,
+				Peers:             map[string]*socketutil.Peer{},
+				Outboxes:          map[string]*socketutil.Outbox{},
+				nextID:            rand.Uint32(),
+			}, nil
+		},
+	)
+}
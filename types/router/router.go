@@ -0,0 +1,361 @@
+package router
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/types"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// Router implements the zmq router socket.
+type Router struct {
+	context.Context
+	Cancel context.CancelFunc
+	*gomq.Config
+	Mech              zmtp.Mechanism
+	ConnectionDrivers map[string]*socketutil.ConnectionDriver
+	BindDrivers       map[string]*socketutil.BindDriver
+	EventBus          gomq.EventBus
+
+	// Queue takes messages from the inboxes of peers in turn.
+	Queue socketutil.FairQueue[socketutil.PeerMessage]
+
+	// Peers maps routing ids to connected peers.
+	Peers map[string]*socketutil.Peer
+
+	// Outboxes maps routing ids to the outboxes of connected peers.
+	Outboxes map[string]*socketutil.Outbox
+
+	// Outgoing holds the outbox of every peer. Messages are addressed to
+	// one outbox rather than dealt across them.
+	Outgoing socketutil.LoadBalancer
+
+	// Backlog counts messages accepted by Send which are yet to be written.
+	Backlog socketutil.Backlog
+
+	lock             sync.Mutex
+	routingID        []byte
+	connectRoutingID []byte
+	mandatory        bool
+	handover         bool
+	nextID           uint32
+}
+
+func (r *Router) Name() string {
+	return "ROUTER"
+}
+
+// Connect to the address. If OptionConnectRoutingID is set, the peer is
+// given that routing id and the option is cleared.
+func (r *Router) Connect(tp transport.Transport, url *url.URL) error {
+	if _, ok := r.ConnectionDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyConnected, url)
+	}
+
+	r.lock.Lock()
+	routingID := r.connectRoutingID
+	r.connectRoutingID = nil
+	r.lock.Unlock()
+
+	driver := &socketutil.ConnectionDriver{}
+	driver.Setup(
+		r.Context,
+		r.Mech,
+		tp,
+		url,
+		r.Config,
+		r.EventBus,
+		func(ctx context.Context, s *socketutil.Peer) error {
+			return r.handleSock(ctx, s, routingID)
+		},
+		r.Meta,
+		r.MetaHandler,
+	)
+	fatal, err := driver.TryConnect()
+	if err != nil && fatal {
+		return err
+	}
+	r.ConnectionDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (r *Router) Disconnect(url *url.URL) error {
+	driver, ok := r.ConnectionDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverConnected, url)
+	}
+
+	delete(r.ConnectionDrivers, url.String())
+	return driver.Close()
+}
+
+func (r *Router) Bind(tp transport.Transport, url *url.URL) error {
+	if _, ok := r.BindDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyBound, url)
+	}
+
+	driver := &socketutil.BindDriver{}
+	driver.Setup(
+		r.Context,
+		tp,
+		r.Mech,
+		url,
+		r.Config,
+		func(ctx context.Context, s *socketutil.Peer) error {
+			return r.handleSock(ctx, s, nil)
+		},
+		r.EventBus,
+		r.Meta,
+		r.MetaHandler,
+	)
+	if err := driver.TryBind(); err != nil {
+		return err
+	}
+	r.BindDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (r *Router) Unbind(url *url.URL) error {
+	driver, ok := r.BindDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverBound, url.String())
+	}
+
+	delete(r.BindDrivers, url.String())
+	return driver.Close()
+}
+
+// handleSock routes messages to and from the peer under its routing id for
+// as long as it remains connected. The id is routingID if given, otherwise
+// the Identity the peer sent during the handshake, otherwise a generated one.
+func (r *Router) handleSock(ctx context.Context, peer *socketutil.Peer, routingID []byte) error {
+	if routingID == nil {
+		if identity, ok := peer.Meta.Property("Identity"); ok && identity != "" {
+			routingID = []byte(identity)
+		}
+	}
+
+	out, err := r.attach(peer, routingID)
+	if err != nil {
+		return err
+	}
+	defer r.detach(peer, out)
+
+	in := r.Queue.Add(r.Config.RecvHWM())
+	derived, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go func() {
+		defer in.Close()
+		cancel(socketutil.ReadMessages(derived, peer, in))
+	}()
+
+	for {
+		msg, err := out.Receive(derived)
+		if err != nil {
+			return context.Cause(derived)
+		}
+
+		err = peer.SendMessages(msg)
+		r.Backlog.Done(1)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (r *Router) attach(peer *socketutil.Peer, routingID []byte) (*socketutil.Outbox, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if routingID == nil {
+		routingID = make([]byte, 5)
+		binary.BigEndian.PutUint32(routingID[1:], r.nextID)
+		r.nextID++
+	}
+
+	if existing, ok := r.Peers[string(routingID)]; ok {
+		if !r.handover {
+			return nil, fmt.Errorf("Routing id %x is already in use", routingID)
+		}
+		existing.Close()
+	}
+
+	out := r.Outgoing.Add(r.Config.SendHWM())
+	peer.RoutingID = routingID
+	r.Peers[string(routingID)] = peer
+	r.Outboxes[string(routingID)] = out
+	return out, nil
+}
+
+// detach frees the peer's routing id unless another peer has taken it over,
+// dropping anything still queued for the peer.
+func (r *Router) detach(peer *socketutil.Peer, out *socketutil.Outbox) {
+	r.lock.Lock()
+	if r.Peers[string(peer.RoutingID)] == peer {
+		delete(r.Peers, string(peer.RoutingID))
+		delete(r.Outboxes, string(peer.RoutingID))
+	}
+	r.lock.Unlock()
+	r.Backlog.Done(r.Outgoing.Remove(out))
+}
+
+func (r *Router) Meta() zmtp.Metadata {
+	meta := zmtp.Metadata{}
+	meta.AddProperty("Socket-Type", "ROUTER")
+	r.lock.Lock()
+	if r.routingID != nil {
+		meta.AddProperty("Identity", string(r.routingID))
+	}
+	r.lock.Unlock()
+	return meta
+}
+
+func (r *Router) MetaHandler(meta zmtp.Metadata) error {
+	var err error
+	meta.Properties(func(name string, value string) {
+		if name == "Socket-Type" && err == nil {
+			if value != "REQ" && value != "DEALER" && value != "ROUTER" {
+				err = fmt.Errorf("Expected req, dealer or router socket to connect, got %s", value)
+			}
+		}
+	})
+
+	return err
+}
+
+// SetOption implements gomq.OptionSetter.
+func (r *Router) SetOption(option string, val any) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	switch option {
+	case gomq.OptionRoutingID, gomq.OptionConnectRoutingID:
+		id, err := types.BytesOption(option, val)
+		if err != nil {
+			return err
+		}
+
+		if option == gomq.OptionRoutingID {
+			r.routingID = id
+		} else {
+			r.connectRoutingID = id
+		}
+	case gomq.OptionRouterMandatory, gomq.OptionRouterHandover:
+		on, err := types.BoolOption(option, val)
+		if err != nil {
+			return err
+		}
+
+		if option == gomq.OptionRouterMandatory {
+			r.mandatory = on
+		} else {
+			r.handover = on
+		}
+	default:
+		return gomq.ErrUnknownOption
+	}
+
+	return nil
+}
+
+// Send the message to the peer named by its first frame.
+func (r *Router) Send(data []zmtp.Message) error {
+	return r.SendContext(context.Background(), data)
+}
+
+// SendContext queues the message for the peer named by its first frame.
+// Like libzmq, messages for unknown peers or peers whose queue is full are
+// dropped. With OptionRouterMandatory set, unknown peers fail with
+// types.ErrHostUnreachable instead, and a full queue is waited on until ctx
+// is done, failing with types.ErrWouldBlock.
+func (r *Router) SendContext(ctx context.Context, data []zmtp.Message) error {
+	if len(data) < 2 {
+		return fmt.Errorf("%w: message must start with a routing id", types.ErrOperationNotPermitted)
+	}
+
+	r.lock.Lock()
+	out, ok := r.Outboxes[string(data[0].Body)]
+	mandatory := r.mandatory
+	r.lock.Unlock()
+
+	if !ok {
+		if mandatory {
+			return fmt.Errorf("%w: no peer with routing id %x", types.ErrHostUnreachable, data[0].Body)
+		}
+		return nil
+	}
+
+	r.Backlog.Add(1)
+	if !mandatory {
+		if !out.TrySend(data[1:]) {
+			r.Backlog.Done(1)
+		}
+		return nil
+	}
+
+	err := out.Send(ctx, r.Context, data[1:])
+	if err != nil {
+		r.Backlog.Done(1)
+	}
+	if errors.Is(err, socketutil.ErrOutboxRemoved) {
+		return fmt.Errorf("%w: peer with routing id %x disconnected", types.ErrHostUnreachable, data[0].Body)
+	}
+	return err
+}
+
+// Recv the next message from any peer, prefixed by the peer's routing id.
+func (r *Router) Recv() ([]zmtp.Message, error) {
+	return r.RecvContext(context.Background())
+}
+
+// RecvContext implements gomq.SocketDriver.
+func (r *Router) RecvContext(ctx context.Context) ([]zmtp.Message, error) {
+	in, err := r.Queue.Receive(ctx, r.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := make([]zmtp.Message, 0, len(in.Message)+1)
+	msg = append(msg, zmtp.Message{More: true, Body: in.Peer.RoutingID})
+	msg = append(msg, in.Message...)
+	return msg, nil
+}
+
+// RecvReady implements gomq.RecvPoller.
+func (r *Router) RecvReady() <-chan struct{} {
+	return r.Queue.Ready()
+}
+
+// QueueDepths implements gomq.QueueReporter.
+func (r *Router) QueueDepths() (send, recv gomq.QueueDepth) {
+	return r.Outgoing.Depth(), r.Queue.Depth()
+}
+
+func (r *Router) Close() error {
+	return r.CloseContext(context.Background())
+}
+
+// CloseContext implements gomq.GracefulCloser, waiting up to the linger
+// period for queued messages to be written before tearing down.
+func (r *Router) CloseContext(ctx context.Context) error {
+	discarded := r.Backlog.Linger(ctx, r.Config.Linger())
+	r.Cancel()
+	for _, conn := range r.ConnectionDrivers {
+		conn.Close()
+	}
+	for _, bind := range r.BindDrivers {
+		bind.Close()
+	}
+	socketutil.PostDiscarded(r.EventBus, discarded)
+	return nil
+}
@@ -0,0 +1,147 @@
+package router_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	_ "github.com/workspace-9/gomq/transport/inproc"
+	"github.com/workspace-9/gomq/types"
+	_ "github.com/workspace-9/gomq/types/dealer"
+	_ "github.com/workspace-9/gomq/types/router"
+	_ "github.com/workspace-9/gomq/zmtp/null"
+)
+
+// newSocket makes a socket closed at the end of the test, whose receives
+// time out.
+func newSocket(t *testing.T, ctx *gomq.Context, typ string) *gomq.Socket {
+	t.Helper()
+	sock, err := ctx.NewSocket(typ, "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sock.Close() })
+	sock.SetOption(gomq.OptionRecvTimeout, 5*time.Second)
+	return sock
+}
+
+// TestRouteByIdentity checks that messages are prefixed with the identity
+// of the peer they came from, and that replies addressed by identity reach
+// only that peer.
+func TestRouteByIdentity(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	router := newSocket(t, ctx, "ROUTER")
+	if err := router.Bind("inproc://route"); err != nil {
+		t.Fatal(err)
+	}
+
+	dealers := map[string]*gomq.Socket{}
+	for _, name := range []string{"a", "b"} {
+		dealer := newSocket(t, ctx, "DEALER")
+		if err := dealer.SetOption(gomq.OptionRoutingID, name); err != nil {
+			t.Fatal(err)
+		}
+		if err := dealer.Connect("inproc://route"); err != nil {
+			t.Fatal(err)
+		}
+		if err := dealer.Send([][]byte{[]byte("from " + name)}); err != nil {
+			t.Fatal(err)
+		}
+		dealers[name] = dealer
+	}
+
+	for range dealers {
+		msg, err := router.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(msg) != 2 || string(msg[1]) != "from "+string(msg[0]) {
+			t.Fatalf("unexpected message %q", msg)
+		}
+	}
+
+	for name := range dealers {
+		if err := router.Send([][]byte{[]byte(name), []byte("to " + name)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, dealer := range dealers {
+		msg, err := dealer.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(msg) != 1 || string(msg[0]) != "to "+name {
+			t.Fatalf("expected %q, got %q", "to "+name, msg)
+		}
+	}
+
+	if err := router.Send([][]byte{[]byte("c"), []byte("lost")}); err != nil {
+		t.Fatalf("expected messages for unknown peers to be dropped, got %v", err)
+	}
+	router.SetOption(gomq.OptionRouterMandatory, true)
+	if err := router.Send([][]byte{[]byte("c"), []byte("lost")}); !errors.Is(err, types.ErrHostUnreachable) {
+		t.Fatalf("expected ErrHostUnreachable, got %v", err)
+	}
+}
+
+// TestFullQueue checks that sends to a peer which is not receiving never
+// wait, dropping messages once its queue is full, unless the router is
+// mandatory, when they fail with ErrWouldBlock instead.
+func TestFullQueue(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	router := newSocket(t, ctx, "ROUTER")
+	router.SetOption(gomq.OptionSendHWM, 1)
+	if err := router.Bind("inproc://full"); err != nil {
+		t.Fatal(err)
+	}
+	dealer := newSocket(t, ctx, "DEALER")
+	dealer.SetOption(gomq.OptionRoutingID, "slow")
+	dealer.SetOption(gomq.OptionRecvHWM, 1)
+	if err := dealer.Connect("inproc://full"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dealer.Send([][]byte{[]byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := router.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Large messages fill the connection's buffer quickly.
+	body := make([]byte, 16*1024)
+	done := make(chan error, 1)
+	go func() {
+		for idx := 0; idx < 100; idx++ {
+			if err := router.Send([][]byte{[]byte("slow"), body}); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected sends to a full queue to drop rather than wait")
+	}
+
+	router.SetOption(gomq.OptionRouterMandatory, true)
+	for idx := 0; ; idx++ {
+		err := router.Send([][]byte{[]byte("slow"), body}, gomq.DontWait)
+		if errors.Is(err, types.ErrWouldBlock) {
+			break
+		}
+		if err != nil || idx == 100 {
+			t.Fatalf("expected ErrWouldBlock, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package scatter
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/types"
+	"github.com/workspace-9/gomq/types/push"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// Scatter implements the zmq scatter socket, which deals messages of one
+// frame across its GATHER peers like PUSH. Send may be called from many
+// goroutines at once.
+type Scatter struct {
+	context.Context
+	Cancel context.CancelFunc
+	*gomq.Config
+	Mech              zmtp.Mechanism
+	ConnectionDrivers map[string]*socketutil.ConnectionDriver
+	BindDrivers       map[string]*socketutil.BindDriver
+	Outboxes          map[string]*socketutil.Outbox
+	EventBus          gomq.EventBus
+
+	// Balancer deals messages across the outboxes of connected peers, and
+	// of connecting peers so that messages queue while they reconnect.
+	Balancer socketutil.LoadBalancer
+
+	// Backlog counts messages accepted by Send which are yet to be written.
+	Backlog socketutil.Backlog
+}
+
+func (s *Scatter) Name() string {
+	return "SCATTER"
+}
+
+func (s *Scatter) Connect(tp transport.Transport, url *url.URL) error {
+	if _, ok := s.Outboxes[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyConnected, url.String())
+	}
+
+	var out *socketutil.Outbox
+	driver := &socketutil.ConnectionDriver{}
+	driver.Setup(
+		s.Context,
+		s.Mech,
+		tp,
+		url,
+		s.Config,
+		s.EventBus,
+		func(ctx context.Context, peer *socketutil.Peer) error {
+			return push.HandleSock(ctx, peer, out, &s.Backlog)
+		},
+		s.Meta,
+		s.MetaHandler,
+	)
+	fatal, err := driver.TryConnect()
+	if err != nil && fatal {
+		return err
+	}
+	out = s.Balancer.Add(s.Config.SendHWM())
+	s.ConnectionDrivers[url.String()] = driver
+	s.Outboxes[url.String()] = out
+	go driver.Run()
+	return nil
+}
+
+func (s *Scatter) Disconnect(url *url.URL) error {
+	driver, ok := s.ConnectionDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverConnected, url.String())
+	}
+
+	delete(s.ConnectionDrivers, url.String())
+	err := driver.Close()
+	s.Backlog.Done(s.Balancer.Remove(s.Outboxes[url.String()]))
+	delete(s.Outboxes, url.String())
+	return err
+}
+
+func (s *Scatter) Bind(tp transport.Transport, url *url.URL) error {
+	if _, ok := s.BindDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyBound, url)
+	}
+
+	driver := &socketutil.BindDriver{}
+	driver.Setup(
+		s.Context,
+		tp,
+		s.Mech,
+		url,
+		s.Config,
+		func(ctx context.Context, peer *socketutil.Peer) error {
+			out := s.Balancer.Add(s.Config.SendHWM())
+			err := push.HandleSock(ctx, peer, out, &s.Backlog)
+			s.Backlog.Done(s.Balancer.Remove(out))
+			return err
+		},
+		s.EventBus,
+		s.Meta,
+		s.MetaHandler,
+	)
+	if err := driver.TryBind(); err != nil {
+		return err
+	}
+	s.BindDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (s *Scatter) Unbind(url *url.URL) error {
+	driver, ok := s.BindDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverBound, url.String())
+	}
+
+	delete(s.BindDrivers, url.String())
+	err := driver.Close()
+	return err
+}
+
+func (s *Scatter) Meta() zmtp.Metadata {
+	meta := zmtp.Metadata{}
+	meta.AddProperty("Socket-Type", "SCATTER")
+	return meta
+}
+
+func (s *Scatter) MetaHandler(meta zmtp.Metadata) error {
+	var err error
+	meta.Properties(func(name string, value string) {
+		if name == "Socket-Type" && err == nil {
+			if value != "GATHER" {
+				err = fmt.Errorf("Expected gather socket to connect, got %s", value)
+			}
+		}
+	})
+
+	return err
+}
+
+func (s *Scatter) Send(data []zmtp.Message) error {
+	return s.SendContext(context.Background(), data)
+}
+
+// SendContext deals the message of one frame to the next peer in turn with
+// room in its queue, failing with types.ErrWouldBlock if ctx is done before
+// one has room.
+func (s *Scatter) SendContext(ctx context.Context, data []zmtp.Message) error {
+	if err := socketutil.CheckSingleFrame(data); err != nil {
+		return err
+	}
+
+	s.Backlog.Add(1)
+	err := s.Balancer.Send(ctx, s.Context, data)
+	if err != nil {
+		s.Backlog.Done(1)
+	}
+	return err
+}
+
+// SendReady implements gomq.SendPoller.
+func (s *Scatter) SendReady() <-chan struct{} {
+	return s.Balancer.Ready()
+}
+
+// QueueDepths implements gomq.QueueReporter.
+func (s *Scatter) QueueDepths() (send, recv gomq.QueueDepth) {
+	return s.Balancer.Depth(), gomq.QueueDepth{}
+}
+
+func (s *Scatter) Recv() ([]zmtp.Message, error) {
+	return nil, types.ErrOperationNotPermitted
+}
+
+func (s *Scatter) RecvContext(context.Context) ([]zmtp.Message, error) {
+	return nil, types.ErrOperationNotPermitted
+}
+
+func (s *Scatter) Close() error {
+	return s.CloseContext(context.Background())
+}
+
+// CloseContext implements gomq.GracefulCloser, waiting up to the linger
+// period for queued messages to be written before tearing down.
+func (s *Scatter) CloseContext(ctx context.Context) error {
+	discarded := s.Backlog.Linger(ctx, s.Config.Linger())
+	s.Cancel()
+	for url, conn := range s.ConnectionDrivers {
+		conn.Close()
+		delete(s.ConnectionDrivers, url)
+	}
+	for url, out := range s.Outboxes {
+		s.Balancer.Remove(out)
+		delete(s.Outboxes, url)
+	}
+	for url, conn := range s.BindDrivers {
+		conn.Close()
+		delete(s.BindDrivers, url)
+	}
+	socketutil.PostDiscarded(s.EventBus, discarded)
+	return nil
+}
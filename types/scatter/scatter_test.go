@@ -0,0 +1,107 @@
+package scatter_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	_ "github.com/workspace-9/gomq/transport/inproc"
+	"github.com/workspace-9/gomq/types"
+	_ "github.com/workspace-9/gomq/types/gather"
+	_ "github.com/workspace-9/gomq/types/scatter"
+	_ "github.com/workspace-9/gomq/zmtp/null"
+)
+
+// newSocket makes a socket closed at the end of the test, whose receives
+// time out.
+func newSocket(t *testing.T, ctx *gomq.Context, typ string) *gomq.Socket {
+	t.Helper()
+	sock, err := ctx.NewSocket(typ, "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sock.Close() })
+	sock.SetOption(gomq.OptionRecvTimeout, 5*time.Second)
+	return sock
+}
+
+// TestBindTwice checks that binding an address twice fails.
+func TestBindTwice(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	scatter := newSocket(t, ctx, "SCATTER")
+	if err := scatter.Bind("inproc://twice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := scatter.Bind("inproc://twice"); !errors.Is(err, types.ErrAlreadyBound) {
+		t.Fatalf("expected ErrAlreadyBound, got %v", err)
+	}
+}
+
+// TestConcurrentSendRecv checks that every message sent from many
+// goroutines at once is received exactly once by many goroutines at once.
+func TestConcurrentSendRecv(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	scatter := newSocket(t, ctx, "SCATTER")
+	if err := scatter.Bind("inproc://concurrent"); err != nil {
+		t.Fatal(err)
+	}
+	gather := newSocket(t, ctx, "GATHER")
+	if err := gather.Connect("inproc://concurrent"); err != nil {
+		t.Fatal(err)
+	}
+
+	const senders, perSender, receivers = 8, 100, 4
+	var sending sync.WaitGroup
+	for sender := 0; sender < senders; sender++ {
+		sending.Add(1)
+		go func(sender int) {
+			defer sending.Done()
+			for idx := 0; idx < perSender; idx++ {
+				body := fmt.Sprintf("%d-%d", sender, idx)
+				if err := scatter.Send([][]byte{[]byte(body)}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(sender)
+	}
+
+	var lock sync.Mutex
+	seen := map[string]int{}
+	var receiving sync.WaitGroup
+	for receiver := 0; receiver < receivers; receiver++ {
+		receiving.Add(1)
+		go func() {
+			defer receiving.Done()
+			for idx := 0; idx < senders*perSender/receivers; idx++ {
+				msg, err := gather.Recv()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				lock.Lock()
+				seen[string(msg[0])]++
+				lock.Unlock()
+			}
+		}()
+	}
+
+	sending.Wait()
+	receiving.Wait()
+	if len(seen) != senders*perSender {
+		t.Fatalf("expected %d distinct messages, got %d", senders*perSender, len(seen))
+	}
+	for body, count := range seen {
+		if count != 1 {
+			t.Fatalf("expected %q once, got it %d times", body, count)
+		}
+	}
+}
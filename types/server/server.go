@@ -0,0 +1,260 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/types"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// Server implements the zmq server socket, which receives messages of one
+// frame stamped with the routing id of the CLIENT peer they came from, and
+// replies to a peer by its routing id.
+type Server struct {
+	context.Context
+	Cancel context.CancelFunc
+	*gomq.Config
+	Mech              zmtp.Mechanism
+	ConnectionDrivers map[string]*socketutil.ConnectionDriver
+	BindDrivers       map[string]*socketutil.BindDriver
+	EventBus          gomq.EventBus
+
+	// Peers maps routing ids to the outboxes of connected peers.
+	Peers  map[uint32]*socketutil.Outbox
+	lock   sync.Mutex
+	nextID uint32
+
+	// Outgoing holds the outbox of every peer. Messages are addressed to
+	// one outbox rather than dealt across them.
+	Outgoing socketutil.LoadBalancer
+
+	// Backlog counts messages accepted by Send which are yet to be written.
+	Backlog socketutil.Backlog
+
+	// Queue takes messages from the inboxes of peers in turn.
+	Queue socketutil.FairQueue[[]zmtp.Message]
+}
+
+func (s *Server) Name() string {
+	return "SERVER"
+}
+
+func (s *Server) Connect(tp transport.Transport, url *url.URL) error {
+	if _, ok := s.ConnectionDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyConnected, url)
+	}
+
+	driver := &socketutil.ConnectionDriver{}
+	driver.Setup(
+		s.Context,
+		s.Mech,
+		tp,
+		url,
+		s.Config,
+		s.EventBus,
+		s.HandleSock,
+		s.Meta,
+		s.MetaHandler,
+	)
+	fatal, err := driver.TryConnect()
+	if err != nil && fatal {
+		return err
+	}
+	s.ConnectionDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (s *Server) Disconnect(url *url.URL) error {
+	driver, ok := s.ConnectionDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverConnected, url)
+	}
+
+	delete(s.ConnectionDrivers, url.String())
+	return driver.Close()
+}
+
+func (s *Server) Bind(tp transport.Transport, url *url.URL) error {
+	if _, ok := s.BindDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyBound, url)
+	}
+
+	driver := &socketutil.BindDriver{}
+	driver.Setup(
+		s.Context,
+		tp,
+		s.Mech,
+		url,
+		s.Config,
+		s.HandleSock,
+		s.EventBus,
+		s.Meta,
+		s.MetaHandler,
+	)
+	if err := driver.TryBind(); err != nil {
+		return err
+	}
+	s.BindDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (s *Server) Unbind(url *url.URL) error {
+	driver, ok := s.BindDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverBound, url.String())
+	}
+
+	delete(s.BindDrivers, url.String())
+	return driver.Close()
+}
+
+// HandleSock gives the peer a routing id, writes messages addressed to it
+// from its outbox and queues the messages it sends in an inbox of its own,
+// until either fails or ctx is done.
+func (s *Server) HandleSock(ctx context.Context, peer *socketutil.Peer) error {
+	id, out := s.attach()
+	defer s.detach(id, out)
+
+	in := s.Queue.Add(s.Config.RecvHWM())
+	derived, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go func() {
+		defer in.Close()
+		cancel(socketutil.ReadSingleFrames(derived, peer, in, id))
+	}()
+
+	for {
+		msg, err := out.Receive(derived)
+		if err != nil {
+			return context.Cause(derived)
+		}
+
+		err = peer.SendMessage(msg[0])
+		s.Backlog.Done(1)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// attach gives a new outbox the next unused routing id, which is never zero.
+func (s *Server) attach() (uint32, *socketutil.Outbox) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for {
+		s.nextID++
+		if _, used := s.Peers[s.nextID]; s.nextID != 0 && !used {
+			break
+		}
+	}
+
+	out := s.Outgoing.Add(s.Config.SendHWM())
+	s.Peers[s.nextID] = out
+	return s.nextID, out
+}
+
+// detach frees the routing id, dropping anything still queued for the peer.
+func (s *Server) detach(id uint32, out *socketutil.Outbox) {
+	s.lock.Lock()
+	delete(s.Peers, id)
+	s.lock.Unlock()
+	s.Backlog.Done(s.Outgoing.Remove(out))
+}
+
+func (s *Server) Meta() zmtp.Metadata {
+	meta := zmtp.Metadata{}
+	meta.AddProperty("Socket-Type", "SERVER")
+	return meta
+}
+
+func (s *Server) MetaHandler(meta zmtp.Metadata) error {
+	var err error
+	meta.Properties(func(name string, value string) {
+		if name == "Socket-Type" && err == nil {
+			if value != "CLIENT" {
+				err = fmt.Errorf("Expected client socket to connect, got %s", value)
+			}
+		}
+	})
+
+	return err
+}
+
+func (s *Server) Send(data []zmtp.Message) error {
+	return s.SendContext(context.Background(), data)
+}
+
+// SendContext queues the message of one frame for the peer with its routing
+// id, failing with types.ErrHostUnreachable if there is none and with
+// types.ErrWouldBlock if ctx is done before its queue has room.
+func (s *Server) SendContext(ctx context.Context, data []zmtp.Message) error {
+	if err := socketutil.CheckSingleFrame(data); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	out, ok := s.Peers[data[0].RoutingID]
+	s.lock.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: no peer with routing id %d", types.ErrHostUnreachable, data[0].RoutingID)
+	}
+
+	s.Backlog.Add(1)
+	err := out.Send(ctx, s.Context, []zmtp.Message{{Body: data[0].Body}})
+	if err != nil {
+		s.Backlog.Done(1)
+	}
+	if errors.Is(err, socketutil.ErrOutboxRemoved) {
+		return fmt.Errorf("%w: peer with routing id %d disconnected", types.ErrHostUnreachable, data[0].RoutingID)
+	}
+	return err
+}
+
+func (s *Server) Recv() ([]zmtp.Message, error) {
+	return s.RecvContext(context.Background())
+}
+
+// RecvContext receives the next message from the peers in turn, failing
+// with types.ErrWouldBlock if ctx is done first.
+func (s *Server) RecvContext(ctx context.Context) ([]zmtp.Message, error) {
+	return s.Queue.Receive(ctx, s.Context)
+}
+
+// RecvReady implements gomq.RecvPoller.
+func (s *Server) RecvReady() <-chan struct{} {
+	return s.Queue.Ready()
+}
+
+// QueueDepths implements gomq.QueueReporter.
+func (s *Server) QueueDepths() (send, recv gomq.QueueDepth) {
+	return s.Outgoing.Depth(), s.Queue.Depth()
+}
+
+func (s *Server) Close() error {
+	return s.CloseContext(context.Background())
+}
+
+// CloseContext implements gomq.GracefulCloser, waiting up to the linger
+// period for queued messages to be written before tearing down.
+func (s *Server) CloseContext(ctx context.Context) error {
+	discarded := s.Backlog.Linger(ctx, s.Config.Linger())
+	s.Cancel()
+	for _, conn := range s.ConnectionDrivers {
+		conn.Close()
+	}
+	for _, bind := range s.BindDrivers {
+		bind.Close()
+	}
+	socketutil.PostDiscarded(s.EventBus, discarded)
+	return nil
+}
@@ -0,0 +1,220 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	_ "github.com/workspace-9/gomq/transport/inproc"
+	"github.com/workspace-9/gomq/types"
+	_ "github.com/workspace-9/gomq/types/client"
+	_ "github.com/workspace-9/gomq/types/server"
+	_ "github.com/workspace-9/gomq/zmtp/null"
+)
+
+// newSocket makes a socket closed at the end of the test, whose receives
+// time out.
+func newSocket(t *testing.T, ctx *gomq.Context, typ string) *gomq.Socket {
+	t.Helper()
+	sock, err := ctx.NewSocket(typ, "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sock.Close() })
+	sock.SetOption(gomq.OptionRecvTimeout, 5*time.Second)
+	return sock
+}
+
+// TestReplyByRoutingID checks that the SERVER tells its clients apart by
+// the routing ids on the messages it receives, and that replies sent with
+// a routing id reach only that client.
+func TestReplyByRoutingID(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	server := newSocket(t, ctx, "SERVER")
+	if err := server.Bind("inproc://server"); err != nil {
+		t.Fatal(err)
+	}
+
+	clients := map[string]*gomq.Socket{}
+	for _, name := range []string{"a", "b"} {
+		client := newSocket(t, ctx, "CLIENT")
+		if err := client.Connect("inproc://server"); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.SendMsg(gomq.Message{Body: []byte(name)}); err != nil {
+			t.Fatal(err)
+		}
+		clients[name] = client
+	}
+
+	ids := map[string]uint32{}
+	for range clients {
+		msg, err := server.RecvMsg()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.RoutingID == 0 {
+			t.Fatalf("expected a routing id on %q", msg.Body)
+		}
+		ids[string(msg.Body)] = msg.RoutingID
+	}
+	if len(ids) != 2 || ids["a"] == ids["b"] {
+		t.Fatalf("expected distinct routing ids for a and b, got %v", ids)
+	}
+
+	for name, id := range ids {
+		if err := server.SendMsg(gomq.Message{Body: []byte("to " + name), RoutingID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, client := range clients {
+		msg, err := client.RecvMsg()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(msg.Body) != "to "+name {
+			t.Fatalf("expected %q, got %q", "to "+name, msg.Body)
+		}
+	}
+
+	unknown := ids["a"] + ids["b"]
+	if err := server.SendMsg(gomq.Message{Body: []byte("lost"), RoutingID: unknown}); !errors.Is(err, types.ErrHostUnreachable) {
+		t.Fatalf("expected ErrHostUnreachable, got %v", err)
+	}
+}
+
+// TestRejectMultipart checks that neither end sends multipart messages.
+func TestRejectMultipart(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	for _, typ := range []string{"CLIENT", "SERVER"} {
+		sock := newSocket(t, ctx, typ)
+		if err := sock.Send([][]byte{[]byte("a"), []byte("b")}); !errors.Is(err, types.ErrMultipartUnsupported) {
+			t.Fatalf("%s: expected ErrMultipartUnsupported, got %v", typ, err)
+		}
+	}
+}
+
+// TestConcurrentUse checks that a CLIENT and a SERVER may be used from
+// several goroutines at once.
+func TestConcurrentUse(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	server := newSocket(t, ctx, "SERVER")
+	if err := server.Bind("inproc://concurrent"); err != nil {
+		t.Fatal(err)
+	}
+	client := newSocket(t, ctx, "CLIENT")
+	if err := client.Connect("inproc://concurrent"); err != nil {
+		t.Fatal(err)
+	}
+
+	const senders, each = 4, 25
+	var wg sync.WaitGroup
+	for sender := 0; sender < senders; sender++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := 0; idx < each; idx++ {
+				if err := client.SendMsg(gomq.Message{Body: []byte("ping")}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	replies := make(chan error, senders*each)
+	for receiver := 0; receiver < senders; receiver++ {
+		go func() {
+			for idx := 0; idx < each; idx++ {
+				msg, err := server.RecvMsg()
+				if err == nil {
+					err = server.SendMsg(gomq.Message{Body: []byte("pong"), RoutingID: msg.RoutingID})
+				}
+				replies <- err
+			}
+		}()
+	}
+
+	for idx := 0; idx < senders*each; idx++ {
+		if err := <-replies; err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.RecvMsg(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+}
+
+// TestSendHWM checks that messages for a client which is not receiving
+// queue up to SNDHWM, after which sends fail rather than wait when asked
+// not to, and that nothing queued is lost.
+func TestSendHWM(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	server := newSocket(t, ctx, "SERVER")
+	if err := server.SetOption(gomq.OptionSendHWM, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Bind("inproc://sendhwm"); err != nil {
+		t.Fatal(err)
+	}
+	client := newSocket(t, ctx, "CLIENT")
+	if err := client.SetOption(gomq.OptionRecvHWM, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Connect("inproc://sendhwm"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SendMsg(gomq.Message{Body: []byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+	hello, err := server.RecvMsg()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Large messages fill the connection's buffer quickly.
+	body := make([]byte, 16*1024)
+	sent := 0
+	for ; sent < 1000; sent++ {
+		err := server.SendMsg(gomq.Message{Body: body, RoutingID: hello.RoutingID}, gomq.DontWait)
+		if errors.Is(err, types.ErrWouldBlock) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sent == 1000 {
+		t.Fatal("expected sends to fail once the queue was full")
+	}
+
+	var capacity float64
+	server.Collect(func(sample gomq.Sample) {
+		for _, label := range sample.Labels {
+			if sample.Name == "gomq_queue_capacity" && label == (gomq.Label{Name: "direction", Value: "send"}) {
+				capacity = sample.Value
+			}
+		}
+	})
+	if capacity != 2 {
+		t.Fatalf("expected a send queue capacity of 2, got %v", capacity)
+	}
+
+	for idx := 0; idx < sent; idx++ {
+		if _, err := client.RecvMsg(); err != nil {
+			t.Fatalf("recv %d of %d: %v", idx, sent, err)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package sub
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/types"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// Sub implements the zmq sub socket.
+type Sub struct {
+	context.Context
+	Cancel context.CancelFunc
+	*gomq.Config
+	Mech              zmtp.Mechanism
+	ConnectionDrivers map[string]*socketutil.ConnectionDriver
+	BindDrivers       map[string]*socketutil.BindDriver
+	EventBus          gomq.EventBus
+	Publishers        socketutil.Publishers
+
+	// Queue takes messages from the inboxes of publishers in turn.
+	Queue socketutil.FairQueue[[]zmtp.Message]
+}
+
+func (s *Sub) Name() string {
+	return "SUB"
+}
+
+func (s *Sub) Connect(tp transport.Transport, url *url.URL) error {
+	if _, ok := s.ConnectionDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyConnected, url)
+	}
+
+	driver := &socketutil.ConnectionDriver{}
+	driver.Setup(
+		s.Context,
+		s.Mech,
+		tp,
+		url,
+		s.Config,
+		s.EventBus,
+		s.HandleSock,
+		s.Meta,
+		s.MetaHandler,
+	)
+	fatal, err := driver.TryConnect()
+	if err != nil && fatal {
+		return err
+	}
+	s.ConnectionDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (s *Sub) Disconnect(url *url.URL) error {
+	driver, ok := s.ConnectionDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverConnected, url)
+	}
+
+	delete(s.ConnectionDrivers, url.String())
+	return driver.Close()
+}
+
+func (s *Sub) Bind(tp transport.Transport, url *url.URL) error {
+	if _, ok := s.BindDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyBound, url)
+	}
+
+	driver := &socketutil.BindDriver{}
+	driver.Setup(
+		s.Context,
+		tp,
+		s.Mech,
+		url,
+		s.Config,
+		s.HandleSock,
+		s.EventBus,
+		s.Meta,
+		s.MetaHandler,
+	)
+	if err := driver.TryBind(); err != nil {
+		return err
+	}
+	s.BindDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (s *Sub) Unbind(url *url.URL) error {
+	driver, ok := s.BindDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverBound, url.String())
+	}
+
+	delete(s.BindDrivers, url.String())
+	return driver.Close()
+}
+
+// HandleSock sends the peer every current subscription, which also replays
+// them after a reconnect, then receives matching messages from it.
+func (s *Sub) HandleSock(ctx context.Context, peer *socketutil.Peer) error {
+	if err := s.Publishers.Attach(peer); err != nil {
+		return err
+	}
+	defer s.Publishers.Detach(peer)
+
+	in := s.Queue.Add(s.Config.RecvHWM())
+	defer in.Close()
+	return ReadMessages(ctx, peer, &s.Publishers.Subscriptions, in)
+}
+
+// ReadMessages pushes each complete message from the socket whose first
+// frame matches the subscriptions into in.
+func ReadMessages(
+	ctx context.Context,
+	sock zmtp.Socket,
+	subs *socketutil.Subscriptions,
+	in *socketutil.Inbox[[]zmtp.Message],
+) error {
+	built := make([]zmtp.Message, 0)
+	for {
+		next, err := sock.Read()
+		if err != nil {
+			return err
+		}
+
+		if !next.IsMessage {
+			continue
+		}
+
+		built = append(built, *next.Message)
+		if next.Message.More {
+			continue
+		}
+
+		if subs.Match(built[0].Body) {
+			if err := in.Deliver(ctx, built); err != nil {
+				return err
+			}
+		}
+		built = make([]zmtp.Message, 0)
+	}
+}
+
+func (s *Sub) Meta() zmtp.Metadata {
+	meta := zmtp.Metadata{}
+	meta.AddProperty("Socket-Type", "SUB")
+	return meta
+}
+
+func (s *Sub) MetaHandler(meta zmtp.Metadata) error {
+	var err error
+	meta.Properties(func(name string, value string) {
+		if name == "Socket-Type" && err == nil {
+			if value != "PUB" && value != "XPUB" {
+				err = fmt.Errorf("Expected pub or xpub socket to connect, got %s", value)
+			}
+		}
+	})
+
+	return err
+}
+
+// Subscribe to messages starting with topic.
+func (s *Sub) Subscribe(topic []byte) error {
+	return s.Publishers.Subscribe(topic)
+}
+
+// Unsubscribe from topic.
+func (s *Sub) Unsubscribe(topic []byte) error {
+	return s.Publishers.Unsubscribe(topic)
+}
+
+func (s *Sub) Send([]zmtp.Message) error {
+	return types.ErrOperationNotPermitted
+}
+
+func (s *Sub) SendContext(context.Context, []zmtp.Message) error {
+	return types.ErrOperationNotPermitted
+}
+
+func (s *Sub) Recv() ([]zmtp.Message, error) {
+	return s.RecvContext(context.Background())
+}
+
+// RecvContext implements gomq.SocketDriver.
+func (s *Sub) RecvContext(ctx context.Context) ([]zmtp.Message, error) {
+	return s.Queue.Receive(ctx, s.Context)
+}
+
+// RecvReady implements gomq.RecvPoller.
+func (s *Sub) RecvReady() <-chan struct{} {
+	return s.Queue.Ready()
+}
+
+// QueueDepths implements gomq.QueueReporter.
+func (s *Sub) QueueDepths() (send, recv gomq.QueueDepth) {
+	return gomq.QueueDepth{}, s.Queue.Depth()
+}
+
+func (s *Sub) Close() error {
+	s.Cancel()
+	for _, conn := range s.ConnectionDrivers {
+		conn.Close()
+	}
+	for _, bind := range s.BindDrivers {
+		bind.Close()
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package sub_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	_ "github.com/workspace-9/gomq/transport/inproc"
+	_ "github.com/workspace-9/gomq/transport/tcp"
+	_ "github.com/workspace-9/gomq/types/pub"
+	_ "github.com/workspace-9/gomq/types/sub"
+	_ "github.com/workspace-9/gomq/zmtp/null"
+)
+
+// newSocket makes a socket closed at the end of the test, whose receives
+// time out quickly so messages sent before a subscription arrived can be
+// sent again.
+func newSocket(t *testing.T, ctx *gomq.Context, typ string) *gomq.Socket {
+	t.Helper()
+	sock, err := ctx.NewSocket(typ, "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sock.Close() })
+	sock.SetOption(gomq.OptionRecvTimeout, 100*time.Millisecond)
+	sock.SetOption(gomq.OptionReconnectIvl, 10*time.Millisecond)
+	return sock
+}
+
+// bind the socket, returning the address it listens on.
+func bind(t *testing.T, sock *gomq.Socket, addr string) string {
+	t.Helper()
+	events := sock.Monitor(gomq.Events(gomq.EventTypeListening))
+	if err := sock.Bind(addr); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-events:
+		return ev.LocalAddr
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the socket to listen")
+		return ""
+	}
+}
+
+// await publishes numbered messages on the topic, each after one on the
+// unwanted topic, until the subscriber receives one, failing if it
+// receives anything not on the topic.
+func await(t *testing.T, pub, sub *gomq.Socket, topic, unwanted string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for idx := 0; time.Now().Before(deadline); idx++ {
+		want := fmt.Sprintf("%s-%d", topic, idx)
+		if err := pub.Send([][]byte{[]byte(unwanted)}); err != nil {
+			t.Fatal(err)
+		}
+		if err := pub.Send([][]byte{[]byte(want)}); err != nil {
+			t.Fatal(err)
+		}
+
+		for {
+			msg, err := sub.Recv()
+			if err != nil {
+				break
+			}
+			if !strings.HasPrefix(string(msg[0]), topic) {
+				t.Fatalf("expected only topic %q, got %q", topic, msg[0])
+			}
+			if string(msg[0]) == want {
+				return
+			}
+		}
+	}
+
+	t.Fatalf("expected the subscriber to receive topic %q", topic)
+}
+
+// TestPrefixFilter checks that subscribers receive only messages starting
+// with a topic they subscribed to, until they unsubscribe.
+func TestPrefixFilter(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	pub := newSocket(t, ctx, "PUB")
+	bind(t, pub, "inproc://filter")
+	sub := newSocket(t, ctx, "SUB")
+	if err := sub.Connect("inproc://filter"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sub.Subscribe([]byte("news.")); err != nil {
+		t.Fatal(err)
+	}
+	await(t, pub, sub, "news.", "sport.")
+
+	if err := sub.Unsubscribe([]byte("news.")); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Subscribe([]byte("sport.")); err != nil {
+		t.Fatal(err)
+	}
+	await(t, pub, sub, "sport.", "news.")
+}
+
+// TestResubscribeOnReconnect checks that subscriptions are sent again to a
+// publisher the subscriber reconnects to.
+func TestResubscribeOnReconnect(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	pub := newSocket(t, ctx, "PUB")
+	addr := bind(t, pub, "tcp://127.0.0.1:0")
+	sub := newSocket(t, ctx, "SUB")
+	if err := sub.Subscribe([]byte("news.")); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Connect(addr); err != nil {
+		t.Fatal(err)
+	}
+	await(t, pub, sub, "news.", "sport.")
+
+	pub.Close()
+	pub = newSocket(t, ctx, "PUB")
+	bind(t, pub, addr)
+	await(t, pub, sub, "news.", "sport.")
+}
@@ -0,0 +1,398 @@
+package xpub
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/types"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// XPub implements the zmq xpub socket.
+type XPub struct {
+	context.Context
+	Cancel context.CancelFunc
+	*gomq.Config
+	Mech              zmtp.Mechanism
+	ConnectionDrivers map[string]*socketutil.ConnectionDriver
+	BindDrivers       map[string]*socketutil.BindDriver
+	EventBus          gomq.EventBus
+	Subscribers       socketutil.Subscribers
+
+	// Queue takes messages from the inboxes of subscribers in turn.
+	Queue socketutil.FairQueue[[]zmtp.Message]
+
+	// Topics counts the subscribers subscribed to each topic.
+	Topics socketutil.Subscriptions
+
+	lock           sync.Mutex
+	verbose        bool
+	verboser       bool
+	manual         bool
+	welcomeMsg     []byte
+	lastSubscriber *socketutil.Subscriber
+}
+
+func (x *XPub) Name() string {
+	return "XPUB"
+}
+
+func (x *XPub) Connect(tp transport.Transport, url *url.URL) error {
+	if _, ok := x.ConnectionDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyConnected, url)
+	}
+
+	driver := &socketutil.ConnectionDriver{}
+	driver.Setup(
+		x.Context,
+		x.Mech,
+		tp,
+		url,
+		x.Config,
+		x.EventBus,
+		x.HandleSock,
+		x.Meta,
+		x.MetaHandler,
+	)
+	fatal, err := driver.TryConnect()
+	if err != nil && fatal {
+		return err
+	}
+	x.ConnectionDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (x *XPub) Disconnect(url *url.URL) error {
+	driver, ok := x.ConnectionDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverConnected, url)
+	}
+
+	delete(x.ConnectionDrivers, url.String())
+	return driver.Close()
+}
+
+func (x *XPub) Bind(tp transport.Transport, url *url.URL) error {
+	if _, ok := x.BindDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyBound, url)
+	}
+
+	driver := &socketutil.BindDriver{}
+	driver.Setup(
+		x.Context,
+		tp,
+		x.Mech,
+		url,
+		x.Config,
+		x.HandleSock,
+		x.EventBus,
+		x.Meta,
+		x.MetaHandler,
+	)
+	if err := driver.TryBind(); err != nil {
+		return err
+	}
+	x.BindDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (x *XPub) Unbind(url *url.URL) error {
+	driver, ok := x.BindDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverBound, url.String())
+	}
+
+	delete(x.BindDrivers, url.String())
+	return driver.Close()
+}
+
+// HandleSock registers the peer as a subscriber for as long as it remains
+// connected. Once it disconnects, topics no other subscriber wants are
+// passed to Recv as unsubscriptions.
+func (x *XPub) HandleSock(ctx context.Context, peer *socketutil.Peer) error {
+	sub := x.Subscribers.Attach(peer, x.Config.SendHWM())
+	defer x.Subscribers.Detach(sub)
+
+	x.lock.Lock()
+	welcomeMsg := x.welcomeMsg
+	x.lock.Unlock()
+	if welcomeMsg != nil {
+		if err := peer.SendMessage(zmtp.Message{Body: welcomeMsg}); err != nil {
+			return err
+		}
+	}
+
+	// The reader alone closes the inbox, once it has finished delivering to
+	// it.
+	in := x.Queue.Add(x.Config.RecvHWM())
+	derived, cancel := context.WithCancel(ctx)
+	defer cancel()
+	readErr := make(chan error, 1)
+	go func() {
+		defer in.Close()
+		err := x.readFrom(derived, sub, in)
+		x.forget(ctx, sub, in)
+		readErr <- err
+	}()
+
+	return sub.Serve(ctx, readErr)
+}
+
+// forget the subscriber once nothing more is read from it, passing topics
+// no other subscriber wants to Recv as unsubscriptions.
+func (x *XPub) forget(
+	ctx context.Context,
+	sub *socketutil.Subscriber,
+	in *socketutil.Inbox[[]zmtp.Message],
+) {
+	x.lock.Lock()
+	if x.lastSubscriber == sub {
+		x.lastSubscriber = nil
+	}
+	x.lock.Unlock()
+
+	for _, topic := range sub.Topics() {
+		if !x.Topics.Remove(topic) {
+			continue
+		}
+		if err := x.deliver(ctx, in, zmtp.CancelMessage(topic)); err != nil {
+			return
+		}
+	}
+}
+
+// readFrom applies the subscriber's subscriptions and passes them to Recv
+// according to the verbosity options. Other messages are passed to Recv as
+// they are.
+func (x *XPub) readFrom(
+	ctx context.Context,
+	sub *socketutil.Subscriber,
+	in *socketutil.Inbox[[]zmtp.Message],
+) error {
+	built := make([]zmtp.Message, 0)
+	for {
+		next, err := sub.Peer.Read()
+		if err != nil {
+			return err
+		}
+
+		if next.IsMessage {
+			built = append(built, *next.Message)
+			if next.Message.More {
+				continue
+			}
+
+			msg := built
+			built = make([]zmtp.Message, 0)
+			if _, _, ok := zmtp.ParseSubscription(next); !ok || len(msg) > 1 {
+				if err := x.deliver(ctx, in, msg...); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		subscribe, topic, ok := zmtp.ParseSubscription(next)
+		if !ok {
+			continue
+		}
+
+		x.lock.Lock()
+		verbose, verboser, manual := x.verbose, x.verboser, x.manual
+		if manual {
+			x.lastSubscriber = sub
+		}
+		x.lock.Unlock()
+
+		var pass bool
+		switch {
+		case manual:
+			pass = true
+		case subscribe:
+			pass = verbose || verboser
+			if sub.Add(topic) && x.Topics.Add(topic) {
+				pass = true
+			}
+		default:
+			pass = verboser
+			if sub.Remove(topic) && x.Topics.Remove(topic) {
+				pass = true
+			}
+		}
+
+		if !pass {
+			continue
+		}
+
+		if subscribe {
+			err = x.deliver(ctx, in, zmtp.SubscribeMessage(topic))
+		} else {
+			err = x.deliver(ctx, in, zmtp.CancelMessage(topic))
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (x *XPub) deliver(ctx context.Context, in *socketutil.Inbox[[]zmtp.Message], msg ...zmtp.Message) error {
+	return in.Deliver(ctx, msg)
+}
+
+func (x *XPub) Meta() zmtp.Metadata {
+	meta := zmtp.Metadata{}
+	meta.AddProperty("Socket-Type", "XPUB")
+	return meta
+}
+
+func (x *XPub) MetaHandler(meta zmtp.Metadata) error {
+	var err error
+	meta.Properties(func(name string, value string) {
+		if name == "Socket-Type" && err == nil {
+			if value != "SUB" && value != "XSUB" {
+				err = fmt.Errorf("Expected sub or xsub socket to connect, got %s", value)
+			}
+		}
+	})
+
+	return err
+}
+
+// SetOption implements gomq.OptionSetter.
+func (x *XPub) SetOption(option string, val any) error {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	switch option {
+	case gomq.OptionXPubVerbose, gomq.OptionXPubVerboser, gomq.OptionXPubManual:
+		on, err := types.BoolOption(option, val)
+		if err != nil {
+			return err
+		}
+
+		switch option {
+		case gomq.OptionXPubVerbose:
+			x.verbose = on
+		case gomq.OptionXPubVerboser:
+			x.verboser = on
+		case gomq.OptionXPubManual:
+			x.manual = on
+		}
+	case gomq.OptionXPubWelcomeMsg:
+		msg, err := types.BytesOption(option, val)
+		if err != nil {
+			return err
+		}
+		x.welcomeMsg = msg
+	default:
+		return gomq.ErrUnknownOption
+	}
+
+	return nil
+}
+
+// Subscribe applies a subscription to the subscriber which most recently
+// sent one, which is only permitted when OptionXPubManual is set.
+func (x *XPub) Subscribe(topic []byte) error {
+	sub, err := x.manualSubscriber()
+	if err != nil {
+		return err
+	}
+
+	if sub.Add(topic) {
+		x.Topics.Add(topic)
+	}
+	return nil
+}
+
+// Unsubscribe removes a subscription from the subscriber which most recently
+// sent one, which is only permitted when OptionXPubManual is set.
+func (x *XPub) Unsubscribe(topic []byte) error {
+	sub, err := x.manualSubscriber()
+	if err != nil {
+		return err
+	}
+
+	if sub.Remove(topic) {
+		x.Topics.Remove(topic)
+	}
+	return nil
+}
+
+func (x *XPub) manualSubscriber() (*socketutil.Subscriber, error) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	if !x.manual {
+		return nil, fmt.Errorf("%w: %s is not set", types.ErrOperationNotPermitted, gomq.OptionXPubManual)
+	}
+
+	if x.lastSubscriber == nil {
+		return nil, fmt.Errorf("%w: no subscriber to apply subscription to", types.ErrOperationNotPermitted)
+	}
+
+	return x.lastSubscriber, nil
+}
+
+// Send queues the message for every subscriber whose subscriptions match its
+// first frame. Subscribers whose queue is full miss the message.
+func (x *XPub) Send(data []zmtp.Message) error {
+	if err := x.Context.Err(); err != nil {
+		return err
+	}
+
+	x.Subscribers.Publish(data)
+	return nil
+}
+
+// SendContext implements gomq.SocketDriver. Publishing never blocks.
+func (x *XPub) SendContext(_ context.Context, data []zmtp.Message) error {
+	return x.Send(data)
+}
+
+// Recv returns the next subscription, unsubscription or other message sent
+// by a subscriber.
+func (x *XPub) Recv() ([]zmtp.Message, error) {
+	return x.RecvContext(context.Background())
+}
+
+// RecvContext implements gomq.SocketDriver.
+func (x *XPub) RecvContext(ctx context.Context) ([]zmtp.Message, error) {
+	return x.Queue.Receive(ctx, x.Context)
+}
+
+// RecvReady implements gomq.RecvPoller.
+func (x *XPub) RecvReady() <-chan struct{} {
+	return x.Queue.Ready()
+}
+
+// QueueDepths implements gomq.QueueReporter.
+func (x *XPub) QueueDepths() (send, recv gomq.QueueDepth) {
+	return x.Subscribers.Depth(), x.Queue.Depth()
+}
+
+func (x *XPub) Close() error {
+	return x.CloseContext(context.Background())
+}
+
+// CloseContext implements gomq.GracefulCloser, waiting up to the linger
+// period for messages queued for subscribers to be written.
+func (x *XPub) CloseContext(ctx context.Context) error {
+	discarded := x.Subscribers.Backlog.Linger(ctx, x.Config.Linger())
+	defer socketutil.PostDiscarded(x.EventBus, discarded)
+	x.Cancel()
+	for _, conn := range x.ConnectionDrivers {
+		conn.Close()
+	}
+	for _, bind := range x.BindDrivers {
+		bind.Close()
+	}
+	return nil
+}
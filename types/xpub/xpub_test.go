@@ -0,0 +1,57 @@
+package xpub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	_ "github.com/workspace-9/gomq/transport/inproc"
+	_ "github.com/workspace-9/gomq/types/sub"
+	_ "github.com/workspace-9/gomq/types/xpub"
+	_ "github.com/workspace-9/gomq/zmtp/null"
+)
+
+// TestCancelOnDisconnect checks that the topics of a subscriber which
+// disconnects are passed to Recv as unsubscriptions.
+func TestCancelOnDisconnect(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	xpub, err := ctx.NewSocket("XPUB", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer xpub.Close()
+	xpub.SetOption(gomq.OptionRecvTimeout, 5*time.Second)
+	if err := xpub.Bind("inproc://cancel"); err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := ctx.NewSocket("SUB", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Connect("inproc://cancel"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Subscribe([]byte("topic")); err != nil {
+		t.Fatal(err)
+	}
+
+	expect(t, xpub, "\x01topic")
+	sub.Close()
+	expect(t, xpub, "\x00topic")
+}
+
+// expect fails the test unless the next message received is want.
+func expect(t *testing.T, sock *gomq.Socket, want string) {
+	t.Helper()
+	msg, err := sock.Recv()
+	if err != nil {
+		t.Fatalf("expected %q: %v", want, err)
+	}
+	if len(msg) != 1 || string(msg[0]) != want {
+		t.Fatalf("expected %q, got %q", want, msg)
+	}
+}
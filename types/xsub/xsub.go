@@ -0,0 +1,240 @@
+package xsub
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/socketutil"
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/types"
+	"github.com/workspace-9/gomq/types/sub"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// XSub implements the zmq xsub socket.
+type XSub struct {
+	context.Context
+	Cancel context.CancelFunc
+	*gomq.Config
+	Mech              zmtp.Mechanism
+	ConnectionDrivers map[string]*socketutil.ConnectionDriver
+	BindDrivers       map[string]*socketutil.BindDriver
+	EventBus          gomq.EventBus
+	Publishers        socketutil.Publishers
+
+	// Queue takes messages from the inboxes of publishers in turn.
+	Queue socketutil.FairQueue[[]zmtp.Message]
+
+	// Outgoing holds the outbox of every publisher, each of which is sent
+	// every message.
+	Outgoing socketutil.LoadBalancer
+
+	// Backlog counts messages accepted by Send which are yet to be written.
+	Backlog socketutil.Backlog
+}
+
+func (x *XSub) Name() string {
+	return "XSUB"
+}
+
+func (x *XSub) Connect(tp transport.Transport, url *url.URL) error {
+	if _, ok := x.ConnectionDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyConnected, url)
+	}
+
+	driver := &socketutil.ConnectionDriver{}
+	driver.Setup(
+		x.Context,
+		x.Mech,
+		tp,
+		url,
+		x.Config,
+		x.EventBus,
+		x.HandleSock,
+		x.Meta,
+		x.MetaHandler,
+	)
+	fatal, err := driver.TryConnect()
+	if err != nil && fatal {
+		return err
+	}
+	x.ConnectionDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (x *XSub) Disconnect(url *url.URL) error {
+	driver, ok := x.ConnectionDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverConnected, url)
+	}
+
+	delete(x.ConnectionDrivers, url.String())
+	return driver.Close()
+}
+
+func (x *XSub) Bind(tp transport.Transport, url *url.URL) error {
+	if _, ok := x.BindDrivers[url.String()]; ok {
+		return fmt.Errorf("%w: %s", types.ErrAlreadyBound, url)
+	}
+
+	driver := &socketutil.BindDriver{}
+	driver.Setup(
+		x.Context,
+		tp,
+		x.Mech,
+		url,
+		x.Config,
+		x.HandleSock,
+		x.EventBus,
+		x.Meta,
+		x.MetaHandler,
+	)
+	if err := driver.TryBind(); err != nil {
+		return err
+	}
+	x.BindDrivers[url.String()] = driver
+	go driver.Run()
+	return nil
+}
+
+func (x *XSub) Unbind(url *url.URL) error {
+	driver, ok := x.BindDrivers[url.String()]
+	if !ok {
+		return fmt.Errorf("%w to %s", types.ErrNeverBound, url.String())
+	}
+
+	delete(x.BindDrivers, url.String())
+	return driver.Close()
+}
+
+// HandleSock sends the peer every current subscription then receives
+// matching messages from it, while writing the messages queued in its
+// outbox, until either fails or ctx is done.
+func (x *XSub) HandleSock(ctx context.Context, peer *socketutil.Peer) error {
+	if err := x.Publishers.Attach(peer); err != nil {
+		return err
+	}
+	defer x.Publishers.Detach(peer)
+
+	out := x.Outgoing.Add(x.Config.SendHWM())
+	defer func() {
+		x.Backlog.Done(x.Outgoing.Remove(out))
+	}()
+
+	in := x.Queue.Add(x.Config.RecvHWM())
+	derived, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go func() {
+		defer in.Close()
+		cancel(sub.ReadMessages(derived, peer, &x.Publishers.Subscriptions, in))
+	}()
+
+	for {
+		msg, err := out.Receive(derived)
+		if err != nil {
+			return context.Cause(derived)
+		}
+
+		err = peer.SendMessages(msg)
+		x.Backlog.Done(1)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (x *XSub) Meta() zmtp.Metadata {
+	meta := zmtp.Metadata{}
+	meta.AddProperty("Socket-Type", "XSUB")
+	return meta
+}
+
+func (x *XSub) MetaHandler(meta zmtp.Metadata) error {
+	var err error
+	meta.Properties(func(name string, value string) {
+		if name == "Socket-Type" && err == nil {
+			if value != "PUB" && value != "XPUB" {
+				err = fmt.Errorf("Expected pub or xpub socket to connect, got %s", value)
+			}
+		}
+	})
+
+	return err
+}
+
+// Subscribe to messages starting with topic.
+func (x *XSub) Subscribe(topic []byte) error {
+	return x.Publishers.Subscribe(topic)
+}
+
+// Unsubscribe from topic.
+func (x *XSub) Unsubscribe(topic []byte) error {
+	return x.Publishers.Unsubscribe(topic)
+}
+
+// Send injects a subscription, given as a single frame holding 0x01 or 0x00
+// followed by the topic, upstream. Every one is forwarded, even repeats, as
+// libzmq does. Any other message is queued for every publisher as it is,
+// and missed by those whose queue is full.
+func (x *XSub) Send(data []zmtp.Message) error {
+	if err := x.Context.Err(); err != nil {
+		return err
+	}
+
+	if len(data) == 1 {
+		next := zmtp.CommandOrMessage{IsMessage: true, Message: &data[0]}
+		if subscribe, topic, ok := zmtp.ParseSubscription(next); ok {
+			x.Publishers.Forward(subscribe, topic)
+			return nil
+		}
+	}
+
+	x.Outgoing.SendAll(data, &x.Backlog)
+	return nil
+}
+
+// SendContext implements gomq.SocketDriver. Sending never waits for peers.
+func (x *XSub) SendContext(_ context.Context, data []zmtp.Message) error {
+	return x.Send(data)
+}
+
+func (x *XSub) Recv() ([]zmtp.Message, error) {
+	return x.RecvContext(context.Background())
+}
+
+// RecvContext implements gomq.SocketDriver.
+func (x *XSub) RecvContext(ctx context.Context) ([]zmtp.Message, error) {
+	return x.Queue.Receive(ctx, x.Context)
+}
+
+// RecvReady implements gomq.RecvPoller.
+func (x *XSub) RecvReady() <-chan struct{} {
+	return x.Queue.Ready()
+}
+
+// QueueDepths implements gomq.QueueReporter.
+func (x *XSub) QueueDepths() (send, recv gomq.QueueDepth) {
+	return x.Outgoing.Depth(), x.Queue.Depth()
+}
+
+func (x *XSub) Close() error {
+	return x.CloseContext(context.Background())
+}
+
+// CloseContext implements gomq.GracefulCloser, waiting up to the linger
+// period for queued messages to be written before tearing down.
+func (x *XSub) CloseContext(ctx context.Context) error {
+	discarded := x.Backlog.Linger(ctx, x.Config.Linger())
+	x.Cancel()
+	for _, conn := range x.ConnectionDrivers {
+		conn.Close()
+	}
+	for _, bind := range x.BindDrivers {
+		bind.Close()
+	}
+	socketutil.PostDiscarded(x.EventBus, discarded)
+	return nil
+}
@@ -0,0 +1,133 @@
+package xsub_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	_ "github.com/workspace-9/gomq/transport/tcp"
+	_ "github.com/workspace-9/gomq/types/xpub"
+	_ "github.com/workspace-9/gomq/types/xsub"
+	_ "github.com/workspace-9/gomq/zmtp/null"
+)
+
+// TestForwardRepeats checks that an XSUB forwards every subscription sent
+// through it, so an XPUB upstream with OptionXPubVerboser sees repeats and
+// cancellations.
+func TestForwardRepeats(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	xpub, err := ctx.NewSocket("XPUB", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer xpub.Close()
+	xpub.SetOption(gomq.OptionXPubVerboser, true)
+	xpub.SetOption(gomq.OptionRecvTimeout, 5*time.Second)
+	events := xpub.Monitor(gomq.Events(gomq.EventTypeListening))
+	if err := xpub.Bind("tcp://127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	addr := (<-events).LocalAddr
+
+	xsub, err := ctx.NewSocket("XSUB", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer xsub.Close()
+	if err := xsub.Connect(addr); err != nil {
+		t.Fatal(err)
+	}
+
+	// A subscription made before the peer is attached is replayed once it
+	// is, so receiving this one means later ones are forwarded directly.
+	if err := xsub.Send([][]byte{[]byte("\x01sync")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := xpub.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := [][]byte{
+		[]byte("\x01topic"),
+		[]byte("\x01topic"),
+		[]byte("\x00topic"),
+		[]byte("\x00topic"),
+	}
+	for _, frame := range sent {
+		if err := xsub.Send([][]byte{frame}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, want := range sent {
+		got, err := xpub.Recv()
+		if err != nil {
+			t.Fatalf("expected %q: %v", want, err)
+		}
+		if len(got) != 1 || !bytes.Equal(got[0], want) {
+			t.Fatalf("expected %q, got %q", want, got)
+		}
+	}
+}
+
+// TestSendNeverWaits checks that messages sent upstream are dropped for a
+// publisher which is not receiving them rather than holding up Send.
+func TestSendNeverWaits(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	xpub, err := ctx.NewSocket("XPUB", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer xpub.Close()
+	xpub.SetOption(gomq.OptionRecvHWM, 1)
+	xpub.SetOption(gomq.OptionRecvTimeout, 5*time.Second)
+	events := xpub.Monitor(gomq.Events(gomq.EventTypeListening))
+	if err := xpub.Bind("tcp://127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	addr := (<-events).LocalAddr
+
+	xsub, err := ctx.NewSocket("XSUB", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer xsub.Close()
+	xsub.SetOption(gomq.OptionSendHWM, 1)
+	if err := xsub.Connect(addr); err != nil {
+		t.Fatal(err)
+	}
+	// Wait for the publisher to be attached, after which it stops reading.
+	if err := xsub.Send([][]byte{[]byte("\x01sync")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := xpub.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	body := make([]byte, 64*1024)
+	done := make(chan error, 1)
+	go func() {
+		for idx := 0; idx < 200; idx++ {
+			if err := xsub.Send([][]byte{body}); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected sends to a slow publisher not to wait")
+	}
+}
@@ -0,0 +1,92 @@
+package gomq
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/workspace-9/gomq/zap"
+)
+
+// ZAPEndpoint is where a classic zap handler binds a REP socket.
+const ZAPEndpoint = "inproc://zeromq.zap.01"
+
+// ZAPTimeout is how long a handler bound to ZAPEndpoint has to answer a
+// request before the client is refused with StatusInternal.
+const ZAPTimeout = time.Second
+
+// SetZAPHandler sets the handler consulted by the mechanisms of sockets
+// created afterwards in this context.
+func (c *Context) SetZAPHandler(handler zap.Handler) {
+	c.Lock()
+	defer c.Unlock()
+	c.zapHandler = handler
+}
+
+// ZAPHandler returns the handler set by SetZAPHandler.
+func (c *Context) ZAPHandler() zap.Handler {
+	c.RLock()
+	defer c.RUnlock()
+	return c.zapHandler
+}
+
+// UseZAPEndpoint makes the mechanisms of sockets created afterwards in this
+// context consult a handler bound to ZAPEndpoint. The REQ socket type and
+// the inproc transport must be registered. Requests the handler does not
+// answer within ZAPTimeout, including while nothing is bound, fail.
+func (c *Context) UseZAPEndpoint() error {
+	sock, err := c.NewSocket("REQ", "NULL")
+	if err != nil {
+		return err
+	}
+
+	// A request which timed out must not stop the next being sent, nor its
+	// late reply be taken for the next one's.
+	sock.SetOption(OptionReqRelaxed, true)
+	sock.SetOption(OptionReqCorrelate, true)
+
+	if err := sock.Connect(ZAPEndpoint); err != nil {
+		sock.Close()
+		return err
+	}
+
+	c.SetZAPHandler(&zapEndpoint{sock: sock})
+	return nil
+}
+
+// zapEndpoint forwards zap requests to a handler over a REQ socket.
+type zapEndpoint struct {
+	lock   sync.Mutex
+	sock   *Socket
+	nextID uint64
+}
+
+func (z *zapEndpoint) Authenticate(req zap.Request) zap.Response {
+	z.lock.Lock()
+	defer z.lock.Unlock()
+
+	z.nextID++
+	req.RequestID = []byte(strconv.FormatUint(z.nextID, 10))
+	ctx, cancel := context.WithTimeout(context.Background(), ZAPTimeout)
+	defer cancel()
+	if err := z.sock.SendContext(ctx, req.Frames()); err != nil {
+		return zap.Response{StatusCode: zap.StatusInternal, StatusText: err.Error()}
+	}
+
+	reply, err := z.sock.RecvContext(ctx)
+	if err != nil {
+		return zap.Response{StatusCode: zap.StatusInternal, StatusText: err.Error()}
+	}
+
+	resp, err := zap.ParseResponse(reply)
+	if err != nil {
+		return zap.Response{StatusCode: zap.StatusInternal, StatusText: err.Error()}
+	}
+
+	if string(resp.RequestID) != string(req.RequestID) {
+		return zap.Response{StatusCode: zap.StatusInternal, StatusText: "Mismatched request id"}
+	}
+
+	return resp
+}
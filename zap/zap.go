@@ -0,0 +1,238 @@
+// Package zap implements the ZeroMQ Authentication Protocol (RFC 27), which
+// mechanisms use to ask a handler whether a client may connect.
+package zap
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/workspace-9/gomq/transport"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// Version of the protocol carried in every request and reply.
+const Version = "1.0"
+
+const (
+	StatusSuccess   = "200"
+	StatusTemporary = "300"
+	StatusFailure   = "400"
+	StatusInternal  = "500"
+)
+
+// Request describes a client asking to connect.
+type Request struct {
+	RequestID []byte
+
+	// Domain is the zap domain set on the server socket.
+	Domain string
+
+	// Address is the client's address, without a port where one applies.
+	Address string
+
+	// Identity is the routing id of the server socket.
+	Identity []byte
+
+	// Mechanism is NULL, PLAIN or CURVE.
+	Mechanism string
+
+	// Credentials are empty for NULL, the username and password for PLAIN
+	// and the client's 32 byte long term public key for CURVE.
+	Credentials [][]byte
+
+	// Properties are what the transport learned about the client, such as
+	// its certificate subject. RFC 27 has no frame for them, so handlers
+	// bound to an endpoint do not see them.
+	Properties map[string]string
+}
+
+// Response is a handler's decision on a Request.
+type Response struct {
+	RequestID  []byte
+	StatusCode string
+	StatusText string
+
+	// UserID is added to the peer's metadata as the User-Id property.
+	UserID string
+
+	// Metadata is added to the peer's metadata.
+	Metadata zmtp.Metadata
+}
+
+// Handler decides whether clients may connect.
+type Handler interface {
+	Authenticate(Request) Response
+}
+
+// HandlerFunc implements Handler with a function.
+type HandlerFunc func(Request) Response
+
+// Authenticate calls f.
+func (f HandlerFunc) Authenticate(req Request) Response {
+	return f(req)
+}
+
+// Frames encodes the request as a multipart message.
+func (r Request) Frames() [][]byte {
+	frames := [][]byte{
+		[]byte(Version),
+		r.RequestID,
+		[]byte(r.Domain),
+		[]byte(r.Address),
+		r.Identity,
+		[]byte(r.Mechanism),
+	}
+	return append(frames, r.Credentials...)
+}
+
+// ParseRequest decodes a request from a multipart message.
+func ParseRequest(frames [][]byte) (Request, error) {
+	if len(frames) < 6 {
+		return Request{}, fmt.Errorf("%w: request has %d frames, expected at least 6", ErrInvalidMessage, len(frames))
+	}
+
+	if string(frames[0]) != Version {
+		return Request{}, fmt.Errorf("%w: unsupported version %q", ErrInvalidMessage, frames[0])
+	}
+
+	return Request{
+		RequestID:   frames[1],
+		Domain:      string(frames[2]),
+		Address:     string(frames[3]),
+		Identity:    frames[4],
+		Mechanism:   string(frames[5]),
+		Credentials: frames[6:],
+	}, nil
+}
+
+// Frames encodes the response as a multipart message.
+func (r Response) Frames() [][]byte {
+	return [][]byte{
+		[]byte(Version),
+		r.RequestID,
+		[]byte(r.StatusCode),
+		[]byte(r.StatusText),
+		[]byte(r.UserID),
+		r.Metadata,
+	}
+}
+
+// ParseResponse decodes a response from a multipart message.
+func ParseResponse(frames [][]byte) (Response, error) {
+	if len(frames) != 6 {
+		return Response{}, fmt.Errorf("%w: response has %d frames, expected 6", ErrInvalidMessage, len(frames))
+	}
+
+	if string(frames[0]) != Version {
+		return Response{}, fmt.Errorf("%w: unsupported version %q", ErrInvalidMessage, frames[0])
+	}
+
+	return Response{
+		RequestID:  frames[1],
+		StatusCode: string(frames[2]),
+		StatusText: string(frames[3]),
+		UserID:     string(frames[4]),
+		Metadata:   zmtp.Metadata(frames[5]),
+	}, nil
+}
+
+// Address formats a client's address for a Request.
+func Address(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// Authenticate consults the handler, returning the peer's metadata extended
+// with the handler's metadata and User-Id if the client was accepted.
+func Authenticate(handler Handler, req Request, peerMeta zmtp.Metadata) (zmtp.Metadata, error) {
+	resp := handler.Authenticate(req)
+	if resp.StatusCode != StatusSuccess {
+		return nil, fmt.Errorf("%w: %s %s", ErrDenied, resp.StatusCode, resp.StatusText)
+	}
+
+	meta := make(zmtp.Metadata, 0, len(peerMeta)+len(resp.Metadata))
+	meta = append(meta, peerMeta...)
+	meta = append(meta, resp.Metadata...)
+	if err := meta.AddProperty("User-Id", resp.UserID); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+type invalidMessage struct{}
+
+func (invalidMessage) Error() string {
+	return "Invalid zap message"
+}
+
+var ErrInvalidMessage invalidMessage
+
+type denied struct{}
+
+func (denied) Error() string {
+	return "Authentication denied"
+}
+
+var ErrDenied denied
+
+// Settings holds a mechanism's zap options. The zero value consults no
+// handler.
+type Settings struct {
+	Handler Handler
+	Domain  string
+}
+
+// SetOption applies zmtp.OptionZAPHandler or zmtp.OptionZAPDomain,
+// returning false if the option is neither.
+func (s *Settings) SetOption(option string, val any) (bool, error) {
+	switch option {
+	case zmtp.OptionZAPHandler:
+		handler, ok := val.(Handler)
+		if !ok && val != nil {
+			return true, fmt.Errorf("Value for option %s must be zap.Handler, got %T", option, val)
+		}
+		s.Handler = handler
+	case zmtp.OptionZAPDomain:
+		domain, ok := val.(string)
+		if !ok {
+			return true, fmt.Errorf("Value for option %s must be string, got %T", option, val)
+		}
+		s.Domain = domain
+	default:
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// Authenticate the client on conn if a handler is set, returning peerMeta
+// unchanged otherwise. meta is the server's own metadata, which carries its
+// routing id.
+func (s *Settings) Authenticate(
+	conn net.Conn,
+	mechanism string,
+	meta zmtp.Metadata,
+	credentials [][]byte,
+	peerMeta zmtp.Metadata,
+) (zmtp.Metadata, error) {
+	if s.Handler == nil {
+		return peerMeta, nil
+	}
+
+	identity, _ := meta.Property("Identity")
+	var properties map[string]string
+	if conn, ok := conn.(transport.PeerPropertiesConn); ok {
+		properties = conn.PeerProperties()
+	}
+	return Authenticate(s.Handler, Request{
+		Domain:      s.Domain,
+		Address:     Address(conn.RemoteAddr()),
+		Identity:    []byte(identity),
+		Mechanism:   mechanism,
+		Credentials: credentials,
+		Properties:  properties,
+	}, peerMeta)
+}
@@ -0,0 +1,329 @@
+package gomq_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/nacl/box"
+
+	"github.com/workspace-9/gomq"
+	_ "github.com/workspace-9/gomq/transport/inproc"
+	_ "github.com/workspace-9/gomq/transport/tcp"
+	_ "github.com/workspace-9/gomq/types/pull"
+	_ "github.com/workspace-9/gomq/types/push"
+	_ "github.com/workspace-9/gomq/types/rep"
+	_ "github.com/workspace-9/gomq/types/req"
+	"github.com/workspace-9/gomq/zap"
+	"github.com/workspace-9/gomq/zmtp"
+	_ "github.com/workspace-9/gomq/zmtp/curve"
+	_ "github.com/workspace-9/gomq/zmtp/null"
+	_ "github.com/workspace-9/gomq/zmtp/plain"
+)
+
+// TestZAPEndpointUnbound checks that a request fails with StatusInternal
+// rather than hanging while no handler is bound, and that a handler bound
+// afterwards is consulted.
+func TestZAPEndpointUnbound(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+	if err := ctx.UseZAPEndpoint(); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	resp := ctx.ZAPHandler().Authenticate(zap.Request{Mechanism: "NULL"})
+	if resp.StatusCode != zap.StatusInternal {
+		t.Fatalf("expected status %s, got %s", zap.StatusInternal, resp.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed > 2*gomq.ZAPTimeout {
+		t.Fatalf("request took %s", elapsed)
+	}
+
+	handler, err := ctx.NewSocket("REP", "NULL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer handler.Close()
+	if err := handler.Bind(gomq.ZAPEndpoint); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			frames, err := handler.Recv()
+			if err != nil {
+				return
+			}
+
+			req, err := zap.ParseRequest(frames)
+			if err != nil {
+				return
+			}
+
+			resp := zap.Response{RequestID: req.RequestID, StatusCode: zap.StatusSuccess, StatusText: "OK"}
+			if handler.Send(resp.Frames()) != nil {
+				return
+			}
+		}
+	}()
+
+	// The REQ socket reconnects on its own interval, which may outlast one
+	// ZAPTimeout.
+	for attempt := 0; attempt < 5; attempt++ {
+		resp = ctx.ZAPHandler().Authenticate(zap.Request{Mechanism: "NULL"})
+		if resp.StatusCode == zap.StatusSuccess {
+			return
+		}
+	}
+	t.Fatalf("expected status %s, got %s: %s", zap.StatusSuccess, resp.StatusCode, resp.StatusText)
+}
+
+// zapCase configures a server and client of one mechanism, and says which
+// credentials the server's handler should be asked about.
+type zapCase struct {
+	mechanism   string
+	server      map[string]any
+	client      map[string]any
+	credentials [][]byte
+}
+
+// zapCases returns a case for NULL with a domain, PLAIN and CURVE.
+func zapCases(t *testing.T) []zapCase {
+	t.Helper()
+	serverPub, serverSec, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientPub, clientSec, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return []zapCase{{
+		mechanism: "NULL",
+		server:    map[string]any{zmtp.OptionZAPDomain: "global"},
+	}, {
+		mechanism:   "PLAIN",
+		server:      map[string]any{zmtp.OptionServer: true, zmtp.OptionZAPDomain: "global"},
+		client:      map[string]any{zmtp.OptionPlainUsername: "user", zmtp.OptionPlainPassword: "secret"},
+		credentials: [][]byte{[]byte("user"), []byte("secret")},
+	}, {
+		mechanism: "CURVE",
+		server:    map[string]any{zmtp.OptionServer: true, zmtp.OptionSecKey: serverSec[:], zmtp.OptionZAPDomain: "global"},
+		client: map[string]any{
+			zmtp.OptionPubKey: clientPub[:],
+			zmtp.OptionSecKey: clientSec[:],
+			zmtp.OptionSrvKey: serverPub[:],
+		},
+		credentials: [][]byte{clientPub[:]},
+	}}
+}
+
+// setOptions sets each option in order of the keys the mechanisms need
+// first.
+func setOptions(t *testing.T, set func(string, any) error, options map[string]any) {
+	t.Helper()
+	for _, option := range []string{
+		zmtp.OptionServer,
+		zmtp.OptionPubKey,
+		zmtp.OptionSecKey,
+		zmtp.OptionSrvKey,
+		zmtp.OptionPlainUsername,
+		zmtp.OptionPlainPassword,
+		zmtp.OptionZAPDomain,
+	} {
+		if val, ok := options[option]; ok {
+			if err := set(option, val); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+// zapHandshake runs the client and server handshakes of the case against
+// each other over loopback tcp, the server consulting handler. It returns
+// the metadata the server learned and each side's error.
+func zapHandshake(t *testing.T, tc zapCase, handler zap.Handler) (serverMeta zmtp.Metadata, clientErr, serverErr error) {
+	t.Helper()
+	newMechanism, ok := gomq.FindMechanism(tc.mechanism)
+	if !ok {
+		t.Fatalf("mechanism %s not registered", tc.mechanism)
+	}
+	server, client := newMechanism(), newMechanism()
+	setOptions(t, server.SetOption, tc.server)
+	if err := server.SetOption(zmtp.OptionZAPHandler, handler); err != nil {
+		t.Fatal(err)
+	}
+	setOptions(t, client.SetOption, tc.client)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	clientConn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clientConn.Close()
+	serverConn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serverConn.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	clientConn.SetDeadline(deadline)
+	serverConn.SetDeadline(deadline)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		meta := zmtp.Metadata{}
+		meta.AddProperty("Socket-Type", "PULL")
+		meta.AddProperty("Identity", "server")
+		_, serverMeta, serverErr = server.Handshake(serverConn, meta)
+		if serverErr != nil {
+			serverConn.Close()
+		}
+	}()
+
+	meta := zmtp.Metadata{}
+	meta.AddProperty("Socket-Type", "PUSH")
+	_, _, clientErr = client.Handshake(clientConn, meta)
+	<-done
+	return serverMeta, clientErr, serverErr
+}
+
+// TestZAPMechanisms checks that each mechanism asks the handler about the
+// client, passing on the User-Id and metadata of an accepted client and
+// failing both sides for a denied one.
+func TestZAPMechanisms(t *testing.T) {
+	for _, tc := range zapCases(t) {
+		t.Run(tc.mechanism, func(t *testing.T) {
+			var got zap.Request
+			serverMeta, clientErr, serverErr := zapHandshake(t, tc, zap.HandlerFunc(func(req zap.Request) zap.Response {
+				got = req
+				meta := zmtp.Metadata{}
+				meta.AddProperty("Role", "admin")
+				return zap.Response{StatusCode: zap.StatusSuccess, StatusText: "OK", UserID: "alice", Metadata: meta}
+			}))
+			if clientErr != nil || serverErr != nil {
+				t.Fatalf("expected the handshake to succeed, got %v and %v", clientErr, serverErr)
+			}
+
+			if got.Mechanism != tc.mechanism || got.Domain != "global" || got.Address != "127.0.0.1" || string(got.Identity) != "server" {
+				t.Errorf("unexpected request %+v", got)
+			}
+			if len(got.Credentials) != len(tc.credentials) {
+				t.Fatalf("expected %d credentials, got %d", len(tc.credentials), len(got.Credentials))
+			}
+			for idx, credential := range tc.credentials {
+				if !bytes.Equal(got.Credentials[idx], credential) {
+					t.Errorf("expected credential %d to be %q, got %q", idx, credential, got.Credentials[idx])
+				}
+			}
+
+			for name, want := range map[string]string{"User-Id": "alice", "Role": "admin", "Socket-Type": "PUSH"} {
+				if value, _ := serverMeta.Property(name); value != want {
+					t.Errorf("expected %s %q in the peer metadata, got %q", name, want, value)
+				}
+			}
+
+			_, clientErr, serverErr = zapHandshake(t, tc, zap.HandlerFunc(func(req zap.Request) zap.Response {
+				return zap.Response{StatusCode: zap.StatusFailure, StatusText: "Not allowed"}
+			}))
+			if !errors.Is(serverErr, zap.ErrDenied) {
+				t.Fatalf("expected ErrDenied, got %v", serverErr)
+			}
+			if clientErr == nil {
+				t.Fatal("expected the denied client to fail")
+			}
+		})
+	}
+}
+
+// TestZAPNullWithoutDomain checks that NULL consults the handler only when
+// a domain is set, as RFC 27 asks.
+func TestZAPNullWithoutDomain(t *testing.T) {
+	_, clientErr, serverErr := zapHandshake(t, zapCase{mechanism: "NULL"}, zap.HandlerFunc(func(req zap.Request) zap.Response {
+		t.Error("handler consulted without a domain")
+		return zap.Response{StatusCode: zap.StatusFailure}
+	}))
+	if clientErr != nil || serverErr != nil {
+		t.Fatalf("expected the handshake to succeed, got %v and %v", clientErr, serverErr)
+	}
+}
+
+// TestZAPSockets checks that a handler set on the context decides whether
+// sockets of each mechanism may connect.
+func TestZAPSockets(t *testing.T) {
+	for _, tc := range zapCases(t) {
+		for _, allow := range []bool{true, false} {
+			name := tc.mechanism + "/accept"
+			if !allow {
+				name = tc.mechanism + "/deny"
+			}
+			t.Run(name, func(t *testing.T) {
+				ctx := gomq.NewContext(context.Background())
+				ctx.SetEventBus(nil)
+				ctx.SetZAPHandler(zap.HandlerFunc(func(req zap.Request) zap.Response {
+					if !allow {
+						return zap.Response{StatusCode: zap.StatusFailure, StatusText: "Not allowed"}
+					}
+					return zap.Response{StatusCode: zap.StatusSuccess, StatusText: "OK"}
+				}))
+
+				pull, err := ctx.NewSocket("PULL", tc.mechanism)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer pull.Close()
+				pull.SetOption(gomq.OptionRecvTimeout, 5*time.Second)
+				setOptions(t, pull.SetOption, tc.server)
+				push, err := ctx.NewSocket("PUSH", tc.mechanism)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer push.Close()
+				setOptions(t, push.SetOption, tc.client)
+
+				events := pull.Monitor(gomq.Events(gomq.EventTypeListening, gomq.EventTypeFailedHandshake))
+				if err := pull.Bind("tcp://127.0.0.1:0"); err != nil {
+					t.Fatal(err)
+				}
+				addr := (<-events).LocalAddr
+
+				if !allow {
+					push.Connect(addr)
+					select {
+					case ev := <-events:
+						if !errors.Is(ev.Err, zap.ErrDenied) {
+							t.Fatalf("expected the handshake to fail with ErrDenied, got %v", ev.Err)
+						}
+					case <-time.After(5 * time.Second):
+						t.Fatal("expected the handshake to fail")
+					}
+					return
+				}
+
+				if err := push.Connect(addr); err != nil {
+					t.Fatal(err)
+				}
+				if err := push.Send([][]byte{[]byte("hello")}); err != nil {
+					t.Fatal(err)
+				}
+				msg, err := pull.Recv()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(msg) != 1 || string(msg[0]) != "hello" {
+					t.Fatalf("expected hello, got %q", msg)
+				}
+			})
+		}
+	}
+}
@@ -11,6 +11,16 @@ type Command struct {
 	Body []byte
 }
 
+// ErrorCommand builds an ERROR command carrying the reason, truncated to 255
+// bytes.
+func ErrorCommand(reason string) Command {
+	if len(reason) > 255 {
+		reason = reason[:255]
+	}
+
+	return Command{Name: "ERROR", Body: append([]byte{byte(len(reason))}, reason...)}
+}
+
 // WriteTo writes a command to the given writer.
 func (c Command) WriteTo(w io.Writer) (int64, error) {
 	total := int64(0)
@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"net"
 
+	"github.com/workspace-9/gomq/zap"
 	"github.com/workspace-9/gomq/zmtp"
 )
 
 type Curve struct {
 	serv *CurveServer
 	cli  *CurveClient
+	zap  zap.Settings
 }
 
 func (c *Curve) Name() string {
@@ -8,6 +8,10 @@ import (
 )
 
 func (c *Curve) SetOption(option string, val any) error {
+	if ok, err := c.zap.SetOption(option, val); ok {
+		return err
+	}
+
 	switch option {
 	case zmtp.OptionServer:
 		serv, ok := val.(bool)
@@ -103,7 +107,7 @@ func (c *Curve) SetOption(option string, val any) error {
 }
 
 func (c *Curve) SetupServer() {
-	c.serv = &CurveServer{}
+	c.serv = &CurveServer{zap: &c.zap}
 	c.cli = nil
 }
 
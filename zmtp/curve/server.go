@@ -6,6 +6,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/workspace-9/gomq/zap"
 	"github.com/workspace-9/gomq/zmtp"
 	"golang.org/x/crypto/curve25519"
 	"golang.org/x/crypto/nacl/box"
@@ -14,6 +15,7 @@ import (
 
 type CurveServer struct {
 	pubKey, privKey [32]byte
+	zap             *zap.Settings
 }
 
 func (c *CurveServer) Handshake(conn net.Conn, meta zmtp.Metadata) (
@@ -33,11 +35,19 @@ func (c *CurveServer) Handshake(conn net.Conn, meta zmtp.Metadata) (
 		return nil, nil, fmt.Errorf("Failed sending welcome: %w", err)
 	}
 
-	clientMeta, err := c.doInitiate(&nonce, conn, &cookieKey, &clientTransPubKey, &servTransSecKey)
+	clientMeta, clientPermPubKey, err := c.doInitiate(&nonce, conn, &cookieKey, &clientTransPubKey, &servTransSecKey)
 	if err != nil {
 		return nil, nil, fmt.Errorf("Client initiate failed: %w", err)
 	}
 
+	if c.zap != nil {
+		clientMeta, err = c.zap.Authenticate(conn, MechName, meta, [][]byte{clientPermPubKey[:]}, clientMeta)
+		if err != nil {
+			zmtp.ErrorCommand(err.Error()).WriteTo(conn)
+			return nil, nil, err
+		}
+	}
+
 	if err := c.doReady(conn, meta, &clientTransPubKey, &servTransSecKey); err != nil {
 		return nil, nil, err
 	}
@@ -133,6 +143,7 @@ func (c *CurveServer) doInitiate(
 	cookieKey, clientTransPubKey, serverTransSecKey *[32]byte,
 ) (
 	clientMeta zmtp.Metadata,
+	clientPermPublicKey [32]byte,
 	err error,
 ) {
 	conn.SetDeadline(time.Now().Add(time.Second * 60))
@@ -180,7 +191,6 @@ func (c *CurveServer) doInitiate(
 		return
 	}
 
-	var clientPermPublicKey [32]byte
 	copy(clientPermPublicKey[:], initBox[:32])
 	vouch := initBox[32:128]
 	clientMeta = zmtp.Metadata(initBox[128:])
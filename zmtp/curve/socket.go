@@ -71,26 +71,62 @@ func (c *CurveSocket) processMessage(body []byte) (ret zmtp.CommandOrMessage, er
 		return zmtp.CommandOrMessage{}, fmt.Errorf("Failed opening message box")
 	}
 
+	if out[0]&flagCommand != 0 {
+		return c.processCommand(out[1:])
+	}
+
 	ret.IsMessage = true
 	ret.Message = &zmtp.Message{
-		More: (out[0] & 0x1) == 1, Body: out[1:],
+		More: (out[0] & flagMore) == 1, Body: out[1:],
+	}
+	return ret, nil
+}
+
+func (c *CurveSocket) processCommand(data []byte) (ret zmtp.CommandOrMessage, err error) {
+	if len(data) == 0 || int(data[0]) > len(data)-1 {
+		err = fmt.Errorf("%w: name length > body size", zmtp.ErrInvalidNameLength)
+		return
+	}
+
+	nameLen := int(data[0])
+	ret.Command = &zmtp.Command{
+		Name: string(data[1 : nameLen+1]), Body: data[nameLen+1:],
 	}
 	return ret, nil
 }
 
+const (
+	flagMore    = 0x1
+	flagCommand = 0x4
+)
+
+// SendCommand sends an ERROR command in plaintext as required by the
+// CurveZMQ spec, any other command is sealed inside a MESSAGE box.
 func (c *CurveSocket) SendCommand(cmd zmtp.Command) error {
-	if cmd.Name != "ERROR" {
-		return fmt.Errorf("Expected error command, got %s", cmd.Name)
+	if cmd.Name == "ERROR" {
+		_, err := cmd.WriteTo(c.Conn)
+		return err
 	}
 
-	_, err := cmd.WriteTo(c.Conn)
-	return err
+	payload := make([]byte, 1+len(cmd.Name)+len(cmd.Body))
+	payload[0] = byte(len(cmd.Name))
+	copy(payload[1:], cmd.Name)
+	copy(payload[1+len(cmd.Name):], cmd.Body)
+	return c.sendBox(flagCommand, payload)
 }
 
 func (c *CurveSocket) SendMessage(msg zmtp.Message) error {
+	var flags byte
+	if msg.More {
+		flags = flagMore
+	}
+	return c.sendBox(flags, msg.Body)
+}
+
+func (c *CurveSocket) sendBox(flags byte, payload []byte) error {
 	defer func() { c.nonceIdx++ }()
 	cmd := zmtp.Command{Name: "MESSAGE"}
-	body := make([]byte, 8+17+len(msg.Body))
+	body := make([]byte, 8+17+len(payload))
 	binary.BigEndian.AppendUint64(body[0:0], c.nonceIdx)
 
 	var nonce [24]byte
@@ -100,11 +136,9 @@ func (c *CurveSocket) SendMessage(msg zmtp.Message) error {
 		copy(nonce[:], []byte("CurveZMQMESSAGEC"))
 	}
 	binary.BigEndian.AppendUint64(nonce[16:16], c.nonceIdx)
-	toSeal := make([]byte, 1+len(msg.Body))
-	if msg.More {
-		toSeal[0] = 0x1
-	}
-	copy(toSeal[1:], msg.Body)
+	toSeal := make([]byte, 1+len(payload))
+	toSeal[0] = flags
+	copy(toSeal[1:], payload)
 	box.SealAfterPrecomputation(body[8:8], toSeal, &nonce, &c.sharedKey)
 	cmd.Body = body
 	_, err := cmd.WriteTo(&overrideFirstByteWriter{
@@ -85,6 +85,48 @@ func (g *Greeting) WriteTo(w io.Writer) (int64, error) {
 	return int64(n), err
 }
 
+// RevisionV2 is the version major sent by ZMTP 2.0 peers, whose greeting
+// continues differently from byte 11.
+const RevisionV2 = 0x01
+
+// ExchangeGreeting sends the greeting and reads the peer's in the stages of
+// RFC 23, so that older peers can be detected: the signature first, then
+// the version major, and the rest only if the peer speaks ZMTP 3.0 or
+// later. If the peer's version major is RevisionV2, only its signature and
+// version major are returned and the exchange continues with ExchangeV2.
+func ExchangeGreeting(rw io.ReadWriter, g *Greeting) (Greeting, error) {
+	var peer Greeting
+	if _, err := rw.Write(g[:10]); err != nil {
+		return peer, err
+	}
+	if _, err := io.ReadFull(rw, peer[:10]); err != nil {
+		return peer, err
+	}
+	// ZMTP 1.0 peers send no signature.
+	if peer[0] != 0xFF || peer[9]&0x01 == 0 {
+		return peer, fmt.Errorf("%w: ZMTP 1.0", ErrUnsupportedVersion)
+	}
+
+	if _, err := rw.Write(g[10:11]); err != nil {
+		return peer, err
+	}
+	if _, err := io.ReadFull(rw, peer[10:11]); err != nil {
+		return peer, err
+	}
+	switch major := peer.VersionMajor(); {
+	case major == RevisionV2:
+		return peer, nil
+	case major < 3:
+		return peer, fmt.Errorf("%w: revision %d", ErrUnsupportedVersion, major)
+	}
+
+	if _, err := rw.Write(g[11:]); err != nil {
+		return peer, err
+	}
+	_, err := io.ReadFull(rw, peer[11:])
+	return peer, err
+}
+
 // ReadFrom reads the greeting from a reader.
 func (g *Greeting) ReadFrom(r io.Reader) (int64, error) {
 	n, err := io.ReadFull(r, g[:])
@@ -98,3 +140,11 @@ func (mechMismatch) Error() string {
 }
 
 var ErrMechMismatch mechMismatch
+
+type unsupportedVersion struct{}
+
+func (unsupportedVersion) Error() string {
+	return "Unsupported ZMTP version"
+}
+
+var ErrUnsupportedVersion unsupportedVersion
@@ -0,0 +1,158 @@
+package zmtp_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// signature is the start of every greeting since ZMTP 2.0.
+var signature = []byte{0xFF, 0, 0, 0, 0, 0, 0, 0, 0, 0x7F}
+
+// pipe returns both ends of a loopback TCP connection, which unlike
+// net.Pipe lets both ends write before reading.
+func pipe(t *testing.T) (net.Conn, net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer, err := ln.Accept()
+	if err != nil {
+		conn.Close()
+		t.Fatal(err)
+	}
+	return conn, peer
+}
+
+func newGreeting() zmtp.Greeting {
+	greeting := zmtp.NewGreeting()
+	greeting.SetVersionMajor(3)
+	greeting.SetVersionMinor(1)
+	greeting.SetMechanism("NULL")
+	return greeting
+}
+
+// TestExchangeV2 checks that a ZMTP 2.0 peer is detected before the rest of
+// the greeting is sent, and that its socket type and identity are returned
+// as metadata.
+func TestExchangeV2(t *testing.T) {
+	conn, peer := pipe(t)
+	defer conn.Close()
+	defer peer.Close()
+
+	peerErr := make(chan error, 1)
+	go func() {
+		peerErr <- func() error {
+			// Signature and revision, socket type PULL and the identity abc.
+			if _, err := peer.Write(append(append([]byte{}, signature...), 0x01)); err != nil {
+				return err
+			}
+			if _, err := peer.Write([]byte{0x07, 0x00, 0x03, 'a', 'b', 'c'}); err != nil {
+				return err
+			}
+
+			// Signature, major version, socket type PUSH and no identity.
+			got := make([]byte, 14)
+			if _, err := io.ReadFull(peer, got); err != nil {
+				return err
+			}
+			want := append(append([]byte{}, signature...), 3, 0x08, 0x00, 0x00)
+			if !bytes.Equal(got, want) {
+				return fmt.Errorf("unexpected greeting %x", got)
+			}
+			return nil
+		}()
+	}()
+
+	greeting := newGreeting()
+	peerGreeting, err := zmtp.ExchangeGreeting(conn, &greeting)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peerGreeting.VersionMajor() != zmtp.RevisionV2 {
+		t.Fatalf("expected revision %d, got %d", zmtp.RevisionV2, peerGreeting.VersionMajor())
+	}
+
+	var meta zmtp.Metadata
+	meta.AddProperty("Socket-Type", "PUSH")
+	peerMeta, err := zmtp.ExchangeV2(conn, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-peerErr; err != nil {
+		t.Fatal(err)
+	}
+
+	if socketType, _ := peerMeta.Property("Socket-Type"); socketType != "PULL" {
+		t.Fatalf("expected Socket-Type PULL, got %q", socketType)
+	}
+	if identity, _ := peerMeta.Property("Identity"); identity != "abc" {
+		t.Fatalf("expected Identity abc, got %q", identity)
+	}
+}
+
+// TestExchangeV2UnknownSocketType checks that socket types ZMTP 2.0 does not
+// know are refused.
+func TestExchangeV2UnknownSocketType(t *testing.T) {
+	var meta zmtp.Metadata
+	meta.AddProperty("Socket-Type", "CLIENT")
+	if _, err := zmtp.ExchangeV2(&bytes.Buffer{}, meta); !errors.Is(err, zmtp.ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
+
+// TestExchangeGreetingV3 checks that a ZMTP 3.x peer's whole greeting is
+// read.
+func TestExchangeGreetingV3(t *testing.T) {
+	conn, peer := pipe(t)
+	defer conn.Close()
+	defer peer.Close()
+
+	peerGreeting := newGreeting()
+	peerGreeting.SetVersionMinor(0)
+	peerGreeting.SetServer(true)
+	go func() {
+		peer.Write(peerGreeting[:])
+		io.ReadFull(peer, make([]byte, len(peerGreeting)))
+	}()
+
+	greeting := newGreeting()
+	got, err := zmtp.ExchangeGreeting(conn, &greeting)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != peerGreeting {
+		t.Fatalf("expected %s, got %s", peerGreeting.String(), got.String())
+	}
+}
+
+// TestExchangeGreetingV1 checks that ZMTP 1.0 peers, which send no
+// signature, are refused.
+func TestExchangeGreetingV1(t *testing.T) {
+	conn, peer := pipe(t)
+	defer conn.Close()
+	defer peer.Close()
+
+	go func() {
+		// A ZMTP 1.0 identity frame: length 1, flags 0.
+		peer.Write([]byte{0x01, 0x00, 0, 0, 0, 0, 0, 0, 0, 0})
+		io.Copy(io.Discard, peer)
+	}()
+
+	greeting := newGreeting()
+	if _, err := zmtp.ExchangeGreeting(conn, &greeting); !errors.Is(err, zmtp.ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
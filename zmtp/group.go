@@ -0,0 +1,39 @@
+package zmtp
+
+const (
+	// CommandJoin is the ZMTP 3.1 command a DISH sends to receive a group.
+	CommandJoin = "JOIN"
+
+	// CommandLeave is the ZMTP 3.1 command a DISH sends to drop a group.
+	CommandLeave = "LEAVE"
+
+	// MaxGroupLen is the length of the longest group.
+	MaxGroupLen = 255
+)
+
+// JoinCommand builds the command joining group.
+func JoinCommand(group string) Command {
+	return Command{Name: CommandJoin, Body: []byte(group)}
+}
+
+// LeaveCommand builds the command leaving group.
+func LeaveCommand(group string) Command {
+	return Command{Name: CommandLeave, Body: []byte(group)}
+}
+
+// ParseGroupCommand interprets traffic from a DISH. ok is false when the
+// traffic is not a JOIN or LEAVE command.
+func ParseGroupCommand(next CommandOrMessage) (join bool, group string, ok bool) {
+	if next.IsMessage {
+		return false, "", false
+	}
+
+	switch next.Command.Name {
+	case CommandJoin:
+		return true, string(next.Command.Body), true
+	case CommandLeave:
+		return false, string(next.Command.Body), true
+	}
+
+	return false, "", false
+}
@@ -0,0 +1,51 @@
+package zmtp
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+const (
+	// CommandPing is the ZMTP 3.1 command asking the peer to prove it is alive.
+	CommandPing = "PING"
+
+	// CommandPong is the ZMTP 3.1 reply to a PING.
+	CommandPong = "PONG"
+
+	// maxPingContext is the most context a PING may carry.
+	maxPingContext = 16
+)
+
+// PingCommand builds a PING asking the peer to close the connection if it
+// hears nothing for ttl, which is sent in deciseconds. The context is echoed
+// back in the PONG and truncated to 16 bytes.
+func PingCommand(ttl time.Duration, context []byte) Command {
+	if len(context) > maxPingContext {
+		context = context[:maxPingContext]
+	}
+
+	deciseconds := ttl / (100 * time.Millisecond)
+	if deciseconds > 0xffff {
+		deciseconds = 0xffff
+	}
+
+	body := make([]byte, 2, 2+len(context))
+	binary.BigEndian.PutUint16(body, uint16(deciseconds))
+	return Command{Name: CommandPing, Body: append(body, context...)}
+}
+
+// PongCommand builds the reply to a PING carrying context.
+func PongCommand(context []byte) Command {
+	return Command{Name: CommandPong, Body: context}
+}
+
+// ParsePing returns the ttl and context of a PING command. ok is false if
+// the command is not a well formed PING.
+func ParsePing(cmd Command) (ttl time.Duration, context []byte, ok bool) {
+	if cmd.Name != CommandPing || len(cmd.Body) < 2 || len(cmd.Body) > 2+maxPingContext {
+		return 0, nil, false
+	}
+
+	ttl = time.Duration(binary.BigEndian.Uint16(cmd.Body)) * 100 * time.Millisecond
+	return ttl, cmd.Body[2:], true
+}
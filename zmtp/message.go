@@ -9,6 +9,15 @@ import (
 type Message struct {
 	More bool
 	Body []byte
+
+	// Group is the group a RADIO sends the message to, or a DISH received
+	// it from. It is not written with the frame; socket types using groups
+	// carry it themselves.
+	Group string
+
+	// RoutingID names the peer a SERVER received the message from or sends
+	// it to. Like Group, it is not written with the frame.
+	RoutingID uint32
 }
 
 // WriteTo writes a message to the given writer.
@@ -21,7 +21,7 @@ func (m Metadata) Properties(f func(name string, value string)) error {
 		name := string(m[idx : idx+nameLen])
 		idx += nameLen
 
-		if idx+4 >= len(m) {
+		if idx+4 > len(m) {
 			return fmt.Errorf("%w: not enough bytes for next value name", ErrInvalidMetadata)
 		}
 		valueLen := int(binary.BigEndian.Uint32(m[idx:]))
@@ -39,6 +39,16 @@ func (m Metadata) Properties(f func(name string, value string)) error {
 	return nil
 }
 
+// Property returns the value of the named property, and whether it was found.
+func (m Metadata) Property(name string) (value string, ok bool) {
+	m.Properties(func(n string, v string) {
+		if n == name && !ok {
+			value, ok = v, true
+		}
+	})
+	return
+}
+
 // AddProperty to the Metadata.
 func (m *Metadata) AddProperty(name string, value string) error {
 	buffer := bytes.NewBuffer(*m)
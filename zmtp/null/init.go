@@ -9,6 +9,6 @@ const MechName = "NULL"
 
 func init() {
 	gomq.RegisterMechanism(MechName, func() zmtp.Mechanism {
-		return Null{}
+		return &Null{}
 	})
 }
@@ -2,11 +2,14 @@ package null
 
 import (
 	"fmt"
+	"github.com/workspace-9/gomq/zap"
 	"github.com/workspace-9/gomq/zmtp"
 	"net"
 )
 
-type Null struct{}
+type Null struct {
+	zap zap.Settings
+}
 
 func (Null) Name() string {
 	return MechName
@@ -33,12 +36,23 @@ func (cannotBeServer) Error() string {
 
 var ErrCannotBeServer cannotBeServer
 
-// Handshake performs a null mechanism handshake.
-func (Null) Handshake(conn net.Conn, meta zmtp.Metadata) (
+// Handshake performs a null mechanism handshake. If both a zap handler and
+// domain are set, the handler is consulted before sending READY.
+func (n Null) Handshake(conn net.Conn, meta zmtp.Metadata) (
 	zmtp.Socket,
 	zmtp.Metadata,
 	error,
 ) {
+	var zapMeta zmtp.Metadata
+	if n.zap.Domain != "" {
+		var err error
+		zapMeta, err = n.zap.Authenticate(conn, MechName, meta, nil, nil)
+		if err != nil {
+			zmtp.ErrorCommand(err.Error()).WriteTo(conn)
+			return nil, nil, err
+		}
+	}
+
 	var cmd zmtp.Command
 	cmd.Name = "READY"
 	cmd.Body = meta
@@ -54,7 +68,31 @@ func (Null) Handshake(conn net.Conn, meta zmtp.Metadata) (
 		return nil, nil, fmt.Errorf("%w: received %s", ErrNotReady, cmd.Name)
 	}
 
-	return NullSocket{conn}, zmtp.Metadata(cmd.Body), nil
+	return NullSocket{conn}, append(zmtp.Metadata(cmd.Body), zapMeta...), nil
+}
+
+// HandshakeV2 sets up a ZMTP 2.0 connection, which is unauthenticated like
+// NULL. The zap handler is still consulted if set.
+func (n Null) HandshakeV2(conn net.Conn, meta zmtp.Metadata) (
+	zmtp.Socket,
+	zmtp.Metadata,
+	error,
+) {
+	var zapMeta zmtp.Metadata
+	if n.zap.Domain != "" {
+		var err error
+		zapMeta, err = n.zap.Authenticate(conn, MechName, meta, nil, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	peerMeta, err := zmtp.ExchangeV2(conn, meta)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return zmtp.V2Socket{Conn: conn}, append(peerMeta, zapMeta...), nil
 }
 
 type notReady struct{}
@@ -73,7 +111,12 @@ func (noOptions) Error() string {
 
 var ErrNoOptions noOptions
 
-func (n Null) SetOption(string, any) error {
+// SetOption accepts only the zap options.
+func (n *Null) SetOption(option string, val any) error {
+	if ok, err := n.zap.SetOption(option, val); ok {
+		return err
+	}
+
 	return ErrNoOptions
 }
 
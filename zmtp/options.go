@@ -5,4 +5,11 @@ const (
 	OptionPubKey = "pubkey"
 	OptionSecKey = "seckey"
 	OptionSrvKey = "srvkey"
+
+	OptionPlainUsername = "plain_username"
+	OptionPlainPassword = "plain_password"
+	OptionPlainVerifier = "plain_verifier"
+
+	OptionZAPHandler = "zap_handler"
+	OptionZAPDomain  = "zap_domain"
 )
@@ -0,0 +1,14 @@
+package plain
+
+import (
+	"github.com/workspace-9/gomq"
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+const MechName = "PLAIN"
+
+func init() {
+	gomq.RegisterMechanism(MechName, func() zmtp.Mechanism {
+		return &Plain{}
+	})
+}
@@ -0,0 +1,57 @@
+package plain
+
+import (
+	"fmt"
+
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+func (p *Plain) SetOption(option string, val any) error {
+	if ok, err := p.zap.SetOption(option, val); ok {
+		return err
+	}
+
+	switch option {
+	case zmtp.OptionServer:
+		serv, ok := val.(bool)
+		if !ok {
+			return fmt.Errorf("Value for option %s must be bool, got %T", option, val)
+		}
+		p.server = serv
+	case zmtp.OptionPlainUsername, zmtp.OptionPlainPassword:
+		var str string
+		switch data := val.(type) {
+		case string:
+			str = data
+		case []byte:
+			str = string(data)
+		default:
+			return fmt.Errorf("Value for option %s must be string or []byte, got %T", option, val)
+		}
+
+		if len(str) > 255 {
+			return fmt.Errorf("Value for option %s must be at most 255 bytes, got %d", option, len(str))
+		}
+
+		if option == zmtp.OptionPlainUsername {
+			p.username = str
+		} else {
+			p.password = str
+		}
+	case zmtp.OptionPlainVerifier:
+		switch verifier := val.(type) {
+		case Verifier:
+			p.verifier = verifier
+		case func(username, password string) error:
+			p.verifier = verifier
+		case nil:
+			p.verifier = nil
+		default:
+			return fmt.Errorf("Value for option %s must be plain.Verifier, got %T", option, val)
+		}
+	default:
+		return fmt.Errorf("Unknown option for plain mechanism: %s", option)
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package plain
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/workspace-9/gomq/zap"
+	"github.com/workspace-9/gomq/zmtp"
+	"github.com/workspace-9/gomq/zmtp/null"
+)
+
+// Verifier decides whether a client may connect with the given credentials,
+// returning an error to reject it.
+type Verifier func(username, password string) error
+
+type Plain struct {
+	server   bool
+	username string
+	password string
+	verifier Verifier
+	zap      zap.Settings
+}
+
+func (p *Plain) Name() string {
+	return MechName
+}
+
+func (p *Plain) Server() bool {
+	return p.server
+}
+
+// ValidateGreeting ensures the other side uses the plain mechanism and takes
+// the opposite role.
+func (p *Plain) ValidateGreeting(g *zmtp.Greeting) error {
+	if g.Mechanism() != MechName {
+		return fmt.Errorf("%w: expected %s", zmtp.ErrMechMismatch, MechName)
+	}
+
+	if g.Server() && p.server {
+		return ErrBothServers
+	}
+
+	if !g.Server() && !p.server {
+		return ErrBothClients
+	}
+
+	return nil
+}
+
+type bothClients struct{}
+
+func (bothClients) Error() string {
+	return "Cannot connect two plain clients"
+}
+
+var ErrBothClients bothClients
+
+type bothServers struct{}
+
+func (bothServers) Error() string {
+	return "Cannot connect two plain servers"
+}
+
+var ErrBothServers bothServers
+
+// Handshake performs a plain mechanism handshake.
+func (p *Plain) Handshake(conn net.Conn, meta zmtp.Metadata) (
+	zmtp.Socket,
+	zmtp.Metadata,
+	error,
+) {
+	var peerMeta zmtp.Metadata
+	var err error
+	if p.server {
+		peerMeta, err = p.serverHandshake(conn, meta)
+	} else {
+		peerMeta, err = p.clientHandshake(conn, meta)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return null.NullSocket{Conn: conn}, peerMeta, nil
+}
+
+func (p *Plain) clientHandshake(conn net.Conn, meta zmtp.Metadata) (zmtp.Metadata, error) {
+	hello := zmtp.Command{Name: "HELLO"}
+	hello.Body = make([]byte, 0, 2+len(p.username)+len(p.password))
+	hello.Body = append(hello.Body, byte(len(p.username)))
+	hello.Body = append(hello.Body, p.username...)
+	hello.Body = append(hello.Body, byte(len(p.password)))
+	hello.Body = append(hello.Body, p.password...)
+	if _, err := hello.WriteTo(conn); err != nil {
+		return nil, fmt.Errorf("Failed sending hello to server: %w", err)
+	}
+
+	if _, err := expect(conn, "WELCOME"); err != nil {
+		return nil, fmt.Errorf("Failed welcome: %w", err)
+	}
+
+	initiate := zmtp.Command{Name: "INITIATE", Body: meta}
+	if _, err := initiate.WriteTo(conn); err != nil {
+		return nil, fmt.Errorf("Failed initiate: %w", err)
+	}
+
+	ready, err := expect(conn, "READY")
+	if err != nil {
+		return nil, fmt.Errorf("Failed ready: %w", err)
+	}
+
+	return zmtp.Metadata(ready.Body), nil
+}
+
+func (p *Plain) serverHandshake(conn net.Conn, meta zmtp.Metadata) (zmtp.Metadata, error) {
+	hello, err := expect(conn, "HELLO")
+	if err != nil {
+		return nil, fmt.Errorf("Client hello failed: %w", err)
+	}
+
+	username, password, err := parseHello(hello.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Client hello failed: %w", err)
+	}
+
+	if p.verifier != nil {
+		if err := p.verifier(username, password); err != nil {
+			zmtp.ErrorCommand(err.Error()).WriteTo(conn)
+			return nil, fmt.Errorf("%w: %s", ErrRejected, err.Error())
+		}
+	}
+
+	credentials := [][]byte{[]byte(username), []byte(password)}
+	zapMeta, err := p.zap.Authenticate(conn, MechName, meta, credentials, nil)
+	if err != nil {
+		zmtp.ErrorCommand(err.Error()).WriteTo(conn)
+		return nil, err
+	}
+
+	welcome := zmtp.Command{Name: "WELCOME"}
+	if _, err := welcome.WriteTo(conn); err != nil {
+		return nil, fmt.Errorf("Failed sending welcome: %w", err)
+	}
+
+	initiate, err := expect(conn, "INITIATE")
+	if err != nil {
+		return nil, fmt.Errorf("Client initiate failed: %w", err)
+	}
+
+	ready := zmtp.Command{Name: "READY", Body: meta}
+	if _, err := ready.WriteTo(conn); err != nil {
+		return nil, err
+	}
+
+	return append(zmtp.Metadata(initiate.Body), zapMeta...), nil
+}
+
+// expect reads the next command, failing if it is not named name. An ERROR
+// command from the peer is returned as an error.
+func expect(conn net.Conn, name string) (zmtp.Command, error) {
+	var cmd zmtp.Command
+	if _, err := cmd.ReadFrom(conn); err != nil {
+		return cmd, err
+	}
+
+	if cmd.Name == "ERROR" {
+		reason := cmd.Body
+		if len(reason) > 0 && int(reason[0]) <= len(reason)-1 {
+			reason = reason[1 : 1+int(reason[0])]
+		}
+		return cmd, fmt.Errorf("Peer sent error: %s", string(reason))
+	}
+
+	if cmd.Name != name {
+		return cmd, fmt.Errorf("Expected %s command, got %s", name, cmd.Name)
+	}
+
+	return cmd, nil
+}
+
+func parseHello(body []byte) (username, password string, err error) {
+	if len(body) < 1 || int(body[0]) > len(body)-2 {
+		err = fmt.Errorf("Invalid hello: username length exceeds body")
+		return
+	}
+	username = string(body[1 : 1+body[0]])
+	body = body[1+body[0]:]
+
+	if int(body[0]) != len(body)-1 {
+		err = fmt.Errorf("Invalid hello: password length does not match body")
+		return
+	}
+	password = string(body[1:])
+	return
+}
+
+type rejected struct{}
+
+func (rejected) Error() string {
+	return "Credentials rejected"
+}
+
+var ErrRejected rejected
@@ -0,0 +1,169 @@
+package plain_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/workspace-9/gomq"
+	_ "github.com/workspace-9/gomq/transport/tcp"
+	"github.com/workspace-9/gomq/types"
+	_ "github.com/workspace-9/gomq/types/pull"
+	_ "github.com/workspace-9/gomq/types/push"
+	"github.com/workspace-9/gomq/zmtp"
+	"github.com/workspace-9/gomq/zmtp/plain"
+)
+
+// verifier accepts only user with the password secret.
+func verifier(username, password string) error {
+	if username != "user" || password != "secret" {
+		return errors.New("unknown user or wrong password")
+	}
+	return nil
+}
+
+// connPair returns both ends of a loopback tcp connection, which unlike
+// net.Pipe buffers writes as the mechanisms expect.
+func connPair(t *testing.T) (net.Conn, net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	client, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := ln.Accept()
+	if err != nil {
+		client.Close()
+		t.Fatal(err)
+	}
+	return client, server
+}
+
+// handshake runs the client and server handshakes against each other,
+// returning the metadata each learned and their errors.
+func handshake(t *testing.T, password string) (clientMeta, serverMeta zmtp.Metadata, clientErr, serverErr error) {
+	t.Helper()
+	server := &plain.Plain{}
+	server.SetOption(zmtp.OptionServer, true)
+	server.SetOption(zmtp.OptionPlainVerifier, plain.Verifier(verifier))
+	client := &plain.Plain{}
+	client.SetOption(zmtp.OptionPlainUsername, "user")
+	client.SetOption(zmtp.OptionPlainPassword, password)
+
+	clientConn, serverConn := connPair(t)
+	defer clientConn.Close()
+	defer serverConn.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	clientConn.SetDeadline(deadline)
+	serverConn.SetDeadline(deadline)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		meta := zmtp.Metadata{}
+		meta.AddProperty("Socket-Type", "PULL")
+		_, serverMeta, serverErr = server.Handshake(serverConn, meta)
+		if serverErr != nil {
+			serverConn.Close()
+		}
+	}()
+
+	meta := zmtp.Metadata{}
+	meta.AddProperty("Socket-Type", "PUSH")
+	_, clientMeta, clientErr = client.Handshake(clientConn, meta)
+	<-done
+	return clientMeta, serverMeta, clientErr, serverErr
+}
+
+// property returns the value of the named property of the metadata.
+func property(meta zmtp.Metadata, name string) (value string) {
+	meta.Properties(func(key, val string) {
+		if key == name {
+			value = val
+		}
+	})
+	return value
+}
+
+// TestHandshake checks that matching credentials complete the handshake,
+// exchanging metadata.
+func TestHandshake(t *testing.T) {
+	clientMeta, serverMeta, clientErr, serverErr := handshake(t, "secret")
+	if clientErr != nil || serverErr != nil {
+		t.Fatalf("expected the handshake to succeed, got %v and %v", clientErr, serverErr)
+	}
+	if got := property(clientMeta, "Socket-Type"); got != "PULL" {
+		t.Fatalf("expected the client to learn the server is a PULL, got %q", got)
+	}
+	if got := property(serverMeta, "Socket-Type"); got != "PUSH" {
+		t.Fatalf("expected the server to learn the client is a PUSH, got %q", got)
+	}
+}
+
+// TestWrongPassword checks that the verifier's rejection fails both sides,
+// telling the client why.
+func TestWrongPassword(t *testing.T) {
+	_, _, clientErr, serverErr := handshake(t, "guess")
+	if !errors.Is(serverErr, plain.ErrRejected) {
+		t.Fatalf("expected ErrRejected, got %v", serverErr)
+	}
+	if clientErr == nil || !strings.Contains(clientErr.Error(), "wrong password") {
+		t.Fatalf("expected the client to be told it was rejected, got %v", clientErr)
+	}
+}
+
+// TestSocketWrongPassword checks that a socket with the wrong password
+// never delivers its messages.
+func TestSocketWrongPassword(t *testing.T) {
+	ctx := gomq.NewContext(context.Background())
+	ctx.SetEventBus(nil)
+
+	pull, err := ctx.NewSocket("PULL", plain.MechName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pull.Close()
+	pull.SetServer(true)
+	pull.SetOption(zmtp.OptionPlainVerifier, plain.Verifier(verifier))
+	pull.SetOption(gomq.OptionRecvTimeout, 200*time.Millisecond)
+	listening := pull.Monitor(gomq.Events(gomq.EventTypeListening))
+	if err := pull.Bind("tcp://127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	addr := (<-listening).LocalAddr
+
+	push, err := ctx.NewSocket("PUSH", plain.MechName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer push.Close()
+	push.SetOption(zmtp.OptionPlainUsername, "user")
+	push.SetOption(zmtp.OptionPlainPassword, "guess")
+	failed := push.Monitor(gomq.Events(gomq.EventTypeFailedHandshake))
+	if err := push.Connect(addr); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ev := <-failed:
+		if ev.Err == nil || !strings.Contains(ev.Err.Error(), "wrong password") {
+			t.Fatalf("expected the rejection as the error, got %v", ev.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the handshake to fail")
+	}
+	if err := push.Send([][]byte{[]byte("hello")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pull.Recv(); !errors.Is(err, types.ErrWouldBlock) {
+		t.Fatalf("expected nothing to be received, got %v", err)
+	}
+}
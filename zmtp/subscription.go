@@ -0,0 +1,65 @@
+package zmtp
+
+const (
+	// CommandSubscribe is the ZMTP 3.1 command a subscriber sends to receive a topic.
+	CommandSubscribe = "SUBSCRIBE"
+
+	// CommandCancel is the ZMTP 3.1 command a subscriber sends to drop a topic.
+	CommandCancel = "CANCEL"
+)
+
+// SubscribeCommand builds the ZMTP 3.1 form of a subscription.
+func SubscribeCommand(topic []byte) Command {
+	return Command{Name: CommandSubscribe, Body: topic}
+}
+
+// CancelCommand builds the ZMTP 3.1 form of a cancelled subscription.
+func CancelCommand(topic []byte) Command {
+	return Command{Name: CommandCancel, Body: topic}
+}
+
+// SubscribeMessage builds the ZMTP 3.0 form of a subscription, a message
+// whose body is the topic prefixed by 0x01.
+func SubscribeMessage(topic []byte) Message {
+	body := make([]byte, len(topic)+1)
+	body[0] = 0x01
+	copy(body[1:], topic)
+	return Message{Body: body}
+}
+
+// CancelMessage builds the ZMTP 3.0 form of a cancelled subscription, a
+// message whose body is the topic prefixed by 0x00.
+func CancelMessage(topic []byte) Message {
+	body := make([]byte, len(topic)+1)
+	copy(body[1:], topic)
+	return Message{Body: body}
+}
+
+// ParseSubscription interprets traffic from a subscriber in either the
+// command or the message form. ok is false when the traffic is not a
+// subscription or cancellation.
+func ParseSubscription(next CommandOrMessage) (subscribe bool, topic []byte, ok bool) {
+	if !next.IsMessage {
+		switch next.Command.Name {
+		case CommandSubscribe:
+			return true, next.Command.Body, true
+		case CommandCancel:
+			return false, next.Command.Body, true
+		}
+		return false, nil, false
+	}
+
+	body := next.Message.Body
+	if next.Message.More || len(body) == 0 {
+		return false, nil, false
+	}
+
+	switch body[0] {
+	case 0x01:
+		return true, body[1:], true
+	case 0x00:
+		return false, body[1:], true
+	}
+
+	return false, nil, false
+}
@@ -0,0 +1,37 @@
+package zmtp_test
+
+import (
+	"testing"
+
+	"github.com/workspace-9/gomq/zmtp"
+)
+
+// TestParseSubscription checks that both the 3.1 command and the 3.0
+// message forms of subscriptions are understood.
+func TestParseSubscription(t *testing.T) {
+	sub := zmtp.SubscribeCommand([]byte("topic"))
+	cancel := zmtp.CancelCommand([]byte("topic"))
+	subMsg := zmtp.SubscribeMessage([]byte("topic"))
+	cancelMsg := zmtp.CancelMessage([]byte("topic"))
+	other := zmtp.Message{Body: []byte("\x02topic")}
+	more := zmtp.Message{Body: []byte("\x01topic"), More: true}
+
+	for _, test := range []struct {
+		name      string
+		next      zmtp.CommandOrMessage
+		subscribe bool
+		ok        bool
+	}{
+		{"SUBSCRIBE", zmtp.CommandOrMessage{Command: &sub}, true, true},
+		{"CANCEL", zmtp.CommandOrMessage{Command: &cancel}, false, true},
+		{"0x01", zmtp.CommandOrMessage{IsMessage: true, Message: &subMsg}, true, true},
+		{"0x00", zmtp.CommandOrMessage{IsMessage: true, Message: &cancelMsg}, false, true},
+		{"other", zmtp.CommandOrMessage{IsMessage: true, Message: &other}, false, false},
+		{"multipart", zmtp.CommandOrMessage{IsMessage: true, Message: &more}, false, false},
+	} {
+		subscribe, topic, ok := zmtp.ParseSubscription(test.next)
+		if ok != test.ok || subscribe != test.subscribe || (ok && string(topic) != "topic") {
+			t.Errorf("%s: expected %v %v, got %v %v %q", test.name, test.subscribe, test.ok, subscribe, ok, topic)
+		}
+	}
+}
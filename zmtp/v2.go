@@ -0,0 +1,109 @@
+package zmtp
+
+import (
+	"fmt"
+	"io"
+	"net"
+)
+
+// socketTypesV2 are the socket types ZMTP 2.0 knows, indexed by the code
+// sent in its greeting.
+var socketTypesV2 = []string{
+	"PAIR", "PUB", "SUB", "REQ", "REP", "DEALER", "ROUTER", "PULL", "PUSH",
+}
+
+// ExchangeV2 finishes the greeting of a ZMTP 2.0 peer (RFC 15) after
+// ExchangeGreeting, sending the socket type and identity held in meta. The
+// peer's socket type and identity are returned as metadata, the same way a
+// ZMTP 3.x peer would send them in READY.
+func ExchangeV2(rw io.ReadWriter, meta Metadata) (Metadata, error) {
+	socketType, _ := meta.Property("Socket-Type")
+	code := -1
+	for idx, name := range socketTypesV2 {
+		if name == socketType {
+			code = idx
+		}
+	}
+	if code < 0 {
+		return nil, fmt.Errorf("%w: %s sockets need ZMTP 3.x", ErrUnsupportedVersion, socketType)
+	}
+
+	identity, _ := meta.Property("Identity")
+	if _, err := rw.Write([]byte{byte(code)}); err != nil {
+		return nil, err
+	}
+	if _, err := (Message{Body: []byte(identity)}).WriteTo(rw); err != nil {
+		return nil, err
+	}
+
+	var peerCode [1]byte
+	if _, err := io.ReadFull(rw, peerCode[:]); err != nil {
+		return nil, err
+	}
+	if int(peerCode[0]) >= len(socketTypesV2) {
+		return nil, fmt.Errorf("%w: socket type %d", ErrInvalidMetadata, peerCode[0])
+	}
+
+	var peerIdentity Message
+	if _, err := readFrameV2(rw, &peerIdentity); err != nil {
+		return nil, err
+	}
+
+	var peerMeta Metadata
+	peerMeta.AddProperty("Socket-Type", socketTypesV2[peerCode[0]])
+	if len(peerIdentity.Body) > 0 {
+		peerMeta.AddProperty("Identity", string(peerIdentity.Body))
+	}
+	return peerMeta, nil
+}
+
+// readFrameV2 reads a ZMTP 2.0 frame, which may only be a message.
+func readFrameV2(r io.Reader, m *Message) (int64, error) {
+	var flags [1]byte
+	if _, err := io.ReadFull(r, flags[:]); err != nil {
+		return 0, err
+	}
+	if flags[0] > 0x03 {
+		return 1, fmt.Errorf("%w: invalid byte %x", ErrInvalidFrameHeader, flags[0])
+	}
+
+	return m.ReadFrom(io.MultiReader(ByteReader(flags[0]), r))
+}
+
+// V2Socket speaks ZMTP 2.0, whose message framing matches ZMTP 3.x but
+// which has no commands.
+type V2Socket struct {
+	net.Conn
+}
+
+func (s V2Socket) Read() (CommandOrMessage, error) {
+	var m Message
+	if _, err := readFrameV2(s.Conn, &m); err != nil {
+		return CommandOrMessage{}, err
+	}
+
+	return CommandOrMessage{IsMessage: true, Message: &m}, nil
+}
+
+func (s V2Socket) SendMessage(m Message) error {
+	_, err := m.WriteTo(s.Conn)
+	return err
+}
+
+// SendCommand fails, ZMTP 2.0 peers cannot receive commands.
+func (s V2Socket) SendCommand(cmd Command) error {
+	return fmt.Errorf("%w: %s", ErrCommandsUnsupported, cmd.Name)
+}
+
+// Net returns the underlying net.Conn for the socket.
+func (s V2Socket) Net() net.Conn {
+	return s.Conn
+}
+
+type commandsUnsupported struct{}
+
+func (commandsUnsupported) Error() string {
+	return "Peer does not support commands"
+}
+
+var ErrCommandsUnsupported commandsUnsupported
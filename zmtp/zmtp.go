@@ -22,6 +22,14 @@ type Mechanism interface {
 	SetOption(option string, value any) error
 }
 
+// LegacyMechanism is implemented by mechanisms which can also set up ZMTP
+// 2.0 connections, which have no security handshake.
+type LegacyMechanism interface {
+	// HandshakeV2 completes the greeting of a ZMTP 2.0 peer once its
+	// version major has been read.
+	HandshakeV2(net.Conn, Metadata) (s Socket, meta Metadata, err error)
+}
+
 // Socket.
 type Socket interface {
 	// Read the next part of traffic.